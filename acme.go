@@ -0,0 +1,90 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// acmeChallengePrefix is the path prefix of ACME HTTP-01 challenges
+// (RFC 8555, section 8.3).
+const acmeChallengePrefix = "/.well-known/acme-challenge/"
+
+// ACMEResponder publishes responses to ACME HTTP-01 challenges on a
+// [ServeMux]. Get it with [ServeMux.ACME].
+//
+// Its Present and CleanUp methods match the HTTP-01 provider interface of
+// common ACME clients, such as lego's challenge.Provider.
+//
+// Published challenges are answered before the registered patterns are
+// consulted, so they never conflict with user patterns, and requests for
+// tokens that are not published are routed as usual.
+type ACMEResponder struct {
+	mu     sync.RWMutex
+	tokens map[string]acmeChallenge
+}
+
+type acmeChallenge struct {
+	domain  string
+	keyAuth string
+}
+
+// ACME returns the ACME HTTP-01 challenge responder of mux.
+func (mux *ServeMux) ACME() *ACMEResponder {
+	return &mux.acme
+}
+
+// Present publishes the key authorization keyAuth for token.
+// If domain is not empty, the challenge is only answered for requests whose
+// host is domain.
+func (a *ACMEResponder) Present(domain, token, keyAuth string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tokens == nil {
+		a.tokens = map[string]acmeChallenge{}
+	}
+	a.tokens[token] = acmeChallenge{domain: domain, keyAuth: keyAuth}
+	return nil
+}
+
+// CleanUp removes the challenge for token.
+func (a *ACMEResponder) CleanUp(domain, token, keyAuth string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.tokens, token)
+	return nil
+}
+
+// acmeHandler returns the handler answering the published ACME challenge
+// requested by r, or nil. As for patterns, the host of r is matched
+// without its port, and requests with a path that isn't clean don't match.
+func (mux *ServeMux) acmeHandler(r *http.Request) http.Handler {
+	if r.Method == "CONNECT" || !strings.HasPrefix(r.URL.Path, acmeChallengePrefix) {
+		return nil
+	}
+	path := r.URL.EscapedPath()
+	if cleanPath(path) != path {
+		return nil
+	}
+	return mux.acme.handler(r.Method, portlessHost(mux.requestHost(r), r.Method), path)
+}
+
+// handler returns the handler answering the challenge requested by a
+// GET or HEAD request for host and path, or nil.
+func (a *ACMEResponder) handler(method, host, path string) http.Handler {
+	token, ok := strings.CutPrefix(path, acmeChallengePrefix)
+	if !ok || (method != "GET" && method != "HEAD") {
+		return nil
+	}
+	a.mu.RLock()
+	c, ok := a.tokens[token]
+	a.mu.RUnlock()
+	if !ok || (c.domain != "" && !strings.EqualFold(c.domain, host)) {
+		return nil
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, c.keyAuth)
+	})
+}
@@ -0,0 +1,41 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestACME(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "user")
+	})
+	mux.ACME().Present("example.com", "tok1", "tok1.key")
+	mux.ACME().Present("", "tok2", "tok2.key")
+
+	get := func(method, target string) string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w.Body.String()
+	}
+	for _, test := range []struct {
+		method, target, want string
+	}{
+		{"GET", "http://example.com/.well-known/acme-challenge/tok1", "tok1.key"},
+		{"GET", "http://other.com/.well-known/acme-challenge/tok1", "user"},
+		{"GET", "http://other.com/.well-known/acme-challenge/tok2", "tok2.key"},
+		{"POST", "http://other.com/.well-known/acme-challenge/tok2", "user"},
+		{"GET", "http://example.com/.well-known/acme-challenge/nope", "user"},
+	} {
+		if got := get(test.method, test.target); got != test.want {
+			t.Errorf("%s %s: got %q, want %q", test.method, test.target, got, test.want)
+		}
+	}
+
+	mux.ACME().CleanUp("example.com", "tok1", "tok1.key")
+	if got := get("GET", "http://example.com/.well-known/acme-challenge/tok1"); got != "user" {
+		t.Errorf("after CleanUp: got %q, want %q", got, "user")
+	}
+}
@@ -0,0 +1,80 @@
+package shortmux
+
+import (
+	"net/http"
+	"time"
+)
+
+// An AuditEvent describes a request served by a route registered with
+// [WithAudit].
+type AuditEvent struct {
+	Category  string            // category given to WithAudit
+	Principal string            // client identity, from ServeMux.Principal
+	Pattern   string            // matched pattern
+	Method    string            // request method
+	Path      string            // request path
+	Params    map[string]string // wildcard values, by name
+	Status    int               // response status code
+	Panicked  bool              // whether the handler panicked
+	Start     time.Time         // when the request was dispatched
+	Duration  time.Duration     // time spent in the handler
+}
+
+// An AuditSink receives audit events.
+// Audit is called synchronously after the handler returns, so
+// implementations that do expensive work should queue events.
+type AuditSink interface {
+	Audit(r *http.Request, e *AuditEvent)
+}
+
+// AuditSinkFunc is an adapter to use ordinary functions as an [AuditSink].
+type AuditSinkFunc func(r *http.Request, e *AuditEvent)
+
+// Audit calls f(r, e).
+func (f AuditSinkFunc) Audit(r *http.Request, e *AuditEvent) {
+	f(r, e)
+}
+
+// WithAudit marks the route as sensitive: after each request is served, an
+// [AuditEvent] with the given category is sent to the mux AuditSink.
+// Nothing is emitted while the mux has no AuditSink.
+func WithAudit(category string) RouteOption {
+	return func(c *routeConfig) {
+		mux, names := c.mux, c.pat.wildcardNames()
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sink := mux.AuditSink
+				if sink == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				iw := &instrumentedWriter{ResponseWriter: w}
+				e := &AuditEvent{
+					Category: category,
+					Pattern:  r.Pattern,
+					Method:   r.Method,
+					Path:     r.URL.Path,
+					Params:   make(map[string]string, len(names)),
+					Start:    time.Now(),
+					Panicked: true,
+				}
+				if mux.Principal != nil {
+					e.Principal = mux.Principal(r)
+				}
+				for _, name := range names {
+					e.Params[name] = r.PathValue(name)
+				}
+				defer func() {
+					e.Duration = time.Since(e.Start)
+					e.Status = iw.Status()
+					if e.Panicked {
+						e.Status = http.StatusInternalServerError
+					}
+					sink.Audit(r, e)
+				}()
+				next.ServeHTTP(iw, r)
+				e.Panicked = false
+			})
+		})
+	}
+}
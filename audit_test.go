@@ -0,0 +1,44 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWithAudit(t *testing.T) {
+	var events []AuditEvent
+	mux := NewServeMux()
+	mux.Principal = func(r *http.Request) string { return r.Header.Get("X-User") }
+	mux.AuditSink = AuditSinkFunc(func(r *http.Request, e *AuditEvent) {
+		events = append(events, *e)
+	})
+	mux.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, WithAudit("user-admin"))
+	mux.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") }, WithAudit("x"))
+
+	for _, method := range []string{"GET", "DELETE"} {
+		r := httptest.NewRequest(method, "/users/42", nil)
+		r.Header.Set("X-User", "alice")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	func() {
+		defer func() { recover() }()
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	e := events[0]
+	if e.Category != "user-admin" || e.Principal != "alice" || e.Pattern != "DELETE /users/{id}" ||
+		e.Status != http.StatusNoContent || !reflect.DeepEqual(e.Params, map[string]string{"id": "42"}) {
+		t.Errorf("got %+v", e)
+	}
+	if e := events[1]; !e.Panicked || e.Status != http.StatusInternalServerError {
+		t.Errorf("got %+v, want panicked with status 500", e)
+	}
+}
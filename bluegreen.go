@@ -0,0 +1,81 @@
+package shortmux
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sync/atomic"
+)
+
+// weightScale is the resolution of the traffic weight of a [BlueGreen].
+const weightScale = 10000
+
+// BlueGreen is a handler splitting the traffic of a route between two
+// handlers, such as the current and the next version of a service, with a
+// weight that can be adjusted at runtime:
+//
+//	bg := &shortmux.BlueGreen{Blue: v1, Green: v2}
+//	mux.Handle("/api/", bg)
+//	bg.SetWeight(0.1) // send 10% of requests to v2
+//
+// The zero weight sends all requests to Blue.
+type BlueGreen struct {
+	Blue  http.Handler
+	Green http.Handler
+
+	// Key, if set, returns the key of the client making the request, such
+	// as a user ID or a session cookie, so that requests with the same key
+	// are sent to the same handler for as long as the weight doesn't
+	// change. Requests for which it returns "" are split at random.
+	// It must be set before serving.
+	Key func(*http.Request) string
+
+	weight atomic.Uint32 // share of Green, in 1/weightScale
+}
+
+// SetWeight sets the share of requests sent to Green, from 0 to 1.
+// Values out of range are clamped. It is safe to call concurrently with
+// serving.
+func (bg *BlueGreen) SetWeight(green float64) {
+	switch {
+	case math.IsNaN(green) || green < 0:
+		green = 0
+	case green > 1:
+		green = 1
+	}
+	bg.weight.Store(uint32(math.Round(green * weightScale)))
+}
+
+// Weight returns the share of requests sent to Green.
+func (bg *BlueGreen) Weight() float64 {
+	return float64(bg.weight.Load()) / weightScale
+}
+
+// Pick returns the handler serving r, along with whether it is Green.
+func (bg *BlueGreen) Pick(r *http.Request) (h http.Handler, green bool) {
+	w := bg.weight.Load()
+	var n uint32
+	if k := bg.key(r); k != "" {
+		f := fnv.New32a()
+		f.Write([]byte(k))
+		n = f.Sum32() % weightScale
+	} else {
+		n = uint32NFor(r.Context(), weightScale)
+	}
+	if n < w {
+		return bg.Green, true
+	}
+	return bg.Blue, false
+}
+
+func (bg *BlueGreen) key(r *http.Request) string {
+	if bg.Key == nil {
+		return ""
+	}
+	return bg.Key(r)
+}
+
+func (bg *BlueGreen) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h, _ := bg.Pick(r)
+	h.ServeHTTP(w, r)
+}
@@ -0,0 +1,74 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestBlueGreen(t *testing.T) {
+	blue, green := http.NotFoundHandler(), http.NotFoundHandler()
+	bg := &BlueGreen{Blue: blue, Green: green}
+	count := func() int {
+		n := 0
+		for range 1000 {
+			if _, g := bg.Pick(httptest.NewRequest("GET", "/", nil)); g {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(); n != 0 {
+		t.Errorf("zero weight: %d requests sent to green", n)
+	}
+	bg.SetWeight(1)
+	if n := count(); n != 1000 {
+		t.Errorf("weight 1: %d requests sent to green, want 1000", n)
+	}
+	bg.SetWeight(0.3)
+	if n := count(); n < 200 || n > 400 {
+		t.Errorf("weight 0.3: %d requests sent to green, want about 300", n)
+	}
+	for _, w := range []struct{ set, want float64 }{{-1, 0}, {2, 1}, {0.25, 0.25}} {
+		bg.SetWeight(w.set)
+		if got := bg.Weight(); got != w.want {
+			t.Errorf("SetWeight(%v): Weight() = %v, want %v", w.set, got, w.want)
+		}
+	}
+}
+
+func TestBlueGreenSticky(t *testing.T) {
+	bg := &BlueGreen{
+		Blue:  http.NotFoundHandler(),
+		Green: http.NotFoundHandler(),
+		Key:   func(r *http.Request) string { return r.Header.Get("User") },
+	}
+	bg.SetWeight(0.5)
+	greens := 0
+	for i := range 100 {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("User", strconv.Itoa(i))
+		_, first := bg.Pick(r)
+		for range 10 {
+			if _, g := bg.Pick(r); g != first {
+				t.Fatalf("user %d switched handlers", i)
+			}
+		}
+		if first {
+			greens++
+		}
+	}
+	if greens == 0 || greens == 100 {
+		t.Errorf("weight 0.5: %d of 100 users sent to green", greens)
+	}
+	// Raising the weight only moves users from blue to green.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("User", "7")
+	if _, g := bg.Pick(r); g {
+		bg.SetWeight(0.9)
+		if _, g := bg.Pick(r); !g {
+			t.Error("raising the weight moved a user from green to blue")
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package shortmux
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithRequireBody rejects requests without a body with 400 Bad Request
+// before the handler is called.
+//
+// If contentTypes are given, requests whose Content-Type media type is not
+// one of them are rejected with 415 Unsupported Media Type. A content type
+// of the form "type/*" accepts any subtype. Parameters such as charset are
+// ignored. The 415 response to a PATCH or POST request lists the accepted
+// types in the Accept-Patch or Accept-Post header.
+func WithRequireBody(contentTypes ...string) RouteOption {
+	contentTypes = slices.Clone(contentTypes)
+	for i, ct := range contentTypes {
+		contentTypes[i] = strings.ToLower(ct)
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !hasBody(r) {
+					mux.errorText(w, r, http.StatusBadRequest, "request body required")
+					return
+				}
+				if len(contentTypes) > 0 && !acceptsContentType(contentTypes, r.Header.Get("Content-Type")) {
+					accepted := strings.Join(contentTypes, ", ")
+					switch r.Method {
+					case "PATCH":
+						w.Header().Set("Accept-Patch", accepted)
+					case "POST":
+						w.Header().Set("Accept-Post", accepted)
+					}
+					mux.Error(w, r, http.StatusUnsupportedMediaType)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// hasBody reports whether r has a non-empty body.
+// If the length of the body is unknown, a byte is read to find out and
+// r.Body is replaced with an equivalent reader.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+	if r.ContentLength > 0 {
+		return true
+	}
+	var b [1]byte
+	n, _ := io.ReadFull(r.Body, b[:])
+	if n == 0 {
+		return false
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b[:n]), r.Body), r.Body}
+	return true
+}
+
+// acceptsContentType reports whether the media type of the Content-Type
+// header value ct matches one of the accepted types.
+func acceptsContentType(accepted []string, ct string) bool {
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	for _, a := range accepted {
+		if a == mt {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mt, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
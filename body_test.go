@@ -0,0 +1,55 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequireBody(t *testing.T) {
+	mux := NewServeMux()
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}
+	mux.HandleFunc("PATCH /items/{id}", echo, WithRequireBody("application/merge-patch+json", "text/*"))
+	mux.HandleFunc("PUT /blobs/{id}", echo, WithRequireBody())
+
+	for _, test := range []struct {
+		method, path, ct, body string
+		chunked                bool
+		wantCode               int
+		wantBody               string
+	}{
+		{"PATCH", "/items/1", "application/merge-patch+json", `{"a":1}`, false, 200, `{"a":1}`},
+		{"PATCH", "/items/1", "Application/Merge-Patch+JSON; charset=utf-8", `{}`, false, 200, `{}`},
+		{"PATCH", "/items/1", "text/plain", "x", false, 200, "x"},
+		{"PATCH", "/items/1", "application/json", `{}`, false, 415, ""},
+		{"PATCH", "/items/1", "", `{}`, false, 415, ""},
+		{"PATCH", "/items/1", "application/merge-patch+json", "", false, 400, ""},
+		{"PUT", "/blobs/1", "", "data", true, 200, "data"},
+		{"PUT", "/blobs/1", "", "", true, 400, ""},
+	} {
+		var body io.Reader = strings.NewReader(test.body)
+		if test.chunked {
+			body = io.MultiReader(body) // hide the length
+		}
+		r := httptest.NewRequest(test.method, test.path, body)
+		if test.chunked {
+			r.ContentLength = -1
+		}
+		if test.ct != "" {
+			r.Header.Set("Content-Type", test.ct)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode || (test.wantCode == 200 && w.Body.String() != test.wantBody) {
+			t.Errorf("%s %s %q %q: got %d %q, want %d %q", test.method, test.path, test.ct, test.body, w.Code, w.Body.String(), test.wantCode, test.wantBody)
+		}
+		if w.Code == 415 && w.Header().Get("Accept-Patch") != "application/merge-patch+json, text/*" {
+			t.Errorf("got Accept-Patch %q", w.Header().Get("Accept-Patch"))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package shortmux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithBudget sets a deadline d from the start of dispatch on the context of
+// the requests matching the route, so that downstream calls made with the
+// request context (database queries, outbound HTTP requests) honor the
+// latency budget of the endpoint.
+//
+// Unlike [http.TimeoutHandler], WithBudget does not interrupt the handler
+// or write a response when the deadline passes; handlers decide how to
+// react to a canceled context. An earlier deadline already present on the
+// context is kept.
+func WithBudget(d time.Duration) RouteOption {
+	if d <= 0 {
+		panic(fmt.Sprintf("shortmux: invalid budget %v", d))
+	}
+	return func(c *routeConfig) {
+		c.budget = d
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx, cancel := context.WithTimeout(r.Context(), d)
+				defer cancel()
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+	}
+}
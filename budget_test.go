@@ -0,0 +1,37 @@
+package shortmux
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithBudget(t *testing.T) {
+	var remaining time.Duration
+	var hasDeadline bool
+	mux := NewServeMux()
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		var dl time.Time
+		dl, hasDeadline = r.Context().Deadline()
+		remaining = time.Until(dl)
+	}, WithBudget(100*time.Millisecond))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+	if !hasDeadline || remaining <= 0 || remaining > 100*time.Millisecond {
+		t.Errorf("got deadline %t in %v, want within 100ms", hasDeadline, remaining)
+	}
+
+	// An earlier deadline is kept.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil).WithContext(ctx))
+	if remaining > 10*time.Millisecond {
+		t.Errorf("got deadline in %v, want within 10ms", remaining)
+	}
+
+	if got := mux.Routes()[0].Budget; got != 100*time.Millisecond {
+		t.Errorf("got route budget %v, want 100ms", got)
+	}
+}
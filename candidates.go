@@ -0,0 +1,114 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// A CandidateReport describes a route matching a request, as returned by
+// [ServeMux.Candidates].
+type CandidateReport struct {
+	Pattern string      `json:"pattern"`
+	Handler string      `json:"handler"` // as given by HandlerName
+	Params  []PathParam `json:"params,omitempty"`
+
+	// Matchers is whether the route has matchers, which may reject the
+	// request and leave it to the next candidate.
+	Matchers bool `json:"matchers,omitempty"`
+
+	// Dominates lists the patterns of the next candidates, which the route
+	// takes precedence over, and Reason tells why it takes precedence over
+	// the first of them.
+	Dominates []string `json:"dominates,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+// Candidates returns the routes matching requests with the given method,
+// host and path, from the one serving them to the least specific, for
+// understanding why a route wins. As for requests, any port is stripped
+// from host, unless method is CONNECT, and path, in its escaped form, is
+// cleaned. Route matchers are not evaluated, so a route whose matchers
+// reject a request leaves it to the next candidate, as with
+// [WithFallthrough]. Trailing-slash redirects are not reported.
+func (mux *ServeMux) Candidates(method, host, path string) []CandidateReport {
+	if method != "CONNECT" {
+		host = mux.matchHost(stripHostPort(host))
+		path = cleanPath(path)
+	}
+	// The scope applies to matches for a request, whose matchers are
+	// ignored.
+	r := &http.Request{Method: method}
+	sc := &matchScope{ignoreMatchers: true}
+	var reports []CandidateReport
+	var pats []*pattern
+	if !mux.frozen.Load() {
+		mux.mu.RLock()
+		defer mux.mu.RUnlock()
+	}
+	for {
+		n, matches, _ := mux.tree.match(host, method, path, mux.StrictHEAD, r, sc)
+		if n == nil {
+			break
+		}
+		sc.skip = append(sc.skip, n)
+		reports = append(reports, CandidateReport{
+			Pattern:  n.pattern.String(),
+			Handler:  HandlerName(n.route.handler),
+			Params:   n.pattern.params(matches),
+			Matchers: len(n.route.cfg.matchers) > 0,
+		})
+		pats = append(pats, n.pattern)
+	}
+	for i := range reports {
+		for _, c := range reports[i+1:] {
+			reports[i].Dominates = append(reports[i].Dominates, c.Pattern)
+		}
+		if i+1 < len(pats) {
+			reports[i].Reason = precedenceReason(pats[i], pats[i+1])
+		}
+	}
+	return reports
+}
+
+// precedenceReason returns why p1 takes precedence over p2 when both match
+// a request, as the routing tree decides.
+func precedenceReason(p1, p2 *pattern) string {
+	switch {
+	case p1.host != "" && p2.host == "":
+		return "patterns with a host take precedence over patterns without one"
+	case p1.host != p2.host:
+		return "patterns with the port of the request take precedence over patterns without one"
+	case p1.method != "" && p2.method == "":
+		return "patterns with a method take precedence over patterns without one"
+	case p1.method == "HEAD" && p2.method == "GET":
+		return "HEAD patterns take precedence over the GET patterns matching HEAD requests"
+	}
+	for i, s1 := range p1.segments {
+		if i >= len(p2.segments) {
+			break
+		}
+		s2 := p2.segments[i]
+		switch {
+		case s1.rank() != s2.rank():
+			return fmt.Sprintf("segment %d of its path is %s, which is more specific than %s", i+1, segmentKind(s1), segmentKind(s2))
+		case s1.multi && s2.multi && p1.suffix != nil && p2.suffix == nil:
+			return fmt.Sprintf("the %s of segment %d of its path is followed by literals or wildcards", segmentKind(s1), i+1)
+		}
+	}
+	return "its path is more specific"
+}
+
+// segmentKind describes the kind of s.
+func segmentKind(s segment) string {
+	switch {
+	case !s.wild:
+		return "a literal"
+	case s.enum != nil:
+		return "a wildcard constrained to literals"
+	case s.multi && s.s == "":
+		return "a trailing slash"
+	case s.multi:
+		return `a "..." wildcard`
+	}
+	return "a wildcard"
+}
@@ -0,0 +1,66 @@
+package shortmux
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	for _, pat := range []string{
+		"/",
+		"/users/",
+		"/users/{id}",
+		"GET /users/{id}",
+		"example.com/users/{id}",
+		"/users/{id:(me|you)}",
+	} {
+		mux.Handle(pat, h)
+	}
+	mux.Handle("/users/{id}/", h, WithMatcher(MatchQuery("never", "")))
+
+	got := mux.Candidates("GET", "example.com:8080", "/users/me")
+	var pats []string
+	for _, c := range got {
+		pats = append(pats, c.Pattern)
+	}
+	want := []string{
+		"example.com/users/{id}",
+		"GET /users/{id}",
+		"/users/{id:(me|you)}",
+		"/users/{id}",
+		"/users/",
+		"/",
+	}
+	if !reflect.DeepEqual(pats, want) {
+		t.Fatalf("got candidates\n%q\nwant\n%q", pats, want)
+	}
+	if !reflect.DeepEqual(got[0].Dominates, want[1:]) || got[len(got)-1].Dominates != nil {
+		t.Errorf("got dominated patterns %q and %q", got[0].Dominates, got[len(got)-1].Dominates)
+	}
+	if p := got[0].Params; len(p) != 1 || p[0] != (PathParam{"id", "me"}) {
+		t.Errorf("got params %v", p)
+	}
+	for i, reason := range []string{
+		"patterns with a host take precedence over patterns without one",
+		"patterns with a method take precedence over patterns without one",
+		"segment 2 of its path is a wildcard constrained to literals, which is more specific than a wildcard",
+		`segment 2 of its path is a wildcard, which is more specific than a trailing slash`,
+		`segment 1 of its path is a literal, which is more specific than a trailing slash`,
+		"",
+	} {
+		if got[i].Reason != reason {
+			t.Errorf("%s: got reason %q, want %q", got[i].Pattern, got[i].Reason, reason)
+		}
+	}
+
+	got = mux.Candidates("GET", "", "/users/7/")
+	if len(got) != 3 || got[0].Pattern != "/users/{id}/" || !got[0].Matchers {
+		t.Errorf("got %+v, want the route with matchers first", got)
+	}
+	if got := mux.Candidates("POST", "", "/x/../users/7/"); len(got) != 3 {
+		t.Errorf("uncleaned path: got %d candidates, want 3", len(got))
+	}
+}
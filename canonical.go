@@ -0,0 +1,85 @@
+package shortmux
+
+import (
+	"net/url"
+	"strings"
+)
+
+// CanonicalPattern parses the pattern s and renders it in canonical form,
+// so that equal patterns written differently, for example by different
+// sources of a routing table, can be recognized:
+//
+//   - the method is separated from the rest by a single space;
+//   - literals are unescaped and escaped again with [url.PathEscape], so
+//     "/%61%2f" becomes "/a%2F";
+//   - constraints always use parentheses, so "{p:!admin}" becomes
+//     "{p:!(admin)}".
+//
+// Wildcard names are kept, so "/{a}" and "/{b}" have distinct canonical
+// forms even though they match the same requests.
+func CanonicalPattern(s string) (string, error) {
+	p, err := parsePattern(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if p.method != "" {
+		b.WriteString(p.method)
+		b.WriteByte(' ')
+	}
+	b.WriteString(p.host)
+	for i, seg := range p.segments {
+		b.WriteByte('/')
+		switch {
+		case !seg.wild && seg.s == "/" && i == len(p.segments)-1:
+			b.WriteString("{$}")
+		case !seg.wild:
+			b.WriteString(url.PathEscape(seg.s))
+		case seg.multi && seg.s == "":
+			// Trailing slash.
+		case seg.multi:
+			b.WriteString("{" + seg.s + "...}")
+		default:
+			b.WriteString("{" + seg.s)
+			switch {
+			case seg.enum != nil:
+				b.WriteString(":" + canonicalList(seg.enum))
+			case seg.exclude != nil:
+				b.WriteString(":!" + canonicalList(seg.exclude))
+			}
+			b.WriteString("}")
+		}
+	}
+	return b.String(), nil
+}
+
+// WildcardNames parses the pattern s and returns the names of its named
+// wildcards, in order, as given to [http.Request.PathValue]. Constraints
+// are not part of the names, so the name of "{format:(json|xml)}" is
+// "format". See [ServeMux.WildcardNames] for the patterns of a mux with
+// custom wildcard names.
+func WildcardNames(s string) ([]string, error) {
+	p, err := parsePattern(s)
+	if err != nil {
+		return nil, err
+	}
+	return p.wildcardNames(), nil
+}
+
+// WildcardNames is like the [WildcardNames] function, for a pattern
+// registered on mux, whose names are validated with mux.WildcardName.
+func (mux *ServeMux) WildcardNames(s string) ([]string, error) {
+	p, err := mux.parsePattern(s)
+	if err != nil {
+		return nil, err
+	}
+	return p.wildcardNames(), nil
+}
+
+func canonicalList(lits []string) string {
+	escaped := make([]string, len(lits))
+	for i, lit := range lits {
+		escaped[i] = url.PathEscape(lit)
+	}
+	return "(" + strings.Join(escaped, "|") + ")"
+}
@@ -0,0 +1,67 @@
+package shortmux
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalPattern(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"/", "/"},
+		{"GET  \t/a/b", "GET /a/b"},
+		{"example.com/{$}", "example.com/{$}"},
+		{"/%61/%2f/a%20b/", "/a/%2F/a%20b/"},
+		{"/b/{bucket}/o/{name...}", "/b/{bucket}/o/{name...}"},
+		{"/{f:(json|x%6dl)}", "/{f:(json|xml)}"},
+		{"/{p:!admin}", "/{p:!(admin)}"},
+		{"/{p:!(a|b%7Cc)}", "/{p:!(a|b%7Cc)}"},
+	} {
+		got, err := CanonicalPattern(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %q, want %q", test.in, got, test.want)
+		}
+		// The canonical form is a fixed point.
+		if again, err := CanonicalPattern(got); err != nil || again != got {
+			t.Errorf("%q: canonical form %q is not canonical: %q, %v", test.in, got, again, err)
+		}
+	}
+	if _, err := CanonicalPattern("/{x"); err == nil {
+		t.Error("got nil error for invalid pattern")
+	}
+}
+
+func TestWildcardNames(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		want    string
+	}{
+		{"/a", ""},
+		{"GET /v1/export/{format:(json|xml)}", "format"},
+		{"/{a}/{b:!(x|y)}/{c...}", "a,b,c"},
+		{"/{p...}/{id}/edit", "p,id"},
+		{"/a/{$}", ""},
+		{"/a/", ""},
+	} {
+		names, err := WildcardNames(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(names, ","); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.pattern, got, test.want)
+		}
+	}
+	if _, err := WildcardNames("/{a%3Ab}"); err == nil {
+		t.Error("name with a colon: got nil error")
+	}
+	mux := NewServeMux()
+	mux.WildcardName = func(name string) bool { return name != "" }
+	if names, err := mux.WildcardNames("/{a%3Ab}"); err != nil || len(names) != 1 || names[0] != "a:b" {
+		t.Errorf("custom name: got %q, %v", names, err)
+	}
+}
@@ -0,0 +1,75 @@
+package shortmux
+
+import "net/http"
+
+// canonicalHost is the configuration set with CanonicalHost.
+type canonicalHost struct {
+	host string // as given, possibly with a port
+	name string // as matched against the hosts of requests
+	code int
+}
+
+// CanonicalHost makes host, such as "www.example.com", the canonical host
+// of mux: requests for other hosts, such as "example.com", are redirected
+// to the same path and query on host before being routed, so that the
+// aliases of a site need no routes of their own. The redirect is a 308
+// Permanent Redirect if permanent is true, and a 307 Temporary Redirect
+// otherwise, both of which keep the method and body of the request.
+//
+// Requests with no Host header and requests for a host named by a
+// registered pattern, such as "api.example.com/", are not redirected.
+// The redirect uses HTTPS if the request did, as told by
+// [HTTPSPolicy.IsHTTPS], or if host is HTTPS-only in [ServeMux.HTTPS].
+//
+// An empty host turns the redirects off. CanonicalHost panics if host is
+// not a valid Host header.
+func (mux *ServeMux) CanonicalHost(host string, permanent bool) {
+	if host == "" {
+		mux.canonical.Store(nil)
+		return
+	}
+	if !validHost(host) {
+		panic("shortmux: invalid canonical host " + host)
+	}
+	c := &canonicalHost{
+		host: host,
+		name: mux.matchHost(stripHostPort(host)),
+		code: http.StatusTemporaryRedirect,
+	}
+	if permanent {
+		c.code = http.StatusPermanentRedirect
+	}
+	mux.canonical.Store(c)
+}
+
+// redirectHost redirects r to the canonical host c if it's for another
+// host, and reports whether it did.
+func (mux *ServeMux) redirectHost(w http.ResponseWriter, r *http.Request, c *canonicalHost) bool {
+	if r.Host == "" {
+		return false
+	}
+	// The port is ignored, even with MatchPorts set, as c.name has none.
+	if mux.matchHost(stripHostPort(r.Host)) == c.name || mux.patternHost(mux.requestHost(r)) {
+		return false
+	}
+	scheme := "http://"
+	if mux.HTTPS.IsHTTPS(r) || (mux.HTTPS != nil && mux.HTTPS.httpsHost(c.name)) {
+		scheme = "https://"
+	}
+	http.Redirect(w, r, scheme+c.host+r.URL.RequestURI(), c.code)
+	return true
+}
+
+// patternHost reports whether host, as returned by requestHost, is named
+// by a registered pattern.
+func (mux *ServeMux) patternHost(host string) bool {
+	if !mux.frozen.Load() {
+		mux.mu.RLock()
+		defer mux.mu.RUnlock()
+	}
+	if mux.tree.findChild(host) != nil {
+		return true
+	}
+	h := portlessHost(host, "")
+	return h != host && mux.tree.findChild(h) != nil
+}
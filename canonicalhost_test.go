@@ -0,0 +1,110 @@
+package shortmux
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("root " + r.Host))
+	})
+	mux.HandleFunc("api.example.com/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api"))
+	})
+	mux.CanonicalHost("www.example.com", true)
+
+	for _, test := range []struct {
+		method, url string
+		tls         bool
+		code        int
+		loc, body   string
+	}{
+		{"GET", "http://www.example.com/a?b=c", false, 200, "", "root www.example.com"},
+		{"GET", "http://www.example.com:8080/a", false, 200, "", "root www.example.com:8080"},
+		{"GET", "http://example.com/a?b=c", false, 308, "http://www.example.com/a?b=c", ""},
+		{"POST", "http://example.com/form", false, 308, "http://www.example.com/form", ""},
+		{"GET", "https://example.net/", true, 308, "https://www.example.com/", ""},
+		{"GET", "http://api.example.com/v1", false, 200, "", "api"},
+	} {
+		r := httptest.NewRequest(test.method, test.url, nil)
+		if !test.tls {
+			r.TLS = nil
+		} else if r.TLS == nil {
+			r.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.code || w.Header().Get("Location") != test.loc {
+			t.Errorf("%s %s: got %d to %q, want %d to %q", test.method, test.url, w.Code, w.Header().Get("Location"), test.code, test.loc)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s %s: got body %q, want %q", test.method, test.url, w.Body.String(), test.body)
+		}
+	}
+
+	mux.CanonicalHost("www.example.com", false)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/", nil))
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Errorf("temporary: got %d, want %d", w.Code, http.StatusTemporaryRedirect)
+	}
+
+	mux.CanonicalHost("", false)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("off: got %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestCanonicalHostACME(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.CanonicalHost("www.example.com", true)
+	mux.HTTPS = &HTTPSPolicy{Hosts: []string{"*"}}
+	mux.ACME().Present("example.com", "tok", "tok.key")
+
+	for _, test := range []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"http://example.com/.well-known/acme-challenge/tok", 200, "tok.key"},
+		{"http://example.com:80/.well-known/acme-challenge/tok", 200, "tok.key"},
+		{"http://example.com/.well-known/acme-challenge/other", 308, ""},
+		{"http://example.net/.well-known/acme-challenge/tok", 308, ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if w.Code != test.code || (test.body != "" && w.Body.String() != test.body) {
+			t.Errorf("%s: got %d %q, want %d %q", test.url, w.Code, w.Body.String(), test.code, test.body)
+		}
+	}
+}
+
+func TestCanonicalHostMatchPorts(t *testing.T) {
+	for _, canonical := range []string{"www.example.com", "www.example.com:8080"} {
+		mux := NewServeMux()
+		mux.MatchPorts = true
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+		mux.CanonicalHost(canonical, true)
+		for _, test := range []struct {
+			url, loc string
+		}{
+			{"http://www.example.com/a", ""},
+			{"http://www.example.com:8080/a", ""},
+			{"http://example.com:8080/a", "http://" + canonical + "/a"},
+		} {
+			r := httptest.NewRequest("GET", test.url, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if got := w.Header().Get("Location"); got != test.loc {
+				t.Errorf("canonical %s: GET %s redirected to %q, want %q", canonical, test.url, got, test.loc)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package shortmux
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// A Clock tells the current time, for the time-dependent features of a
+// [ServeMux]; see ServeMux.Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is an adapter to use an ordinary function as a [Clock].
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// envKey is the context key of the mux whose Clock and Rand serve a
+// request, set by ServeHTTP when either is set.
+type envKey struct{}
+
+// withEnv returns r with the Clock and Rand of mux, if it has any.
+func (mux *ServeMux) withEnv(r *http.Request) *http.Request {
+	if mux.Clock == nil && mux.Rand == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), envKey{}, mux))
+}
+
+// now returns the current time by the mux Clock.
+func (mux *ServeMux) now() time.Time {
+	if mux.Clock != nil {
+		return mux.Clock.Now()
+	}
+	return time.Now()
+}
+
+// nowFor returns the current time by the Clock of the mux serving ctx.
+func nowFor(ctx context.Context) time.Time {
+	if mux, ok := ctx.Value(envKey{}).(*ServeMux); ok {
+		return mux.now()
+	}
+	return time.Now()
+}
+
+// randFor returns the Rand of the mux serving ctx, or nil if it has none.
+func randFor(ctx context.Context) *rand.Rand {
+	if mux, ok := ctx.Value(envKey{}).(*ServeMux); ok && mux.Rand != nil {
+		return rand.New(mux.Rand)
+	}
+	return nil
+}
+
+// uint32NFor returns a random number in [0, n) from the Rand of the mux
+// serving ctx.
+func uint32NFor(ctx context.Context, n uint32) uint32 {
+	if r := randFor(ctx); r != nil {
+		return r.Uint32N(n)
+	}
+	return rand.Uint32N(n)
+}
+
+// float64For returns a random number in [0, 1) from the Rand of the mux
+// serving ctx.
+func float64For(ctx context.Context) float64 {
+	if r := randFor(ctx); r != nil {
+		return r.Float64()
+	}
+	return rand.Float64()
+}
@@ -0,0 +1,82 @@
+package shortmux
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	t0 := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	mux := NewServeMux()
+	mux.Clock = ClockFunc(func() time.Time { return now })
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	mux.Handle("/launch", ok, WithSchedule(t0.Add(time.Hour), time.Time{}))
+	mux.Handle("POST /hook", ok, WithWebhookDedupe("Id", time.Minute, nil))
+
+	serve := func(method, target string) string {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Id", "1")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return fmt.Sprint(w.Code, " ", w.Body)
+	}
+
+	if got := serve("GET", "/launch"); got != "404 404 page not found\n" {
+		t.Errorf("before the schedule: got %q", got)
+	}
+	if got := serve("POST", "/hook"); got != "200 ok" {
+		t.Errorf("first delivery: got %q", got)
+	}
+	if got := serve("POST", "/hook"); got != "200 " {
+		t.Errorf("duplicate delivery: got %q", got)
+	}
+
+	now = t0.Add(2 * time.Hour)
+	if got := serve("GET", "/launch"); got != "200 ok" {
+		t.Errorf("in the schedule: got %q", got)
+	}
+	if got := serve("POST", "/hook"); got != "200 ok" {
+		t.Errorf("delivery after the window: got %q", got)
+	}
+}
+
+// lockedSource is a rand.Source safe for concurrent use.
+type lockedSource struct {
+	ch chan rand.Source
+}
+
+func (s lockedSource) Uint64() uint64 {
+	src := <-s.ch
+	defer func() { s.ch <- src }()
+	return src.Uint64()
+}
+
+func TestRand(t *testing.T) {
+	split := func(seed uint64) []bool {
+		src := lockedSource{make(chan rand.Source, 1)}
+		src.ch <- rand.NewPCG(seed, 0)
+		mux := NewServeMux()
+		mux.Rand = src
+		var greens []bool
+		blue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { greens = append(greens, false) })
+		green := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { greens = append(greens, true) })
+		bg := &BlueGreen{Blue: blue, Green: green}
+		bg.SetWeight(0.5)
+		mux.Handle("/", bg)
+		for range 32 {
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}
+		return greens
+	}
+	if a, b := split(1), split(1); fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("same seed, different splits:\n%v\n%v", a, b)
+	}
+}
@@ -0,0 +1,113 @@
+package shortmux
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// WithCoalescedRevalidation marks the route as cacheable and collapses
+// concurrent revalidation requests into a single handler call.
+//
+// A revalidation request is a GET or HEAD request with an If-None-Match or
+// If-Modified-Since header. Requests are identical when they have the same
+// method, host, request URI, validators, and Accept, Accept-Encoding,
+// Accept-Language, Authorization and Cookie headers. While the handler
+// serves one of them, identical requests wait and then receive a copy of
+// its response, typically a 304 Not Modified or a 200 OK with the new
+// representation.
+//
+// Responses that the handler streams (by flushing) are not shared; the
+// waiting requests are then served by the handler independently.
+func WithCoalescedRevalidation() RouteOption {
+	return func(c *routeConfig) {
+		g := &coalescer{calls: map[string]*coalescedCall{}}
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if (r.Method != "GET" && r.Method != "HEAD") ||
+					(r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "") {
+					next.ServeHTTP(w, r)
+					return
+				}
+				g.serve(next, w, r)
+			})
+		})
+	}
+}
+
+// revalidationKey returns the key identifying requests equivalent to r.
+func revalidationKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(0)
+	b.WriteString(r.Host)
+	b.WriteByte(0)
+	b.WriteString(r.URL.RequestURI())
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cookie"} {
+		b.WriteByte(0)
+		b.WriteString(strings.Join(r.Header.Values(h), "\n"))
+	}
+	return b.String()
+}
+
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	res  *BufferedResponse // nil if the response can't be shared
+}
+
+func (g *coalescer) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	key := revalidationKey(r)
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-r.Context().Done():
+			return
+		}
+		if c.res == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		writeBufferedResponse(w, c.res)
+		return
+	}
+	c := &coalescedCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	bw := &transformWriter{ResponseWriter: w, header: w.Header().Clone()}
+	next.ServeHTTP(bw, r)
+	if bw.streaming {
+		return
+	}
+	status := bw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.res = &BufferedResponse{StatusCode: status, Header: bw.header, Body: bw.buf.Bytes()}
+	writeBufferedResponse(w, c.res)
+}
+
+// writeBufferedResponse writes res to w.
+func writeBufferedResponse(w http.ResponseWriter, res *BufferedResponse) {
+	h := w.Header()
+	for k, v := range res.Header {
+		// Copy the values, as res may be shared by several requests.
+		h[k] = slices.Clone(v)
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = w.Write(res.Body)
+}
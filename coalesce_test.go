@@ -0,0 +1,63 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithCoalescedRevalidation(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	mux := NewServeMux()
+	mux.HandleFunc("GET /doc", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("v1"))
+	}, WithCoalescedRevalidation())
+
+	const n = 5
+	var wg sync.WaitGroup
+	codes := make([]int, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest("GET", "/doc", nil)
+			r.Header.Set("If-None-Match", `"v1"`)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Header().Get("ETag") != `"v1"` {
+				t.Errorf("request %d: missing ETag", i)
+			}
+			codes[i] = w.Code
+		}()
+	}
+	// Give the requests time to queue up behind the first one.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusNotModified {
+			t.Errorf("request %d: got status %d, want 304", i, code)
+		}
+	}
+	if got := calls.Load(); got >= n {
+		t.Errorf("got %d handler calls, want fewer than %d", got, n)
+	}
+
+	// Requests without validators are never coalesced.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/doc", nil))
+	if w.Body.String() != "v1" {
+		t.Errorf("got body %q, want %q", w.Body.String(), "v1")
+	}
+}
@@ -0,0 +1,68 @@
+package shortmux
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// A Cohort is the routing decision made from a cohort cookie, such as the
+// arm of an A/B test, for analytics.
+type Cohort struct {
+	Cookie string // name of the cookie
+	Value  string // value of the cookie, or empty if the request has none
+}
+
+type cohortKey struct{}
+
+// CohortOf returns the cohort decided for r by [MatchCohort] or
+// [SplitByCookie], if any.
+func CohortOf(r *http.Request) (Cohort, bool) {
+	c, ok := r.Context().Value(cohortKey{}).(Cohort)
+	return c, ok
+}
+
+// MatchCohort returns a matcher accepting requests whose cookie has one of
+// values, or requests without the cookie if values is empty. The decision
+// is available to the handler of the route with [CohortOf].
+func MatchCohort(cookie string, values ...string) Matcher {
+	return MatcherFunc(func(r *http.Request, s *MatchState) bool {
+		var v string
+		if c, err := r.Cookie(cookie); err == nil {
+			v = c.Value
+		}
+		if len(values) == 0 && v != "" || len(values) > 0 && !slices.Contains(values, v) {
+			return false
+		}
+		s.SetValue(cohortKey{}, Cohort{Cookie: cookie, Value: v})
+		return true
+	})
+}
+
+// SplitByCookie returns a handler serving each request with the handler
+// for the value of its cookie, or with fallback, which may be nil for a
+// 404 Not Found response, if there is none. It lets cohorts be served by
+// different handlers on the same pattern:
+//
+//	mux.Handle("/checkout", shortmux.SplitByCookie("exp", map[string]http.Handler{
+//		"a": checkoutA,
+//		"b": checkoutB,
+//	}, checkoutA))
+//
+// The decision is available to the handlers with [CohortOf].
+func SplitByCookie(cookie string, handlers map[string]http.Handler, fallback http.Handler) http.Handler {
+	if fallback == nil {
+		fallback = http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v string
+		if c, err := r.Cookie(cookie); err == nil {
+			v = c.Value
+		}
+		h, ok := handlers[v]
+		if !ok {
+			h = fallback
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cohortKey{}, Cohort{Cookie: cookie, Value: v})))
+	})
+}
@@ -0,0 +1,43 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCohorts(t *testing.T) {
+	show := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := CohortOf(r)
+			fmt.Fprintf(w, "%s %t %q", name, ok, c.Value)
+		})
+	}
+	mux := NewServeMux()
+	mux.Handle("/checkout", SplitByCookie("exp", map[string]http.Handler{"a": show("A"), "b": show("B")}, show("default")))
+	mux.Handle("/home/", show("new home"), WithMatcher(MatchCohort("exp", "b")))
+	mux.Handle("/", show("old home"))
+
+	for _, test := range []struct {
+		path, cookie, want string
+	}{
+		{"/checkout", "a", `A true "a"`},
+		{"/checkout", "b", `B true "b"`},
+		{"/checkout", "c", `default true "c"`},
+		{"/checkout", "", `default true ""`},
+		{"/home/", "b", `new home true "b"`},
+		{"/home/", "a", `old home false ""`},
+		{"/home/", "", `old home false ""`},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "exp", Value: test.cookie})
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s with cookie %q: got %s, want %s", test.path, test.cookie, got, test.want)
+		}
+	}
+}
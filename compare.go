@@ -0,0 +1,86 @@
+package shortmux
+
+import (
+	"iter"
+	"net/http"
+)
+
+// A Router is a request multiplexer reporting the handler and pattern for
+// requests, such as [ServeMux] or [http.ServeMux].
+type Router interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
+// A Decision is how a [Router] routes a request.
+type Decision struct {
+	// Pattern is the pattern reported by the router: the matched pattern,
+	// the pattern that will match after a redirect, or empty.
+	Pattern string
+
+	// Handler is the name of the handler, as given by HandlerName, unless
+	// Pattern is empty. For a ServeMux, it is the handler registered for
+	// the route, without its options.
+	Handler string
+
+	// Status is the status code of the response of the router, when
+	// Pattern is empty, e.g. 404 or 405.
+	Status int
+}
+
+// A Divergence is a request two routers route differently.
+type Divergence struct {
+	Request Request
+	A, B    Decision
+	Err     error // error building the request, if any
+}
+
+// CompareMuxes routes each of reqs with a and b, without calling any route
+// handler, and returns the requests they route differently, for checking a
+// migration between routing tables or from another router.
+//
+// Requests are routed the same when the decisions of a and b are equal:
+// they dispatch to handlers with the same name for the same pattern, or
+// respond by themselves with the same status code.
+func CompareMuxes(a, b Router, reqs iter.Seq[Request]) []Divergence {
+	var divs []Divergence
+	for req := range reqs {
+		r, err := req.httpRequest()
+		if err != nil {
+			divs = append(divs, Divergence{Request: req, Err: err})
+			continue
+		}
+		da, db := decide(a, r), decide(b, r)
+		if da != db {
+			divs = append(divs, Divergence{Request: req, A: da, B: db})
+		}
+	}
+	return divs
+}
+
+// decide returns the decision of rt for r.
+func decide(rt Router, r *http.Request) Decision {
+	var h http.Handler
+	var d Decision
+	if mux, ok := rt.(*ServeMux); ok {
+		var n *routingNode
+		h, d.Pattern, n, _, _ = mux.findHandler(r)
+		if n != nil {
+			d.Handler = HandlerName(n.route.handler)
+			return d
+		}
+	} else {
+		h, d.Pattern = rt.Handler(r)
+	}
+	if d.Pattern != "" {
+		d.Handler = HandlerName(h)
+		return d
+	}
+	// The router responds by itself; record the status code.
+	w := &headerRecorder{header: http.Header{}}
+	h.ServeHTTP(w, r)
+	d.Status = w.code
+	if d.Status == 0 {
+		d.Status = http.StatusOK
+	}
+	return d
+}
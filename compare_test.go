@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"net/http"
+	"testing"
+)
+
+func listUsers(http.ResponseWriter, *http.Request) {}
+func getUser(http.ResponseWriter, *http.Request)   {}
+func getDoc(http.ResponseWriter, *http.Request)    {}
+
+func TestCompareMuxes(t *testing.T) {
+	old := http.NewServeMux()
+	old.HandleFunc("GET /users", listUsers)
+	old.HandleFunc("GET /users/{id}", getUser)
+	old.HandleFunc("/docs/", getDoc)
+
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users", listUsers, WithBudget(1))
+	mux.HandleFunc("GET /users/{name}", getUser)
+	mux.HandleFunc("GET /docs/", getDoc)
+
+	divs := CompareMuxes(old, mux, Requests(
+		Request{Path: "/users"},
+		Request{Path: "/users/7"},
+		Request{Path: "/docs"},
+		Request{Path: "/docs/a"},
+		Request{Method: "POST", Path: "/docs/a"},
+		Request{Path: "/missing"},
+		Request{Method: "POST", Path: "/users"},
+	))
+	want := []Divergence{
+		{
+			Request: Request{Path: "/users/7"},
+			A:       Decision{Pattern: "GET /users/{id}", Handler: "github.com/henvic/shortmux.getUser"},
+			B:       Decision{Pattern: "GET /users/{name}", Handler: "github.com/henvic/shortmux.getUser"},
+		},
+		{
+			Request: Request{Path: "/docs/a"},
+			A:       Decision{Pattern: "/docs/", Handler: "github.com/henvic/shortmux.getDoc"},
+			B:       Decision{Pattern: "GET /docs/", Handler: "github.com/henvic/shortmux.getDoc"},
+		},
+		{
+			Request: Request{Method: "POST", Path: "/docs/a"},
+			A:       Decision{Pattern: "/docs/", Handler: "github.com/henvic/shortmux.getDoc"},
+			B:       Decision{Status: 405},
+		},
+	}
+	if len(divs) != len(want) {
+		t.Fatalf("got %d divergences, want %d: %+v", len(divs), len(want), divs)
+	}
+	for i := range want {
+		if divs[i] != want[i] {
+			t.Errorf("divergence %d:\ngot  %+v\nwant %+v", i, divs[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package shortmux
+
+import (
+	"net/http"
+	"time"
+)
+
+// A CompletedRequest describes a request served by a mux, as reported to
+// its OnComplete hook.
+type CompletedRequest struct {
+	Pattern  string        // matched pattern, or empty if none matched
+	Method   string        // request method
+	Path     string        // request path
+	Status   int           // status code written, or 200 if none was
+	Written  int64         // number of body bytes written
+	Start    time.Time     // when the mux received the request
+	Duration time.Duration // time until the response was done
+}
+
+// complete reports the request r, received at start and answered through
+// w, to the mux OnComplete hook. It is deferred, so that handlers that
+// panic are reported too.
+func (mux *ServeMux) complete(w *instrumentedWriter, r *http.Request, start time.Time) {
+	mux.OnComplete(r, &CompletedRequest{
+		Pattern:  r.Pattern,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Status:   w.Status(),
+		Written:  w.written,
+		Start:    start,
+		Duration: time.Since(start),
+	})
+}
@@ -0,0 +1,47 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOnComplete(t *testing.T) {
+	var got []string
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{Recover: true}
+	mux.OnComplete = func(r *http.Request, c *CompletedRequest) {
+		if c.Duration < 0 || c.Start.IsZero() {
+			t.Errorf("%s: got start %v, duration %v", c.Path, c.Start, c.Duration)
+		}
+		got = append(got, fmt.Sprintf("%s %s %q %d %d", c.Method, c.Path, c.Pattern, c.Status, c.Written))
+	}
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /items/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	for _, req := range []struct{ method, target string }{
+		{"GET", "/items/abc"},
+		{"POST", "/items/"},
+		{"GET", "/missing"},
+		{"GET", "/panic"},
+	} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.target, nil))
+	}
+	want := []string{
+		`GET /items/abc "GET /items/{id}" 200 3`,
+		`POST /items/ "POST /items/" 201 0`,
+		`GET /missing "" 404 19`,
+		`GET /panic "/panic" 500 22`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
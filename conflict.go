@@ -0,0 +1,85 @@
+package shortmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// describeConflict returns a message explaining why p2 can't be registered
+// alongside p1, which matches the same requests, with suggestions for
+// telling them apart.
+func describeConflict(p1, p2 *pattern) string {
+	var b strings.Builder
+	if p1.str == p2.str {
+		fmt.Fprintf(&b, "exact pattern already registered at %s", p1.loc)
+	} else {
+		fmt.Fprintf(&b, "pattern %q (registered at %s) matches the same requests as %q (registered at %s)",
+			p1, p1.loc, p2, p2.loc)
+	}
+	if fixes := suggestFixes(p1, p2); len(fixes) > 0 {
+		b.WriteString("; possible fixes:")
+		for _, fix := range fixes {
+			b.WriteString("\n\t- ")
+			b.WriteString(fix)
+		}
+	}
+	return b.String()
+}
+
+// suggestFixes returns changes to p2 that would stop it from conflicting
+// with p1, computed from the structure of both patterns. The patterns
+// must occupy the same leaf of the routing tree, so they have the same
+// host, method and number of segments.
+func suggestFixes(p1, p2 *pattern) []string {
+	var fixes []string
+	if p1.str == p2.str {
+		fixes = append(fixes, fmt.Sprintf("remove one of the registrations, or call Remove(%q) before registering it again", p1))
+	}
+	for i, s1 := range p1.segments {
+		s2 := p2.segments[i]
+		switch {
+		case s1.multi || s2.multi:
+		case s1.enum != nil && s2.enum != nil:
+			fixes = append(fixes, fmt.Sprintf("remove %s from the literals of {%s}, as they are already matched by {%s}",
+				quoteList(intersect(s1.enum, s2.enum)), s2.s, s1.s))
+		case s1.enum != nil && !s2.wild:
+			fixes = append(fixes, fmt.Sprintf("exclude %q from {%s}, or drop segment %d of %q as it is already matched by {%s}",
+				s2.s, s1.s, i+1, p2, s1.s))
+		case s2.enum != nil && !s1.wild:
+			fixes = append(fixes, fmt.Sprintf("remove %q from the literals of {%s}, as it is already matched by %q",
+				s1.s, s2.s, p1))
+		case s1.wild && s2.wild && s1.enum == nil && s2.enum == nil:
+			fixes = append(fixes, fmt.Sprintf("replace {%s} with a literal, or constrain it with {%s:(a|b)}, to make %q more specific",
+				s2.s, s2.s, p2))
+		}
+	}
+	if last := p2.lastSegment(); last.multi && last.s == "" {
+		fixes = append(fixes, fmt.Sprintf("use %q to match only the path ending in a slash", p2.str+"{$}"))
+	}
+	if p2.method == "" {
+		fixes = append(fixes, fmt.Sprintf("add a method, as in %q, since patterns with different methods don't conflict", "GET "+p2.str))
+	}
+	return fixes
+}
+
+// intersect returns the elements of a also in b, in order.
+func intersect(a, b []string) []string {
+	var common []string
+	for _, s := range a {
+		for _, t := range b {
+			if s == t {
+				common = append(common, s)
+				break
+			}
+		}
+	}
+	return common
+}
+
+func quoteList(list []string) string {
+	q := make([]string, len(list))
+	for i, s := range list {
+		q[i] = fmt.Sprintf("%q", s)
+	}
+	return strings.Join(q, ", ")
+}
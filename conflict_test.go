@@ -0,0 +1,49 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDescribeConflict(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   []string
+	}{
+		{"/a", "/a", []string{
+			"exact pattern already registered at",
+			`call Remove("/a") before registering it again`,
+			`add a method, as in "GET /a"`,
+		}},
+		{"GET /users/{id}", "GET /users/{name}", []string{
+			`matches the same requests as "GET /users/{name}"`,
+			`replace {name} with a literal, or constrain it with {name:(a|b)}`,
+		}},
+		{"/{x}/", "/{y}/", []string{
+			`use "/{y}/{$}" to match only the path ending in a slash`,
+		}},
+		{"/e/{f:(json|xml|csv)}", "/e/{g:(csv|xml)}", []string{
+			`remove "xml", "csv" from the literals of {g}`,
+		}},
+		{"/e/{f:(json|xml)}", "/e/json", []string{
+			`exclude "json" from {f}`,
+		}},
+		{"/e/json", "/e/{f:(json|xml)}", []string{
+			`remove "json" from the literals of {f}, as it is already matched by "/e/json"`,
+		}},
+	} {
+		mux := NewServeMux()
+		mux.Handle(test.p1, http.NotFoundHandler())
+		err := mux.registerErr(test.p2, http.NotFoundHandler())
+		if err == nil {
+			t.Errorf("%s, %s: got nil error", test.p1, test.p2)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s, %s: error\n%s\ndoes not contain %q", test.p1, test.p2, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package shortmux
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// Content is seekable content served by [ServeMux.HandleContent].
+type Content struct {
+	// Body is the content. If nil, the content is read from ReaderAt.
+	// If Body implements io.Closer, it is closed once served.
+	Body io.ReadSeeker
+
+	// ReaderAt and Size describe the content when Body is nil.
+	// If ReaderAt implements io.Closer, it is closed once served.
+	ReaderAt io.ReaderAt
+	Size     int64
+
+	// Name is used to detect the Content-Type from its extension, if the
+	// response has none. It defaults to the requested name.
+	Name string
+
+	// ModTime, if not zero, is sent as Last-Modified and used for
+	// conditional requests.
+	ModTime time.Time
+
+	// ETag, if not empty, is sent as ETag and used for conditional
+	// requests, including If-Range. It must be a quoted string.
+	ETag string
+}
+
+// HandleContent registers for pattern, which must end in a "..." wildcard
+// such as "GET /files/{name...}", a handler serving the content returned
+// by open for the value of the wildcard, with support for Range,
+// If-Range and the other conditional requests, as with [http.ServeContent].
+//
+// If open returns an error matching [fs.ErrNotExist] or
+// [fs.ErrPermission], the response is 404 Not Found or 403 Forbidden
+// respectively; for any other error, it is 500 Internal Server Error.
+func (mux *ServeMux) HandleContent(pattern string, open func(r *http.Request, name string) (*Content, error), opts ...RouteOption) {
+	if open == nil {
+		panic("http: nil handler")
+	}
+	p, err := mux.parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	last := p.lastSegment()
+	if !last.multi || last.s == "" {
+		panic("shortmux: content pattern " + pattern + " must end in a \"...\" wildcard")
+	}
+	mux.register(pattern, &contentHandler{mux: mux, param: last.s, open: open}, opts)
+}
+
+// contentHandler serves the content returned by open for the value of the
+// wildcard param.
+type contentHandler struct {
+	mux   *ServeMux
+	param string
+	open  func(r *http.Request, name string) (*Content, error)
+}
+
+func (h *contentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue(h.param)
+	c, err := h.open(r, name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		h.mux.Error(w, r, http.StatusNotFound)
+		return
+	case errors.Is(err, fs.ErrPermission):
+		h.mux.errorText(w, r, http.StatusForbidden, "403 Forbidden")
+		return
+	case err != nil:
+		h.mux.errorText(w, r, http.StatusInternalServerError, "500 Internal Server Error")
+		return
+	}
+	body := c.Body
+	var closer any = c.Body
+	if body == nil {
+		body = io.NewSectionReader(c.ReaderAt, 0, c.Size)
+		closer = c.ReaderAt
+	}
+	if cl, ok := closer.(io.Closer); ok {
+		defer cl.Close()
+	}
+	if c.ETag != "" {
+		w.Header().Set("ETag", c.ETag)
+	}
+	if c.Name != "" {
+		name = c.Name
+	}
+	http.ServeContent(w, r, name, c.ModTime, body)
+}
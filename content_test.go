@@ -0,0 +1,77 @@
+package shortmux
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleContent(t *testing.T) {
+	const data = "0123456789"
+	mod := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mux := NewServeMux()
+	mux.HandleContent("GET /files/{name...}", func(r *http.Request, name string) (*Content, error) {
+		switch name {
+		case "a.txt":
+			return &Content{Body: strings.NewReader(data), ModTime: mod, ETag: `"v1"`}, nil
+		case "b":
+			return &Content{ReaderAt: strings.NewReader(data), Size: int64(len(data)), Name: "b.txt"}, nil
+		case "secret":
+			return nil, fs.ErrPermission
+		}
+		return nil, fs.ErrNotExist
+	})
+
+	for _, test := range []struct {
+		path   string
+		header map[string]string
+		status int
+		body   string
+	}{
+		{"/files/a.txt", nil, 200, data},
+		{"/files/a.txt", map[string]string{"Range": "bytes=2-4"}, 206, "234"},
+		{"/files/a.txt", map[string]string{"Range": "bytes=2-4", "If-Range": `"v1"`}, 206, "234"},
+		{"/files/a.txt", map[string]string{"Range": "bytes=2-4", "If-Range": `"v0"`}, 200, data},
+		{"/files/a.txt", map[string]string{"If-None-Match": `"v1"`}, 304, ""},
+		{"/files/a.txt", map[string]string{"Range": "bytes=20-"}, 416, ""},
+		{"/files/b", map[string]string{"Range": "bytes=-3"}, 206, "789"},
+		{"/files/secret", nil, 403, ""},
+		{"/files/missing", nil, 404, ""},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s %v: status %d, want %d", test.path, test.header, w.Code, test.status)
+			continue
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%s %v: body %q, want %q", test.path, test.header, w.Body, test.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/files/b", nil))
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+}
+
+func TestHandleContentPattern(t *testing.T) {
+	for _, pat := range []string{"/files/{name}", "/files/"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("HandleContent(%q) did not panic", pat)
+				}
+			}()
+			NewServeMux().HandleContent(pat, func(*http.Request, string) (*Content, error) { return nil, nil })
+		}()
+	}
+}
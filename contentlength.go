@@ -0,0 +1,24 @@
+package shortmux
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// verifyLength reports the response to r, written through w, if it
+// declared a Content-Length other than the length of its body.
+func (mux *ServeMux) verifyLength(w *instrumentedWriter, r *http.Request) {
+	if w.status == 0 || w.hijacked || w.declared < 0 || r.Method == "HEAD" || !bodyAllowedForStatus(w.status) {
+		return
+	}
+	written := w.written + w.excess
+	if written == w.declared {
+		return
+	}
+	if mux.OnContentLengthMismatch != nil {
+		mux.OnContentLengthMismatch(r, w.declared, written)
+		return
+	}
+	slog.Error("shortmux: Content-Length mismatch", "pattern", r.Pattern, "method", r.Method,
+		"path", r.URL.Path, "declared", w.declared, "written", written)
+}
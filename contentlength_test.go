@@ -0,0 +1,53 @@
+package shortmux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyContentLength(t *testing.T) {
+	type mismatch struct{ declared, written int64 }
+	var got []mismatch
+	mux := NewServeMux()
+	mux.VerifyContentLength = true
+	mux.OnContentLengthMismatch = func(r *http.Request, declared, written int64) {
+		got = append(got, mismatch{declared, written})
+	}
+	var writeErr error
+	mux.HandleFunc("/{cl}/{body}", func(w http.ResponseWriter, r *http.Request) {
+		if cl := r.PathValue("cl"); cl != "-" {
+			w.Header().Set("Content-Length", cl)
+		}
+		if r.URL.Query().Has("nc") {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		_, writeErr = fmt.Fprint(w, r.PathValue("body"))
+	})
+
+	for _, test := range []struct {
+		method, target string
+		want           []mismatch
+	}{
+		{"GET", "/5/hello", nil},
+		{"GET", "/-/hello", nil},
+		{"GET", "/8/hello", []mismatch{{8, 5}}},
+		{"GET", "/3/hello", []mismatch{{3, 5}}},
+		{"HEAD", "/8/hello", nil},
+		{"GET", "/8/hello?nc", nil},
+	} {
+		got, writeErr = nil, nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s %s: got %v, want %v", test.method, test.target, got, test.want)
+		}
+		if test.target == "/3/hello" {
+			if !errors.Is(writeErr, http.ErrContentLength) || w.Body.String() != "hel" {
+				t.Errorf("%s: got %q, %v, want %q, %v", test.target, w.Body, writeErr, "hel", http.ErrContentLength)
+			}
+		}
+	}
+}
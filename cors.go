@@ -0,0 +1,185 @@
+package shortmux
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A CORSPolicy configures Cross-Origin Resource Sharing for a route,
+// following the CORS protocol of the Fetch standard.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins allowed to make requests,
+	// e.g. "https://example.com". The origin "*" allows any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods lists the methods allowed in preflight requests.
+	// If empty, any method the route serves is allowed.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers allowed in preflight
+	// requests, in addition to the CORS-safelisted ones.
+	// The header "*" allows any header.
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers exposed to scripts,
+	// in addition to the CORS-safelisted ones.
+	ExposedHeaders []string
+
+	// AllowCredentials allows requests with credentials (cookies, HTTP
+	// authentication). The origin and headers are then always listed
+	// explicitly, as browsers don't honor "*" for such requests.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache the preflight response, in
+	// whole seconds. Zero omits Access-Control-Max-Age, so browsers use
+	// their default of 5 seconds; negative values disable caching.
+	MaxAge time.Duration
+
+	// AdjustPreflight, if non-nil, is called with the origin and the
+	// headers of each successful preflight response before it is sent,
+	// e.g. to use a different max age for some origins.
+	AdjustPreflight func(origin string, h http.Header)
+}
+
+// WithCORS applies the CORS policy p to the route.
+//
+// Responses to requests with an allowed Origin carry the
+// Access-Control-Allow-Origin header and related ones.
+// Preflight requests (OPTIONS requests with an Access-Control-Request-Method
+// header) are answered by the mux with 204 No Content on behalf of the route
+// matching the requested method, unless a pattern with the OPTIONS method
+// matches the request.
+//
+// Vary is set to the request headers each response depends on, so shared
+// caches keep responses for different origins apart.
+func WithCORS(p *CORSPolicy) RouteOption {
+	if p == nil {
+		panic("shortmux: nil CORS policy")
+	}
+	return func(c *routeConfig) {
+		c.cors = p
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				p.setOrigin(w.Header(), r.Header.Get("Origin"))
+				if len(p.ExposedHeaders) > 0 && w.Header().Get("Access-Control-Allow-Origin") != "" {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// anyOrigin reports whether any origin is allowed with a literal "*".
+func (p *CORSPolicy) anyOrigin() bool {
+	return !p.AllowCredentials && slices.Contains(p.AllowedOrigins, "*")
+}
+
+// setOrigin sets the headers allowing origin, if it is allowed, and the
+// matching Vary header. It reports whether origin is allowed.
+func (p *CORSPolicy) setOrigin(h http.Header, origin string) bool {
+	if p.anyOrigin() {
+		// The response doesn't depend on the origin.
+		if origin == "" {
+			return false
+		}
+		h.Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+	h.Add("Vary", "Origin")
+	if origin == "" || !(slices.Contains(p.AllowedOrigins, origin) || slices.Contains(p.AllowedOrigins, "*")) {
+		return false
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// preflight returns the handler answering r if it is a CORS preflight
+// request for a route with a CORS policy, and the pattern of that route.
+// n is the node matching the request itself, if any.
+func (mux *ServeMux) preflight(r *http.Request, host, path string, n *routingNode) (http.Handler, string) {
+	if r.Method != "OPTIONS" || r.Header.Get("Origin") == "" {
+		return nil, ""
+	}
+	method := r.Header.Get("Access-Control-Request-Method")
+	if method == "" || (n != nil && n.pattern.method == "OPTIONS") {
+		return nil, ""
+	}
+	target, _, _, _ := mux.matchOrRedirect(host, method, path, nil, nil, nil)
+	if target == nil || target.route == nil || target.route.cfg.cors == nil {
+		return nil, ""
+	}
+	p := target.route.cfg.cors
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.servePreflight(w, r, method)
+	}), target.pattern.String()
+}
+
+// servePreflight answers a preflight request for method.
+// If the request is not allowed, the response carries no CORS headers, and
+// the browser fails the CORS check.
+func (p *CORSPolicy) servePreflight(w http.ResponseWriter, r *http.Request, method string) {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+	reqHeaders := parseHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+	// The route, the allowed methods and the allowed headers all depend on
+	// these request headers.
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if p.allowsPreflight(method, reqHeaders) {
+		if p.setOrigin(h, origin) {
+			h.Set("Access-Control-Allow-Methods", method)
+			if len(reqHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+			}
+			switch {
+			case p.MaxAge > 0:
+				h.Set("Access-Control-Max-Age", strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+			case p.MaxAge < 0:
+				h.Set("Access-Control-Max-Age", "0")
+			}
+			if p.AdjustPreflight != nil {
+				p.AdjustPreflight(origin, h)
+			}
+		}
+	} else if !p.anyOrigin() {
+		h.Add("Vary", "Origin")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowsPreflight reports whether the method and request headers are allowed.
+func (p *CORSPolicy) allowsPreflight(method string, headers []string) bool {
+	if len(p.AllowedMethods) > 0 && !slices.Contains(p.AllowedMethods, method) {
+		return false
+	}
+	if slices.Contains(p.AllowedHeaders, "*") && !p.AllowCredentials {
+		return true
+	}
+	for _, name := range headers {
+		if !slices.ContainsFunc(p.AllowedHeaders, func(s string) bool { return strings.EqualFold(s, name) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList parses comma-separated header names into lowercase
+// names, as the Fetch standard serializes them.
+func parseHeaderList(values []string) []string {
+	var names []string
+	for _, v := range values {
+		for name := range strings.SplitSeq(v, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,147 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCORS checks the CORS protocol of the Fetch standard
+// (https://fetch.spec.whatwg.org/#http-cors-protocol).
+func TestCORS(t *testing.T) {
+	public := &CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"X-Total"},
+		MaxAge:         10 * time.Minute,
+	}
+	private := &CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example"},
+		AllowedMethods:   []string{"PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "X-Token"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+		AdjustPreflight: func(origin string, h http.Header) {
+			if origin == "https://app.example" && h.Get("Access-Control-Allow-Methods") == "DELETE" {
+				h.Set("Access-Control-Max-Age", "60")
+			}
+		},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux := NewServeMux()
+	mux.HandleFunc("GET /public", ok, WithCORS(public))
+	mux.HandleFunc("PUT /items/{id}", ok, WithCORS(private))
+	mux.HandleFunc("DELETE /items/{id}", ok, WithCORS(private))
+	mux.HandleFunc("POST /items/{id}", ok, WithCORS(private))
+	mux.HandleFunc("GET /items/{id}", ok)
+	mux.HandleFunc("OPTIONS /custom", ok)
+	mux.HandleFunc("PUT /custom", ok, WithCORS(public))
+
+	type headers map[string]string
+	for _, test := range []struct {
+		name     string
+		method   string
+		path     string
+		req      headers
+		wantCode int
+		want     headers // "" means absent
+	}{
+		{
+			"simple request, any origin", "GET", "/public",
+			headers{"Origin": "https://a.example"}, 200,
+			headers{"Access-Control-Allow-Origin": "*", "Access-Control-Expose-Headers": "X-Total", "Vary": ""},
+		},
+		{
+			"simple request without origin", "GET", "/public",
+			headers{}, 200,
+			headers{"Access-Control-Allow-Origin": "", "Access-Control-Expose-Headers": ""},
+		},
+		{
+			"credentialed request echoes origin", "PUT", "/items/1",
+			headers{"Origin": "https://app.example"}, 200,
+			headers{"Access-Control-Allow-Origin": "https://app.example", "Access-Control-Allow-Credentials": "true", "Vary": "Origin"},
+		},
+		{
+			"disallowed origin", "PUT", "/items/1",
+			headers{"Origin": "https://evil.example"}, 200,
+			headers{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			"preflight, any origin", "OPTIONS", "/public",
+			headers{"Origin": "https://a.example", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Foo, content-type"}, 204,
+			headers{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET",
+				"Access-Control-Allow-Headers": "x-foo, content-type",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			"preflight, credentialed", "OPTIONS", "/items/1",
+			headers{"Origin": "https://app.example", "Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "x-token"}, 204,
+			headers{
+				"Access-Control-Allow-Origin":      "https://app.example",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "PUT",
+				"Access-Control-Allow-Headers":     "x-token",
+				"Access-Control-Max-Age":           "3600",
+			},
+		},
+		{
+			"preflight, per-origin adjustment", "OPTIONS", "/items/1",
+			headers{"Origin": "https://app.example", "Access-Control-Request-Method": "DELETE"}, 204,
+			headers{"Access-Control-Allow-Methods": "DELETE", "Access-Control-Max-Age": "60"},
+		},
+		{
+			"preflight, method not in allowed list", "OPTIONS", "/items/1",
+			headers{"Origin": "https://app.example", "Access-Control-Request-Method": "POST"}, 204,
+			headers{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			"preflight, header not allowed", "OPTIONS", "/items/1",
+			headers{"Origin": "https://app.example", "Access-Control-Request-Method": "PUT", "Access-Control-Request-Headers": "x-other"}, 204,
+			headers{"Access-Control-Allow-Origin": "", "Access-Control-Max-Age": ""},
+		},
+		{
+			"preflight, origin not allowed", "OPTIONS", "/items/1",
+			headers{"Origin": "https://evil.example", "Access-Control-Request-Method": "PUT"}, 204,
+			headers{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			"preflight for route without policy", "OPTIONS", "/items/1",
+			headers{"Origin": "https://app.example", "Access-Control-Request-Method": "GET"}, 405,
+			headers{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			"explicit OPTIONS pattern wins", "OPTIONS", "/custom",
+			headers{"Origin": "https://a.example", "Access-Control-Request-Method": "PUT"}, 200,
+			headers{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			"OPTIONS without request method is not a preflight", "OPTIONS", "/public",
+			headers{"Origin": "https://a.example"}, 405,
+			headers{"Access-Control-Allow-Methods": ""},
+		},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		for k, v := range test.req {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.name, w.Code, test.wantCode)
+		}
+		for k, want := range test.want {
+			if got := w.Header().Get(k); got != want {
+				t.Errorf("%s: got %s %q, want %q", test.name, k, got, want)
+			}
+		}
+		if test.method == "OPTIONS" && w.Code == 204 {
+			if got := w.Header().Values("Vary"); len(got) < 2 {
+				t.Errorf("%s: got Vary %q, want the preflight request headers", test.name, got)
+			}
+		}
+	}
+}
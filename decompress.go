@@ -0,0 +1,76 @@
+package shortmux
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithRequestDecompression transparently decompresses request bodies sent
+// with a Content-Encoding of gzip or deflate before the handler reads
+// them, removing the Content-Encoding and Content-Length headers.
+//
+// To defuse compression bombs, reading more than maxBytes of decompressed
+// data fails with an [http.MaxBytesError], as with [http.MaxBytesReader].
+// Requests with a malformed body are answered with 400 Bad Request, and
+// requests with other encodings with 415 Unsupported Media Type and an
+// Accept-Encoding header listing the supported ones.
+func WithRequestDecompression(maxBytes int64) RouteOption {
+	if maxBytes <= 0 {
+		panic(fmt.Sprintf("shortmux: invalid decompression limit %d", maxBytes))
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+				var zr io.ReadCloser
+				var err error
+				switch enc {
+				case "", "identity":
+					next.ServeHTTP(w, r)
+					return
+				case "gzip", "x-gzip":
+					zr, err = gzip.NewReader(r.Body)
+				case "deflate":
+					// The deflate coding of HTTP is the zlib format
+					// (RFC 9110, section 8.4.1.2).
+					zr, err = zlib.NewReader(r.Body)
+				default:
+					w.Header().Set("Accept-Encoding", "gzip, deflate")
+					mux.Error(w, r, http.StatusUnsupportedMediaType)
+					return
+				}
+				if err != nil {
+					mux.Error(w, r, http.StatusBadRequest)
+					return
+				}
+				r2 := r.Clone(r.Context())
+				r2.Header.Del("Content-Encoding")
+				r2.Header.Del("Content-Length")
+				r2.ContentLength = -1
+				r2.Body = &decompressedBody{
+					Reader: http.MaxBytesReader(w, zr, maxBytes),
+					zr:     zr,
+					body:   r.Body,
+				}
+				next.ServeHTTP(w, r2)
+			})
+		})
+	}
+}
+
+// decompressedBody is a request body decompressed from body by zr.
+type decompressedBody struct {
+	io.Reader
+	zr   io.Closer
+	body io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	b.zr.Close()
+	return b.body.Close()
+}
@@ -0,0 +1,65 @@
+package shortmux
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestDecompression(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("POST /api", func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		io.WriteString(w, r.Header.Get("Content-Encoding")+"|"+string(b))
+	}, WithRequestDecompression(100))
+
+	var gz, zl bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("hello gzip"))
+	gw.Close()
+	zw := zlib.NewWriter(&zl)
+	zw.Write([]byte("hello deflate"))
+	zw.Close()
+	var bomb bytes.Buffer
+	gw = gzip.NewWriter(&bomb)
+	gw.Write(make([]byte, 1<<20))
+	gw.Close()
+
+	for _, test := range []struct {
+		encoding string
+		body     []byte
+		status   int
+		want     string
+	}{
+		{"", []byte("plain"), 200, "|plain"},
+		{"gzip", gz.Bytes(), 200, "|hello gzip"},
+		{"deflate", zl.Bytes(), 200, "|hello deflate"},
+		{"gzip", []byte("not gzip"), 400, ""},
+		{"br", []byte("x"), 415, ""},
+		{"gzip", bomb.Bytes(), 413, ""},
+	} {
+		r := httptest.NewRequest("POST", "/api", bytes.NewReader(test.body))
+		if test.encoding != "" {
+			r.Header.Set("Content-Encoding", test.encoding)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || test.want != "" && w.Body.String() != test.want {
+			t.Errorf("%q: status %d, body %q; want %d, %q", test.encoding, w.Code, w.Body, test.status, test.want)
+		}
+		if test.status == 415 && !strings.Contains(w.Header().Get("Accept-Encoding"), "gzip") {
+			t.Errorf("415 response without Accept-Encoding")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package shortmux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A DedupeStore records the keys of the requests seen by routes with
+// [WithWebhookDedupe]. Its methods may be called concurrently.
+type DedupeStore interface {
+	// Seen records key for window and reports whether it was already
+	// recorded and hasn't expired.
+	Seen(ctx context.Context, key string, window time.Duration) (bool, error)
+
+	// Forget removes key, so that a retry of a failed request is served.
+	Forget(ctx context.Context, key string) error
+}
+
+// WithWebhookDedupe marks the route as a webhook receiver whose deliveries
+// are identified by the event ID in the given header, such as
+// "X-GitHub-Delivery". A delivery with an ID already seen by the route
+// within window gets an empty 200 OK response without calling the handler,
+// as providers retry deliveries they consider lost.
+//
+// If store is nil, IDs are kept in memory by the route. Requests without
+// the header, or for which store fails, are served as usual. If the
+// handler responds with a 5xx status code, the ID is forgotten, so that
+// the provider can retry the delivery.
+func WithWebhookDedupe(header string, window time.Duration, store DedupeStore) RouteOption {
+	if window <= 0 {
+		panic(fmt.Sprintf("shortmux: invalid dedupe window %v", window))
+	}
+	if store == nil {
+		store = NewMemoryDedupeStore()
+	}
+	return func(c *routeConfig) {
+		// Keys are scoped by pattern, so that routes can share a store.
+		prefix := c.pat.str + "\x00"
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id := r.Header.Get(header)
+				if id == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+				key := prefix + id
+				if seen, err := store.Seen(r.Context(), key, window); err == nil && seen {
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				iw := &instrumentedWriter{ResponseWriter: w}
+				defer func() {
+					if iw.Status() >= 500 {
+						store.Forget(context.WithoutCancel(r.Context()), key)
+					}
+				}()
+				next.ServeHTTP(iw, r)
+			})
+		})
+	}
+}
+
+// NewMemoryDedupeStore returns a [DedupeStore] keeping keys in memory.
+// Expired keys are removed as new ones are recorded.
+func NewMemoryDedupeStore() DedupeStore {
+	return &memoryDedupeStore{keys: map[string]time.Time{}}
+}
+
+type memoryDedupeStore struct {
+	mu    sync.Mutex
+	keys  map[string]time.Time // expiration by key
+	sweep time.Time            // when expired keys are next removed
+}
+
+func (s *memoryDedupeStore) Seen(ctx context.Context, key string, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := nowFor(ctx)
+	if now.After(s.sweep) {
+		for k, exp := range s.keys {
+			if now.After(exp) {
+				delete(s.keys, k)
+			}
+		}
+		s.sweep = now.Add(window)
+	}
+	if exp, ok := s.keys[key]; ok && !now.After(exp) {
+		return true, nil
+	}
+	s.keys[key] = now.Add(window)
+	return false, nil
+}
+
+func (s *memoryDedupeStore) Forget(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithWebhookDedupe(t *testing.T) {
+	calls := 0
+	fail := false
+	mux := NewServeMux()
+	mux.HandleFunc("POST /hooks/github", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}, WithWebhookDedupe("X-GitHub-Delivery", 50*time.Millisecond, nil))
+
+	deliver := func(id string) int {
+		r := httptest.NewRequest("POST", "/hooks/github", nil)
+		if id != "" {
+			r.Header.Set("X-GitHub-Delivery", id)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+	for _, step := range []struct {
+		id        string
+		fail      bool
+		wantCode  int
+		wantCalls int
+	}{
+		{"1", false, 202, 1},
+		{"1", false, 200, 1}, // duplicate
+		{"2", false, 202, 2},
+		{"", false, 202, 3}, // no ID
+		{"", false, 202, 4},
+		{"3", true, 503, 5},
+		{"3", false, 202, 6}, // retry after a failure
+		{"3", false, 200, 6},
+	} {
+		fail = step.fail
+		if code := deliver(step.id); code != step.wantCode || calls != step.wantCalls {
+			t.Errorf("delivery %q: status %d, %d calls; want %d, %d calls", step.id, code, calls, step.wantCode, step.wantCalls)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if code := deliver("1"); code != 202 || calls != 7 {
+		t.Errorf("delivery after the window: status %d, %d calls", code, calls)
+	}
+}
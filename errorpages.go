@@ -0,0 +1,269 @@
+package shortmux
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrorPages configures the error responses of the mux, set as
+// [ServeMux.ErrorPages], or of a subtree, in [ServeMux.SubtreeErrorPages]:
+// 404 Not Found and 405 Method Not Allowed for requests matching no route,
+// responses written with [ServeMux.Error], and 500 Internal Server Error
+// for recovered panics.
+//
+// Clients preferring JSON in their Accept header get a problem details
+// object (RFC 9457); the others get the HTML page for the status code, or a
+// plain text response if there is none.
+type ErrorPages struct {
+	// Pages maps status codes to templates executed with an [ErrorInfo].
+	// Default, if set, is used for the other status codes.
+	Pages   map[int]*template.Template
+	Default *template.Template
+
+	// Recover, if true, recovers panics of handlers, other than
+	// [http.ErrAbortHandler], and responds with 500 Internal Server Error
+	// if the response wasn't committed yet. The panic is logged with its
+	// stack trace. Otherwise, panics reach the server, as usual.
+	Recover bool
+
+	// RedactWildcards, if true, keeps the wildcard values of the request
+	// out of the logs of recovered panics, so that tokens in URLs aren't
+	// logged: the request is logged with the path of its pattern, such as
+	// "/reset/{token}", and the values are replaced by the names of their
+	// wildcards in the panic message.
+	RedactWildcards bool
+
+	// LogHeaders, if true, adds the request headers to the logs of
+	// recovered panics. The values of the headers whose canonical names
+	// match one of the patterns of SecretHeaders, as for [path.Match],
+	// such as "X-*-Token", are redacted, as are those of Authorization,
+	// Proxy-Authorization and Cookie.
+	LogHeaders    bool
+	SecretHeaders []string
+
+	// ProblemJSON, if true, answers with a problem details object whatever
+	// the Accept header of the request, as APIs do.
+	ProblemJSON bool
+}
+
+// An ErrorInfo is the data error page templates are executed with.
+type ErrorInfo struct {
+	Status int    // status code, e.g. 404
+	Title  string // status text, e.g. "Not Found"
+	Method string // request method
+	Path   string // request path
+}
+
+// ErrorPageFile returns a template for [ErrorPages] parsed from the file
+// at path.
+func ErrorPageFile(path string) (*template.Template, error) {
+	return template.ParseFiles(path)
+}
+
+// Error replies to r with the error page for status, as configured by the
+// mux ErrorPages, or with a plain text error if there is none. Headers
+// already set on w, such as Allow, are kept.
+func (mux *ServeMux) Error(w http.ResponseWriter, r *http.Request, status int) {
+	text := http.StatusText(status)
+	if status == http.StatusNotFound {
+		text = "404 page not found" // as http.NotFound
+	}
+	mux.errorText(w, r, status, text)
+}
+
+// errorText is like Error, with text as the plain text error.
+func (mux *ServeMux) errorText(w http.ResponseWriter, r *http.Request, status int, text string) {
+	ep := mux.errorPages(r)
+	if ep == nil {
+		http.Error(w, text, status)
+		return
+	}
+	info := ErrorInfo{Status: status, Title: http.StatusText(status), Method: r.Method, Path: r.URL.Path}
+	if !ep.ProblemJSON {
+		w.Header().Add("Vary", "Accept")
+	}
+	if ep.ProblemJSON || prefersJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(struct {
+			Type     string `json:"type"`
+			Title    string `json:"title"`
+			Status   int    `json:"status"`
+			Instance string `json:"instance"`
+		}{"about:blank", info.Title, status, info.Path})
+		return
+	}
+	t := ep.Pages[status]
+	if t == nil {
+		t = ep.Default
+	}
+	if t == nil {
+		http.Error(w, text, status)
+		return
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, info); err != nil {
+		slog.Error("shortmux: rendering error page", "status", status, "error", err)
+		http.Error(w, text, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// errorPages returns the ErrorPages of the requests for the host and path
+// of r: those of the longest subtree of SubtreeErrorPages containing r,
+// or ErrorPages. Of subtrees of the same length, those with a host win,
+// and then the first in lexical order, so the choice doesn't depend on
+// the map order.
+func (mux *ServeMux) errorPages(r *http.Request) *ErrorPages {
+	ep, best := mux.ErrorPages, ""
+	if len(mux.SubtreeErrorPages) == 0 {
+		return ep
+	}
+	host := mux.matchHost(stripHostPort(r.Host))
+	for st, sep := range mux.SubtreeErrorPages {
+		if precedesSubtree(st, best) && inSubtree(st, host, r.URL.Path) {
+			ep, best = sep, st
+		}
+	}
+	return ep
+}
+
+// precedesSubtree reports whether the subtree st takes precedence over
+// the subtree best, or best is empty.
+func precedesSubtree(st, best string) bool {
+	switch {
+	case best == "" || len(st) != len(best):
+		return len(st) > len(best)
+	case (st[0] == '/') != (best[0] == '/'):
+		return st[0] != '/'
+	}
+	return st < best
+}
+
+// errorHandler returns a handler replying with the error page for status.
+func (mux *ServeMux) errorHandler(status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Error(w, r, status)
+	})
+}
+
+// recoverPanic handles the value v recovered from a panic of the handler
+// serving r, as described in [ErrorPages].
+func (mux *ServeMux) recoverPanic(v any, w *instrumentedWriter, r *http.Request) {
+	if v == http.ErrAbortHandler || w.status != 0 {
+		panic(v)
+	}
+	mux.logPanic(v, r, "")
+	mux.Error(w, r, http.StatusInternalServerError)
+}
+
+// logPanic logs the value v recovered from a panic of the handler serving
+// r, with the stack and what is done about it, if note isn't empty,
+// redacted as set by the ErrorPages of r.
+func (mux *ServeMux) logPanic(v any, r *http.Request, note string) {
+	ep := mux.errorPages(r)
+	path, msg := r.URL.Path, fmt.Sprint(v)
+	if ep != nil && ep.RedactWildcards {
+		path, msg = mux.redactWildcards(r, msg)
+	}
+	args := []any{"error", msg, "stack", string(debug.Stack())}
+	if ep != nil && ep.LogHeaders {
+		args = append(args, "headers", redactHeaders(r.Header, ep.SecretHeaders))
+	}
+	if note != "" {
+		path += "; " + note
+	}
+	slog.Error("shortmux: panic serving "+path, args...)
+}
+
+// redacted replaces secrets in logs.
+const redacted = "[REDACTED]"
+
+// redactWildcards returns the path of the pattern matched by r, and msg
+// with the wildcard values of r replaced by the names of their wildcards.
+// Unmatched requests have no wildcard values, and keep their path.
+func (mux *ServeMux) redactWildcards(r *http.Request, msg string) (string, string) {
+	if r.Pattern == "" {
+		return r.URL.Path, msg
+	}
+	p, err := mux.parsePattern(r.Pattern)
+	if err != nil {
+		return redacted, redacted
+	}
+	path := p.str[strings.IndexByte(p.str, '/'):]
+	for _, seg := range p.segments {
+		if !seg.wild {
+			continue
+		}
+		name := seg.s
+		if name == "" {
+			name = "..."
+			path += "{...}"
+		}
+		if v := r.PathValue(name); v != "" {
+			msg = strings.ReplaceAll(msg, v, "{"+name+"}")
+		}
+	}
+	return path, msg
+}
+
+// alwaysSecretHeaders are the headers whose values are never logged.
+var alwaysSecretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redactHeaders returns a copy of h, with the values of the headers whose
+// names match one of the patterns of secret, or of alwaysSecretHeaders,
+// redacted.
+func redactHeaders(h http.Header, secret []string) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if slices.Contains(alwaysSecretHeaders, k) || slices.ContainsFunc(secret, func(p string) bool {
+			ok, _ := path.Match(http.CanonicalHeaderKey(p), k)
+			return ok
+		}) {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = slices.Clone(vs)
+	}
+	return out
+}
+
+// prefersJSON reports whether the Accept header value accept ranks a JSON
+// media type above HTML. Wildcards count for HTML only.
+func prefersJSON(accept string) bool {
+	var jsonQ, htmlQ float64
+	for mr := range strings.SplitSeq(accept, ",") {
+		typ, params, _ := strings.Cut(strings.TrimSpace(mr), ";")
+		q := 1.0
+		for p := range strings.SplitSeq(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil || f < 0 || f > 1 {
+					f = 0
+				}
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(typ)) {
+		case "application/json", "application/problem+json":
+			jsonQ = max(jsonQ, q)
+		case "text/html", "text/*", "*/*":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+	return jsonQ > htmlQ
+}
@@ -0,0 +1,257 @@
+package shortmux
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorPages(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "404.html")
+	if err := os.WriteFile(file, []byte(`<h1>{{.Path}} not found</h1>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := ErrorPageFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{
+		Pages:   map[int]*template.Template{404: notFound},
+		Default: template.Must(template.New("").Parse(`<h1>{{.Status}} {{.Title}}</h1>`)),
+		Recover: true,
+	}
+	mux.HandleFunc("GET /items", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		mux.Error(w, r, http.StatusForbidden)
+	})
+
+	for _, test := range []struct {
+		method, path, accept string
+		status               int
+		contentType, body    string
+	}{
+		{"GET", "/missing", "text/html", 404, "text/html; charset=utf-8", "<h1>/missing not found</h1>"},
+		{"POST", "/items", "", 405, "text/html; charset=utf-8", "<h1>405 Method Not Allowed</h1>"},
+		{"GET", "/panic", "*/*", 500, "text/html; charset=utf-8", "<h1>500 Internal Server Error</h1>"},
+		{"GET", "/forbidden", "", 403, "text/html; charset=utf-8", "<h1>403 Forbidden</h1>"},
+		{"GET", "/missing", "application/json", 404, "application/problem+json",
+			`{"type":"about:blank","title":"Not Found","status":404,"instance":"/missing"}`},
+		{"GET", "/missing", "text/html;q=0.5, application/json", 404, "application/problem+json", ""},
+		{"GET", "/missing", "application/json;q=0.5, text/html", 404, "text/html; charset=utf-8", ""},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("%s %s, Accept %q: status %d, Content-Type %q; want %d, %q", test.method, test.path, test.accept,
+				w.Code, w.Header().Get("Content-Type"), test.status, test.contentType)
+		}
+		if got := strings.TrimSpace(w.Body.String()); test.body != "" && got != test.body {
+			t.Errorf("%s %s, Accept %q: body %s, want %s", test.method, test.path, test.accept, got, test.body)
+		}
+		if test.status == 405 && w.Header().Get("Allow") != "GET, HEAD" {
+			t.Errorf("405 response without Allow: %v", w.Header())
+		}
+	}
+}
+
+func TestSubtreeErrorPages(t *testing.T) {
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{
+		Default: template.Must(template.New("").Parse(`<h1>{{.Status}}</h1>`)),
+	}
+	api := &ErrorPages{ProblemJSON: true, Recover: true}
+	mux.SubtreeErrorPages = map[string]*ErrorPages{
+		"/api/":              api,
+		"/api/legacy/":       {},
+		"admin.example.com/": {Default: template.Must(template.New("").Parse(`admin {{.Status}}`))},
+	}
+	mux.HandleFunc("/api/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/api/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		mux.Error(w, r, http.StatusForbidden)
+	})
+
+	for _, test := range []struct {
+		host, path        string
+		status            int
+		contentType, body string
+	}{
+		{"example.com", "/missing", 404, "text/html; charset=utf-8", "<h1>404</h1>"},
+		{"example.com", "/api/missing", 404, "application/problem+json",
+			`{"type":"about:blank","title":"Not Found","status":404,"instance":"/api/missing"}`},
+		{"example.com", "/api", 404, "application/problem+json", ""},
+		{"example.com", "/api/forbidden", 403, "application/problem+json", ""},
+		{"example.com", "/api/panic", 500, "application/problem+json", ""},
+		{"example.com", "/api/legacy/x", 404, "text/plain; charset=utf-8", "404 page not found"},
+		{"admin.example.com", "/missing", 404, "text/html; charset=utf-8", "admin 404"},
+	} {
+		r := httptest.NewRequest("GET", "http://"+test.host+test.path, nil)
+		r.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Content-Type") != test.contentType {
+			t.Errorf("%s%s: status %d, Content-Type %q; want %d, %q", test.host, test.path,
+				w.Code, w.Header().Get("Content-Type"), test.status, test.contentType)
+		}
+		if got := strings.TrimSpace(w.Body.String()); test.body != "" && got != test.body {
+			t.Errorf("%s%s: body %q, want %q", test.host, test.path, got, test.body)
+		}
+	}
+}
+
+func TestErrorPagesAbort(t *testing.T) {
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{Recover: true}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) })
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestErrorPagesRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{
+		Recover:         true,
+		RedactWildcards: true,
+		LogHeaders:      true,
+		SecretHeaders:   []string{"x-*-token"},
+	}
+	mux.HandleFunc("/reset/{token}", func(w http.ResponseWriter, r *http.Request) {
+		panic("bad token " + r.PathValue("token"))
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		panic("no file")
+	})
+	for _, target := range []string{"/reset/s3cr3t", "/files/private/s3cr3t"} {
+		r := httptest.NewRequest("GET", target, nil)
+		r.Header.Set("Authorization", "Bearer s3cr3t")
+		r.Header.Set("X-Api-Token", "s3cr3t")
+		r.Header.Set("Accept-Language", "en")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != 500 {
+			t.Errorf("%s: got %d, want 500", target, w.Code)
+		}
+	}
+	logs := buf.String()
+	if strings.Contains(logs, "s3cr3t") {
+		t.Errorf("secret logged:\n%s", logs)
+	}
+	for _, want := range []string{
+		`"msg":"shortmux: panic serving /reset/{token}"`,
+		`"error":"bad token {token}"`,
+		`"msg":"shortmux: panic serving /files/{...}"`,
+		`"Accept-Language":["en"]`,
+		`"X-Api-Token":["[REDACTED]"]`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("missing %s in logs:\n%s", want, logs)
+		}
+	}
+}
+
+func TestErrorPagesRouteOptions(t *testing.T) {
+	mux := NewServeMux()
+	mux.SubtreeErrorPages = map[string]*ErrorPages{"/api/": {ProblemJSON: true}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.Copy(io.Discard, r.Body) })
+	mux.Handle("/api/gz", ok, WithRequestDecompression(1<<10))
+	mux.Handle("/api/expect", ok, WithExpectContinue(4))
+	mux.Handle("/api/replay", ok, WithReplayableBody(4))
+
+	for _, test := range []struct {
+		method, path, encoding, body string
+		status                       int
+	}{
+		{"POST", "/api/gz", "br", "x", 415},
+		{"POST", "/api/gz", "gzip", "not gzip", 400},
+		{"POST", "/api/expect", "", "too large", 413},
+		{"POST", "/api/replay", "", "too large", 413},
+	} {
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.encoding != "" {
+			r.Header.Set("Content-Encoding", test.encoding)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%s %s: status %d, Content-Type %q; want %d, problem details", test.method, test.path,
+				w.Code, w.Header().Get("Content-Type"), test.status)
+		}
+	}
+}
+
+func TestErrorPagesMuxErrors(t *testing.T) {
+	mux := NewServeMux()
+	mux.ErrorPages = &ErrorPages{}
+	mux.StrictRequests = true
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/auth", ok, WithAuth(func(*http.Request) bool { return false }))
+	mux.Handle("/tls", ok, WithTLS())
+	mux.Handle("/h2", ok, WithMinProto(2, 0))
+	mux.Handle("/body", ok, WithRequireBody())
+	mux.Handle("/signed", ok, WithSignedURL([]byte("key")))
+	mux.Handle("/transform", ok, WithResponseTransform(func(*http.Request, *BufferedResponse) error {
+		return errors.New("failed")
+	}))
+
+	for _, test := range []struct {
+		target string
+		status int
+	}{
+		{"/auth", 403},
+		{"/tls", 426},
+		{"/h2", 505},
+		{"/body", 400},
+		{"/signed", 403},
+		{"/transform", 500},
+		{"http://example.com/auth", 400}, // absolute-form
+	} {
+		r := httptest.NewRequest("POST", test.target, nil)
+		r.TLS = nil
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("POST %s: status %d, Content-Type %q; want %d, problem details", test.target,
+				w.Code, w.Header().Get("Content-Type"), test.status)
+		}
+	}
+}
+
+func TestSubtreeErrorPagesTie(t *testing.T) {
+	mux := NewServeMux()
+	mux.SubtreeErrorPages = map[string]*ErrorPages{
+		"a.com/api/": {ProblemJSON: true},
+		"/api/v1/x/": {},
+		"/api/v1/y/": {},
+	}
+	for range 20 {
+		r := httptest.NewRequest("GET", "http://a.com/api/v1/x/z", nil)
+		if ep := mux.errorPages(r); ep == nil || !ep.ProblemJSON {
+			t.Fatalf("got %+v, want the pages of a.com/api/", ep)
+		}
+	}
+	if !precedesSubtree("/api/v1/x/", "/api/v1/y/") || precedesSubtree("/api/v1/y/", "/api/v1/x/") {
+		t.Error("subtrees of the same length aren't ordered lexically")
+	}
+}
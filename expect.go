@@ -0,0 +1,44 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithExpectContinue limits the request bodies of the route to maxBytes,
+// deciding on requests with an "Expect: 100-continue" header before their
+// body is sent: a request whose Content-Length exceeds maxBytes is
+// answered with 413 Content Too Large, and the client, still waiting for
+// the 100 Continue response the server sends when the body is first read,
+// doesn't upload it.
+//
+// Requests whose length is unknown until their body is read fail to read
+// more than maxBytes with an [http.MaxBytesError]. Routes may combine the
+// option with [WithAuth] and other checks run before the handler, which
+// then also answer before the body is sent.
+func WithExpectContinue(maxBytes int64) RouteOption {
+	if maxBytes < 0 {
+		panic(fmt.Sprintf("shortmux: invalid body limit %d", maxBytes))
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.ContentLength > maxBytes {
+					if strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+						// The unsent body can't be skipped on this
+						// connection.
+						w.Header().Set("Connection", "close")
+					}
+					mux.Error(w, r, http.StatusRequestEntityTooLarge)
+					return
+				}
+				if r.Body != nil && r.Body != http.NoBody {
+					r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package shortmux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithExpectContinue(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("PUT /upload", func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		fmt.Fprint(w, len(b))
+	}, WithExpectContinue(10))
+
+	for _, test := range []struct {
+		body   string
+		length int64
+		status int
+	}{
+		{"small", 5, 200},
+		{"too large body", 14, 413},
+		{"too large body", -1, 413}, // unknown length
+	} {
+		r := httptest.NewRequest("PUT", "/upload", strings.NewReader(test.body))
+		r.ContentLength = test.length
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%q with length %d: status %d, want %d", test.body, test.length, w.Code, test.status)
+		}
+	}
+}
+
+func TestExpectContinueBeforeBody(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("PUT /upload", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}, WithExpectContinue(10))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// Send the headers only, as a client waiting for 100 Continue does.
+	fmt.Fprintf(conn, "PUT /upload HTTP/1.1\r\nHost: example.com\r\nContent-Length: 1000\r\nExpect: 100-continue\r\n\r\n")
+	var b bytes.Buffer
+	io.Copy(&b, conn)
+	if !strings.HasPrefix(b.String(), "HTTP/1.1 413 ") {
+		t.Errorf("got response %q, want 413 without 100 Continue", b.String())
+	}
+}
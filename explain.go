@@ -0,0 +1,119 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// An Explanation describes how a [ServeMux] routes a request.
+type Explanation struct {
+	Method string `json:"method"`
+	Host   string `json:"host"` // without port
+	Path   string `json:"path"` // escaped and cleaned
+
+	// Pattern is the pattern of the matched route, or empty if the mux
+	// responds by itself, e.g. with a redirect or a Not Found error.
+	Pattern string      `json:"pattern,omitempty"`
+	Params  []PathParam `json:"params,omitempty"` // in pattern order
+
+	// Status is the status code of the response of the mux, or 200 if
+	// the request is dispatched to the route handler.
+	Status   int    `json:"status"`
+	Redirect string `json:"redirect,omitempty"` // Location of a redirect
+
+	// Allow lists the methods allowed for the host and path when the
+	// method is not, i.e. when Status is 405.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// A PathParam is a wildcard of a pattern with the path segments it matched.
+type PathParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Explain reports how mux would route r, without calling any route
+// handler. It is meant for debugging and tooling. Requests the mux rejects
+// or redirects before routing, as set by StrictRequests, StrictMethods,
+// CanonicalHost and HTTPS, are reported with the mux response.
+func (mux *ServeMux) Explain(r *http.Request) *Explanation {
+	host := r.Host
+	path := r.URL.EscapedPath()
+	if r.Method != "CONNECT" {
+		host = mux.matchHost(stripHostPort(host))
+		path = cleanPath(path)
+	}
+	e := &Explanation{
+		Method: r.Method,
+		Host:   host,
+		Path:   path,
+		Status: http.StatusOK,
+	}
+	// The mux may respond by itself; record what it would send. r is
+	// copied, as answering may set its pattern.
+	r = r.WithContext(r.Context())
+	w := &headerRecorder{header: http.Header{}}
+	if mux.preempt(w, r) {
+		e.record(w)
+		return e
+	}
+	h, _, n, matches, _ := mux.findHandler(r)
+	if mux.rejectMatch(w, r, n) {
+		e.record(w)
+		return e
+	}
+	if n == nil {
+		h.ServeHTTP(w, r)
+		e.record(w)
+	} else {
+		e.Pattern = n.pattern.String()
+		e.Params = n.pattern.params(matches)
+	}
+	return e
+}
+
+// record sets the response of the mux recorded by w.
+func (e *Explanation) record(w *headerRecorder) {
+	e.Status = w.code
+	e.Redirect = w.header.Get("Location")
+	if allow := w.header.Get("Allow"); allow != "" {
+		e.Allow = strings.Split(allow, ", ")
+	}
+}
+
+// headerRecorder is a ResponseWriter recording the status code and header
+// of a response, and discarding its body.
+type headerRecorder struct {
+	header http.Header
+	code   int
+}
+
+func (w *headerRecorder) Header() http.Header { return w.header }
+
+func (w *headerRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headerRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(b), nil
+}
+
+// params pairs the named wildcards of p with their values in matches, as
+// returned by [routingNode.match].
+func (p *pattern) params(matches []string) []PathParam {
+	var params []PathParam
+	for _, seg := range p.segments {
+		switch {
+		case !seg.wild || len(matches) == 0:
+		case seg.s != "":
+			params = append(params, PathParam{Name: seg.s, Value: matches[0]})
+			matches = matches[1:]
+		case seg.multi:
+			matches = nil
+		}
+	}
+	return params
+}
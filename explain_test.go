@@ -0,0 +1,75 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	mux := NewServeMux()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { t.Error("handler called") })
+	mux.Handle("GET /{a}/x/{a}/", h)
+	mux.Handle("PUT /b/{id}", h)
+
+	for _, test := range []struct {
+		method, target string
+		want           Explanation
+	}{
+		{"GET", "/1/x/2/rest", Explanation{
+			Method: "GET", Host: "example.com", Path: "/1/x/2/rest",
+			Pattern: "GET /{a}/x/{a}/", Params: []PathParam{{"a", "1"}, {"a", "2"}}, Status: 200,
+		}},
+		{"GET", "/b/../b/7", Explanation{
+			Method: "GET", Host: "example.com", Path: "/b/7", Status: 301, Redirect: "/b/7",
+		}},
+		{"GET", "/b/7", Explanation{
+			Method: "GET", Host: "example.com", Path: "/b/7", Status: 405, Allow: []string{"PUT"},
+		}},
+	} {
+		got := mux.Explain(httptest.NewRequest(test.method, test.target, nil))
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%s %s:\ngot  %+v\nwant %+v", test.method, test.target, *got, test.want)
+		}
+	}
+}
+
+func TestExplainPreDispatch(t *testing.T) {
+	mux := NewServeMux()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { t.Error("handler called") })
+	mux.Handle("/", h)
+	mux.Handle("/account", h, WithHTTPSOnly())
+	mux.Handle("secure.example.com/", h)
+	mux.CanonicalHost("www.example.com", true)
+	mux.HTTPS = &HTTPSPolicy{Hosts: []string{"secure.example.com"}}
+	mux.StrictMethods = true
+	mux.StrictRequests = true
+
+	for _, test := range []struct {
+		method, target    string
+		status            int
+		pattern, redirect string
+	}{
+		{"GET", "http://www.example.com/a", 200, "/", ""},
+		{"GET", "http://example.com/a", 308, "", "http://www.example.com/a"},
+		{"GET", "http://secure.example.com/a", 308, "", "https://secure.example.com/a"},
+		{"GET", "http://www.example.com/account", 308, "", "https://www.example.com/account"},
+		{"BREW", "http://www.example.com/a", 501, "", ""},
+	} {
+		r := httptest.NewRequest(test.method, test.target, nil)
+		r.RequestURI = r.URL.RequestURI() // origin-form
+		got := mux.Explain(r)
+		if got.Status != test.status || got.Pattern != test.pattern || got.Redirect != test.redirect {
+			t.Errorf("%s %s: got %d %q to %q, want %d %q to %q", test.method, test.target,
+				got.Status, got.Pattern, got.Redirect, test.status, test.pattern, test.redirect)
+		}
+		w := httptest.NewRecorder()
+		if test.status != 200 {
+			mux.ServeHTTP(w, r)
+			if w.Code != got.Status {
+				t.Errorf("%s %s: served with %d, explained %d", test.method, test.target, w.Code, got.Status)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package shortmux
+
+import (
+	"bufio"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// WithFallback serves GET and HEAD requests with fallback, for example a
+// handler serving stale cached data, when the route handler fails before
+// writing anything to the client. A failure is either a panic, logged as
+// with [ErrorPages] Recover, or a response status listed in codes, or 502,
+// 503 and 504 if codes is empty.
+//
+// The failed attempt's headers and body are discarded, and the request is
+// dispatched to fallback once. Panics with [http.ErrAbortHandler], panics
+// after the response started, and panics in fallback propagate as usual.
+// Requests with other methods are never retried, as they may not be
+// idempotent.
+func WithFallback(fallback http.Handler, codes ...int) RouteOption {
+	if fallback == nil {
+		panic("shortmux: nil fallback handler")
+	}
+	if len(codes) == 0 {
+		codes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	codes = slices.Clone(codes)
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != "GET" && r.Method != "HEAD" {
+					next.ServeHTTP(w, r)
+					return
+				}
+				fw := &fallbackWriter{ResponseWriter: w, mux: mux, header: w.Header().Clone(), codes: codes}
+				if fw.serve(next, r) {
+					fallback.ServeHTTP(w, r)
+				}
+			})
+		})
+	}
+}
+
+// fallbackWriter holds back the response headers until the handler commits
+// to a status code that isn't a failure.
+type fallbackWriter struct {
+	http.ResponseWriter
+	mux       *ServeMux
+	header    http.Header
+	codes     []int
+	committed bool
+	failed    bool
+}
+
+// serve calls h and reports whether the request should be retried.
+func (w *fallbackWriter) serve(h http.Handler, r *http.Request) (retry bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler || w.committed {
+				panic(v)
+			}
+			w.mux.logPanic(v, r, "serving the fallback")
+			retry = true
+		}
+	}()
+	h.ServeHTTP(w, r)
+	return w.failed
+}
+
+func (w *fallbackWriter) Header() http.Header {
+	if w.committed {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *fallbackWriter) WriteHeader(code int) {
+	switch {
+	case w.failed:
+	case w.committed || (code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols):
+		w.ResponseWriter.WriteHeader(code)
+	case slices.Contains(w.codes, code):
+		w.failed = true
+	default:
+		w.commit()
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *fallbackWriter) Write(b []byte) (int, error) {
+	if w.failed {
+		return len(b), nil
+	}
+	if !w.committed {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// commit sends the held back headers to the underlying writer.
+func (w *fallbackWriter) commit() {
+	w.committed = true
+	h := w.ResponseWriter.Header()
+	clear(h)
+	maps.Copy(h, w.header)
+}
+
+func (w *fallbackWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *fallbackWriter) FlushError() error {
+	if w.failed {
+		return nil
+	}
+	if !w.committed {
+		w.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection, after which the request is never
+// retried.
+func (w *fallbackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.failed {
+		return nil, nil, errors.New("shortmux: hijacking the response of a failed attempt")
+	}
+	w.committed = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *fallbackWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package shortmux
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithFallback(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	stale := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Warning", `110 - "Response is Stale"`)
+		io.WriteString(w, "stale")
+	})
+	mux := NewServeMux()
+	mux.HandleFunc("/data/{mode}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Primary", "1")
+		switch r.PathValue("mode") {
+		case "panic":
+			panic("db down")
+		case "unavailable":
+			http.Error(w, "db down", http.StatusServiceUnavailable)
+		case "notfound":
+			http.Error(w, "no data", http.StatusNotFound)
+		default:
+			io.WriteString(w, "fresh")
+		}
+	}, WithFallback(stale))
+
+	for _, test := range []struct {
+		method, path string
+		wantCode     int
+		wantBody     string
+		wantPrimary  string
+	}{
+		{"GET", "/data/ok", 200, "fresh", "1"},
+		{"GET", "/data/panic", 200, "stale", ""},
+		{"GET", "/data/unavailable", 200, "stale", ""},
+		{"GET", "/data/notfound", 404, "no data\n", "1"},
+		{"POST", "/data/unavailable", 503, "db down\n", "1"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(test.method, test.path, nil))
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody || w.Header().Get("X-Primary") != test.wantPrimary {
+			t.Errorf("%s %s: got %d %q X-Primary=%q, want %d %q X-Primary=%q", test.method, test.path,
+				w.Code, w.Body.String(), w.Header().Get("X-Primary"), test.wantCode, test.wantBody, test.wantPrimary)
+		}
+	}
+
+	if log := buf.String(); !strings.Contains(log, `"msg":"shortmux: panic serving /data/panic; serving the fallback"`) ||
+		!strings.Contains(log, `"error":"db down"`) || !strings.Contains(log, "fallback.go") {
+		t.Errorf("got log %s, want the panic with its stack", log)
+	}
+
+	defer func() {
+		if v := recover(); v != "db down" {
+			t.Errorf("POST panic: got %v, want it to propagate", v)
+		}
+	}()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/data/panic", nil))
+}
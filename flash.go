@@ -0,0 +1,63 @@
+package shortmux
+
+import (
+	"net/http"
+)
+
+// A Flash is a message for the next page the client sees, such as the
+// confirmation of a form submission.
+type Flash struct {
+	Kind    string // e.g. "info" or "error", for styling
+	Message string
+}
+
+// flashKey is the session key of the pending flash messages.
+const flashKey = "shortmux.flash"
+
+// AddFlash adds a flash message to the session of r, to be shown by the
+// next request that calls [Flashes]. It panics if r has no session; see
+// [ServeMux.Sessions].
+func AddFlash(r *http.Request, kind, message string) {
+	s := mustSession(r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, _ := s.values[flashKey].([]Flash)
+	s.values[flashKey] = append(flashes[:len(flashes):len(flashes)], Flash{kind, message})
+	s.modified = true
+}
+
+// Flashes removes the pending flash messages from the session of r and
+// returns them, oldest first. It returns nil if r has no session.
+func Flashes(r *http.Request) []Flash {
+	s := SessionOf(r)
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes, _ := s.values[flashKey].([]Flash)
+	if _, ok := s.values[flashKey]; ok {
+		delete(s.values, flashKey)
+		s.modified = true
+	}
+	return flashes
+}
+
+// RedirectAfterPost answers a form submission with a 303 See Other
+// redirect to url, so that reloading the resulting page doesn't submit the
+// form again, after adding the given flash messages, if any, of the kind
+// "info" to the session of r.
+func RedirectAfterPost(w http.ResponseWriter, r *http.Request, url string, messages ...string) {
+	for _, m := range messages {
+		AddFlash(r, "info", m)
+	}
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+func mustSession(r *http.Request) *Session {
+	s := SessionOf(r)
+	if s == nil {
+		panic("shortmux: request has no session")
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFlashes(t *testing.T) {
+	sm := &memorySessions{sessions: map[string]map[string]any{}}
+	mux := NewServeMux()
+	mux.Sessions = sm
+	mux.HandleFunc("POST /items", func(w http.ResponseWriter, r *http.Request) {
+		AddFlash(r, "warning", "item name was truncated")
+		RedirectAfterPost(w, r, "/items/", "item created")
+	})
+	mux.HandleFunc("GET /items/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, Flashes(r))
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/items", nil))
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/items/" {
+		t.Fatalf("POST: status %d, Location %q", w.Code, w.Header().Get("Location"))
+	}
+	cookie := w.Result().Cookies()[0]
+
+	// The flashes survive the trailing-slash redirect of the mux, and are
+	// shown once.
+	for _, step := range []struct {
+		path string
+		want string
+	}{
+		{"/items", ""},
+		{"/items/", "[{warning item name was truncated} {info item created}]"},
+		{"/items/", "[]"},
+	} {
+		r := httptest.NewRequest("GET", step.path, nil)
+		r.AddCookie(cookie)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if step.want == "" {
+			if w.Code != http.StatusMovedPermanently {
+				t.Fatalf("GET %s: status %d", step.path, w.Code)
+			}
+			continue
+		}
+		if got := w.Body.String(); got != step.want {
+			t.Errorf("GET %s: got %s, want %s", step.path, got, step.want)
+		}
+	}
+
+	if got := Flashes(httptest.NewRequest("GET", "/", nil)); got != nil {
+		t.Errorf("Flashes without a session = %v", got)
+	}
+}
@@ -0,0 +1,116 @@
+package shortmux
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithFlushPolicy flushes the responses of the route as they are written,
+// so that streaming endpoints send data promptly even behind buffering
+// middleware: once n bytes were written since the last flush, if n is
+// positive, and d after a write that wasn't flushed, if d is positive.
+func WithFlushPolicy(n int64, d time.Duration) RouteOption {
+	if n < 0 || d < 0 || n == 0 && d == 0 {
+		panic(fmt.Sprintf("shortmux: invalid flush policy %d bytes, %v", n, d))
+	}
+	return func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fw := &flushWriter{ResponseWriter: w, bytes: n, delay: d}
+				defer fw.stop()
+				next.ServeHTTP(fw, r)
+			})
+		})
+	}
+}
+
+// flushWriter is a [http.ResponseWriter] flushing the response after a
+// number of bytes or a delay. As the delayed flushes happen on their own
+// goroutine, calls to the underlying writer are serialized.
+type flushWriter struct {
+	http.ResponseWriter
+	bytes int64         // flush after this many bytes, if positive
+	delay time.Duration // flush this long after a write, if positive
+
+	mu      sync.Mutex
+	pending int64       // bytes written since the last flush
+	timer   *time.Timer // running while pending > 0, if delay > 0
+	stopped bool        // whether the handler returned
+}
+
+func (w *flushWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *flushWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.ResponseWriter.Write(b)
+	w.pending += int64(n)
+	switch {
+	case err != nil || w.pending == 0:
+	case w.bytes > 0 && w.pending >= w.bytes:
+		w.flushLocked()
+	case w.delay > 0 && w.timer == nil:
+		w.timer = time.AfterFunc(w.delay, w.flushPending)
+	}
+	return n, err
+}
+
+// flushPending flushes the bytes written since the last flush, if any.
+func (w *flushWriter) flushPending() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer = nil
+	if !w.stopped && w.pending > 0 {
+		w.flushLocked()
+	}
+}
+
+func (w *flushWriter) flushLocked() error {
+	w.pending = 0
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// stop stops the delayed flushes, once the handler returned.
+func (w *flushWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+func (w *flushWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *flushWriter) FlushError() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushLocked()
+}
+
+func (w *flushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *flushWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,67 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flushCounter is a ResponseWriter counting flushes and the bytes written
+// before each of them.
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	flushed []int
+}
+
+func (w *flushCounter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushed = append(w.flushed, w.Body.Len())
+}
+
+func (w *flushCounter) flushes() []int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]int(nil), w.flushed...)
+}
+
+func TestWithFlushPolicyBytes(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		for range 5 {
+			w.Write([]byte("abc"))
+		}
+	}, WithFlushPolicy(4, 0))
+	w := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	got := w.flushes()
+	if want := []int{6, 12}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("flushed after %v bytes, want %v", got, want)
+	}
+}
+
+func TestWithFlushPolicyDelay(t *testing.T) {
+	mux := NewServeMux()
+	var w *flushCounter
+	mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("event 1\n"))
+		deadline := time.Now().Add(5 * time.Second)
+		for len(w.flushes()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("no flush after the delay")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		rw.Write([]byte("event 2\n"))
+	}, WithFlushPolicy(0, 10*time.Millisecond))
+	w = &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	// The pending write is not flushed after the handler returned.
+	time.Sleep(30 * time.Millisecond)
+	if got := w.flushes(); len(got) != 1 || got[0] != len("event 1\n") {
+		t.Errorf("flushed after %v bytes, want [8]", got)
+	}
+}
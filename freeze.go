@@ -0,0 +1,23 @@
+package shortmux
+
+import "errors"
+
+// ErrFrozen is returned, or panicked with by [ServeMux.Handle] and
+// [ServeMux.HandleFunc], when changing the routes of a frozen mux.
+var ErrFrozen = errors.New("shortmux: routes are frozen")
+
+// Freeze makes the routes of mux immutable, encoding the common discipline
+// of registering routes at startup only: from then on, registering or
+// removing a route fails with [ErrFrozen]. As the routing table no longer
+// changes, requests are matched without taking the lock guarding it.
+// Freeze can't be undone.
+func (mux *ServeMux) Freeze() {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.frozen.Store(true)
+}
+
+// Frozen reports whether [ServeMux.Freeze] was called.
+func (mux *ServeMux) Frozen() bool {
+	return mux.frozen.Load()
+}
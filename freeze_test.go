@@ -0,0 +1,49 @@
+package shortmux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("/a", http.NotFoundHandler())
+	mux.Freeze()
+	if !mux.Frozen() {
+		t.Fatal("not frozen")
+	}
+
+	func() {
+		defer func() {
+			if v := recover(); v != ErrFrozen {
+				t.Errorf("Handle: got panic %v, want ErrFrozen", v)
+			}
+		}()
+		mux.Handle("/b", http.NotFoundHandler())
+	}()
+	if err := mux.Remove("/a"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Remove: got %v, want ErrFrozen", err)
+	}
+	if err := mux.Apply([]RouteChange{{Pattern: "/c", Handler: http.NotFoundHandler()}}); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Apply: got %v, want ErrFrozen", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_, p := mux.Handler(httptest.NewRequest("GET", "/a", nil))
+				if p != "/a" {
+					t.Errorf("got pattern %q, want /a", p)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package shortmux
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A GraphFormat is an output format of [ServeMux.WriteGraph].
+type GraphFormat int
+
+const (
+	GraphDOT     GraphFormat = iota // Graphviz DOT
+	GraphMermaid                    // Mermaid flowchart
+)
+
+// WriteGraph writes a visualization of the routing tree of mux to w in the
+// given format, for documentation and reviews of large route tables.
+//
+// The first levels of the tree are the hosts and the methods of the
+// patterns, where "*" stands for any, followed by a level per path
+// segment, where "{}" stands for a single wildcard, "{...}" for a "..."
+// wildcard or trailing slash, and "{$}" for the end of the path. Nodes
+// where patterns end are labeled with the pattern and the name of its
+// handler, as given by [HandlerName].
+func (mux *ServeMux) WriteGraph(w io.Writer, format GraphFormat) error {
+	g := &graphWriter{format: format}
+	switch format {
+	case GraphDOT:
+		g.b.WriteString("digraph routes {\n\trankdir=LR;\n\tnode [shape=ellipse];\n")
+	case GraphMermaid:
+		g.b.WriteString("graph LR\n")
+	default:
+		return fmt.Errorf("shortmux: unknown graph format %d", format)
+	}
+	mux.mu.RLock()
+	g.walk(&mux.tree, "mux", -1, 0)
+	mux.mu.RUnlock()
+	if format == GraphDOT {
+		g.b.WriteString("}\n")
+	}
+	_, err := w.Write(g.b.Bytes())
+	return err
+}
+
+type graphWriter struct {
+	format GraphFormat
+	b      bytes.Buffer
+	nodes  int
+}
+
+// walk writes n, labeled label, as a child of the node with ID parent, if
+// any, and then its children. depth is 1 for hosts, 2 for methods and more
+// for path segments.
+func (g *graphWriter) walk(n *routingNode, label string, parent, depth int) {
+	id := g.nodes
+	g.nodes++
+	leaf := n.pattern != nil
+	if leaf {
+		label += "\n" + n.pattern.str + "\n" + HandlerName(n.route.handler)
+		if n.route.cfg.mounted != nil {
+			label += " (mounted)"
+		}
+	}
+	g.node(id, label, leaf)
+	if parent >= 0 {
+		g.edge(parent, id)
+	}
+
+	var keys []string
+	n.children.eachPair(func(k string, _ *routingNode) bool {
+		keys = append(keys, k)
+		return true
+	})
+	slices.Sort(keys)
+	for _, k := range keys {
+		c, _ := n.children.find(k)
+		g.walk(c, childLabel(k, depth+1), id, depth+1)
+	}
+	if n.emptyChild != nil {
+		g.walk(n.emptyChild, childLabel("", depth+1), id, depth+1)
+	}
+	if n.multiChild != nil {
+		g.walk(n.multiChild, "{...}", id, depth+1)
+	}
+	for _, c := range n.suffixed {
+		g.walk(c, "{...}"+c.pattern.str[strings.LastIndex(c.pattern.str, "...}")+4:], id, depth+1)
+	}
+}
+
+// childLabel returns the label of a child with key k at depth.
+func childLabel(k string, depth int) string {
+	switch {
+	case depth <= 2 && k == "":
+		return "*"
+	case depth <= 2:
+		return k
+	case k == "":
+		return "{}"
+	case k == "/":
+		return "{$}"
+	}
+	return "/" + k
+}
+
+func (g *graphWriter) node(id int, label string, leaf bool) {
+	switch g.format {
+	case GraphDOT:
+		shape := ""
+		if leaf {
+			shape = ", shape=box"
+		}
+		fmt.Fprintf(&g.b, "\tn%d [label=%s%s];\n", id, strconv.Quote(label), shape)
+	case GraphMermaid:
+		label = strings.NewReplacer(`"`, "#quot;", "\n", "<br>").Replace(label)
+		if leaf {
+			fmt.Fprintf(&g.b, "\tn%d[\"%s\"]\n", id, label)
+		} else {
+			fmt.Fprintf(&g.b, "\tn%d([\"%s\"])\n", id, label)
+		}
+	}
+}
+
+func (g *graphWriter) edge(from, to int) {
+	switch g.format {
+	case GraphDOT:
+		fmt.Fprintf(&g.b, "\tn%d -> n%d;\n", from, to)
+	case GraphMermaid:
+		fmt.Fprintf(&g.b, "\tn%d --> n%d\n", from, to)
+	}
+}
@@ -0,0 +1,67 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func graphHandler(http.ResponseWriter, *http.Request) {}
+
+func TestWriteGraph(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /users/{id}", graphHandler)
+	mux.HandleFunc("GET /users/{$}", graphHandler)
+	mux.HandleFunc("api.example.com/static/", graphHandler)
+
+	var b strings.Builder
+	if err := mux.WriteGraph(&b, GraphDOT); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph routes {
+	rankdir=LR;
+	node [shape=ellipse];
+	n0 [label="mux"];
+	n1 [label="api.example.com"];
+	n0 -> n1;
+	n2 [label="*"];
+	n1 -> n2;
+	n3 [label="/static"];
+	n2 -> n3;
+	n4 [label="{...}\napi.example.com/static/\ngithub.com/henvic/shortmux.graphHandler", shape=box];
+	n3 -> n4;
+	n5 [label="*"];
+	n0 -> n5;
+	n6 [label="GET"];
+	n5 -> n6;
+	n7 [label="/users"];
+	n6 -> n7;
+	n8 [label="{$}\nGET /users/{$}\ngithub.com/henvic/shortmux.graphHandler", shape=box];
+	n7 -> n8;
+	n9 [label="{}\nGET /users/{id}\ngithub.com/henvic/shortmux.graphHandler", shape=box];
+	n7 -> n9;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("DOT:\n%s\nwant:\n%s", got, want)
+	}
+
+	b.Reset()
+	if err := mux.WriteGraph(&b, GraphMermaid); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"graph LR\n",
+		"\tn0([\"mux\"])\n",
+		"\tn9[\"{}<br>GET /users/{id}<br>github.com/henvic/shortmux.graphHandler\"]\n",
+		"\tn7 --> n9\n",
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("Mermaid output lacks %q:\n%s", line, b.String())
+		}
+	}
+
+	if err := mux.WriteGraph(&b, GraphFormat(99)); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
@@ -0,0 +1,49 @@
+package shortmux
+
+import (
+	"net/http"
+	"slices"
+)
+
+// A Group registers routes on a mux with a common set of route options,
+// such as a [WithRenderer] shared by the pages of an admin area:
+//
+//	admin := mux.Group(shortmux.WithRenderer(adminViews), shortmux.WithStripHeaders("Cookie"))
+//	admin.HandleFunc("GET /admin/users", listUsers)
+//	admin.HandleFunc("GET /admin/users/{id}", showUser, shortmux.WithName("user"))
+//
+// The options of the group are applied before those given for each route,
+// so their middleware runs first.
+type Group struct {
+	mux  *ServeMux
+	opts []RouteOption
+}
+
+// Group returns a group registering routes on mux with the options opts.
+func (mux *ServeMux) Group(opts ...RouteOption) *Group {
+	return &Group{mux: mux, opts: slices.Clone(opts)}
+}
+
+// Group returns a group registering routes with the options of g followed
+// by opts.
+func (g *Group) Group(opts ...RouteOption) *Group {
+	return &Group{mux: g.mux, opts: g.with(opts)}
+}
+
+// with returns the options of g followed by opts.
+func (g *Group) with(opts []RouteOption) []RouteOption {
+	return append(slices.Clip(g.opts), opts...)
+}
+
+// Handle registers the handler for the given pattern on the mux of g, as
+// [ServeMux.Handle] does, with the options of g followed by opts.
+func (g *Group) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.mux.register(pattern, handler, g.with(opts))
+}
+
+// HandleFunc registers the handler function for the given pattern on the
+// mux of g, as [ServeMux.HandleFunc] does, with the options of g followed
+// by opts.
+func (g *Group) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	g.mux.register(pattern, http.HandlerFunc(handler), g.with(opts))
+}
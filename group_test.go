@@ -0,0 +1,51 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	mux := NewServeMux()
+	tag := func(s string) RouteOption {
+		return func(c *routeConfig) {
+			c.use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.WriteString(w, s)
+					next.ServeHTTP(w, r)
+				})
+			})
+		}
+	}
+	show := func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, " "+r.Pattern) }
+	admin := mux.Group(tag("admin"))
+	admin.HandleFunc("/admin/a", show)
+	admin.HandleFunc("/admin/b", show, tag("+b"))
+	admin.Group(tag("+sub")).Handle("/admin/c", http.HandlerFunc(show))
+	mux.HandleFunc("/other", show)
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/admin/a", "admin /admin/a"},
+		{"/admin/b", "admin+b /admin/b"},
+		{"/admin/c", "admin+sub /admin/c"},
+		{"/other", " /other"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(error).Error(), "group_test.go") {
+			t.Errorf("got panic %v, want a conflict at the caller", v)
+		}
+	}()
+	admin.HandleFunc("/admin/a", show)
+}
@@ -0,0 +1,79 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// An Unwrapper is a handler wrapping another one, such as a middleware.
+// Handlers implementing it let the introspection of a mux, such as
+// [HandlerName] and [HandlerChain], see the code they delegate to.
+type Unwrapper interface {
+	http.Handler
+
+	// Unwrap returns the wrapped handler, or nil if there is none.
+	Unwrap() http.Handler
+}
+
+// maxChain bounds the length of a chain of wrappers, in case an Unwrap
+// method returns its receiver.
+const maxChain = 100
+
+// HandlerChain returns h followed by the handlers it wraps, as reported by
+// their [Unwrapper] implementations, outermost first.
+func HandlerChain(h http.Handler) []http.Handler {
+	chain := []http.Handler{h}
+	for len(chain) < maxChain {
+		u, ok := h.(Unwrapper)
+		if !ok {
+			break
+		}
+		if h = u.Unwrap(); h == nil {
+			break
+		}
+		chain = append(chain, h)
+	}
+	return chain
+}
+
+// HandlerName returns the name of the code serving requests for h, so
+// that operators can tell which code serves which URL: the qualified name
+// of the function for an [http.HandlerFunc], such as
+// "example.com/app.(*Server).users", or the type of other handlers, such
+// as "*example.com/app.Server".
+//
+// Wrappers implementing [Unwrapper] are looked through, so the name is
+// that of the last handler of [HandlerChain].
+func HandlerName(h http.Handler) string {
+	chain := HandlerChain(h)
+	return name(chain[len(chain)-1])
+}
+
+// name returns the name of h itself.
+func name(h http.Handler) string {
+	if f, ok := h.(http.HandlerFunc); ok && f != nil {
+		if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+			// Method values are named after a wrapper with a -fm suffix.
+			return strings.TrimSuffix(fn.Name(), "-fm")
+		}
+	}
+	t := reflect.TypeOf(h)
+	if t == nil {
+		return "<nil>"
+	}
+	return typeName(t)
+}
+
+// typeName returns the name of t qualified with its package path.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		return "*" + typeName(t.Elem())
+	}
+	if t.PkgPath() == "" || t.Name() == "" {
+		return t.String()
+	}
+	return fmt.Sprintf("%s.%s", t.PkgPath(), t.Name())
+}
@@ -0,0 +1,93 @@
+package shortmux
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// foldHost returns host in lower case and without a trailing dot, for
+// ServeMux.FoldHosts.
+func foldHost(host string) string {
+	host = strings.TrimSuffix(host, ".")
+	for i := 0; i < len(host); i++ {
+		if c := host[i]; 'A' <= c && c <= 'Z' {
+			return strings.ToLower(host)
+		}
+	}
+	return host
+}
+
+// matchHost returns the host of a request as matched against the
+// patterns of mux.
+func (mux *ServeMux) matchHost(host string) string {
+	if mux.FoldHosts {
+		return foldHost(host)
+	}
+	return host
+}
+
+// canonicalIPv6 returns the IPv6 literal host, in brackets and possibly
+// followed by a port, in a canonical form: compressed, in lower case, and
+// with any zone ID escaped as in RFC 6874, as in "[fe80::1%25eth0]".
+// It reports false if host is not an IPv6 literal.
+func canonicalIPv6(host string) (string, bool) {
+	if !strings.HasPrefix(host, "[") {
+		return "", false
+	}
+	end := strings.IndexByte(host, ']')
+	if end < 0 {
+		return "", false
+	}
+	lit, port := host[1:end], host[end+1:]
+	if port != "" && (port[0] != ':' || !isDigits(port[1:])) {
+		return "", false
+	}
+	if i := strings.IndexByte(lit, '%'); i >= 0 && strings.HasPrefix(lit[i:], "%25") {
+		lit = lit[:i] + "%" + lit[i+len("%25"):]
+	}
+	addr, err := netip.ParseAddr(lit)
+	if err != nil || !addr.Is6() {
+		return "", false
+	}
+	s := addr.WithZone("").String()
+	if zone := addr.Zone(); zone != "" {
+		s += "%25" + zone
+	}
+	return "[" + s + "]" + port, true
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// portlessHost returns host without its port, if any, unless method is
+// CONNECT, whose hosts are matched as given.
+func portlessHost(host, method string) string {
+	if method == "CONNECT" || !strings.Contains(host, ":") {
+		return host
+	}
+	return stripHostPort(host)
+}
+
+// requestHost returns the host of r as matched against the patterns of
+// mux, with its port if MatchPorts is set.
+func (mux *ServeMux) requestHost(r *http.Request) string {
+	host := mux.matchHost(stripHostPort(r.Host))
+	if mux.MatchPorts {
+		if _, port, err := net.SplitHostPort(r.Host); err == nil && isDigits(port) {
+			return host + ":" + port
+		}
+	}
+	return host
+}
@@ -0,0 +1,144 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFoldHosts(t *testing.T) {
+	for _, fold := range []bool{false, true} {
+		mux := NewServeMux()
+		mux.FoldHosts = fold
+		mux.HandleFunc("Example.com/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("example"))
+		})
+		for _, host := range []string{"example.com", "EXAMPLE.com:8080", "example.com.", "Example.COM.:443"} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Host = host
+			mux.ServeHTTP(w, r)
+			want := http.StatusNotFound
+			if fold {
+				want = http.StatusOK
+			}
+			if w.Code != want {
+				t.Errorf("FoldHosts=%t, host %q: got %d, want %d", fold, host, w.Code, want)
+			}
+		}
+	}
+}
+
+func TestFoldHost(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"", ""},
+		{"example.com", "example.com"},
+		{"Example.COM", "example.com"},
+		{"example.com.", "example.com"},
+		{"EXAMPLE.COM.", "example.com"},
+	} {
+		if got := foldHost(test.in); got != test.want {
+			t.Errorf("foldHost(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestIPv6Hosts(t *testing.T) {
+	mux := NewServeMux()
+	for _, pat := range []string{"[::1]/", "[FE80:0::1%25eth0]/", "[2001:db8::1]/x"} {
+		mux.HandleFunc(pat, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pat))
+		})
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default"))
+	})
+	for _, test := range []struct {
+		host, path, want string
+	}{
+		{"[::1]", "/", "[::1]/"},
+		{"[::1]:8080", "/", "[::1]/"},
+		{"[0:0::1]:8080", "/", "[::1]/"},
+		{"[fe80::1%25eth0]:80", "/", "[FE80:0::1%25eth0]/"},
+		{"[fe80::1%25eth1]:80", "/", "default"},
+		{"[fe80::1]:80", "/", "default"},
+		{"[2001:DB8::1]", "/x", "[2001:db8::1]/x"},
+		{"[2001:db8::1]:443", "/y", "default"},
+		{"[::2]", "/", "default"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Host = test.host
+		mux.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s%s: got %q, want %q", test.host, test.path, got, test.want)
+		}
+	}
+}
+
+func TestStripHostPort(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"example.com", "example.com"},
+		{"example.com:80", "example.com"},
+		{"1.2.3.4:80", "1.2.3.4"},
+		{"[::1]", "[::1]"},
+		{"[::1]:80", "[::1]"},
+		{"[0::1]:80", "[::1]"},
+		{"[fe80::1%25en0]:80", "[fe80::1%25en0]"},
+		{"[fe80::1%en0]", "[fe80::1%25en0]"},
+		{"[::1", "[::1"},
+		{"[::1]x", "[::1]x"},
+	} {
+		if got := stripHostPort(test.in); got != test.want {
+			t.Errorf("stripHostPort(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestInvalidIPv6Pattern(t *testing.T) {
+	for _, pat := range []string{"[::g]/", "[1.2.3.4]/", "[::1/", "[::1]x/"} {
+		if _, err := parsePattern(pat); err == nil {
+			t.Errorf("%q: got nil error", pat)
+		}
+	}
+}
+
+func TestMatchPorts(t *testing.T) {
+	mux := NewServeMux()
+	mux.MatchPorts = true
+	for _, pat := range []string{"example.com:8443/admin/", "example.com/", "[::1]:8443/", "/"} {
+		mux.HandleFunc(pat, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pat))
+		})
+	}
+	for _, test := range []struct {
+		host, path, want string
+	}{
+		{"example.com:8443", "/admin/x", "example.com:8443/admin/"},
+		{"example.com:8443", "/other", "example.com/"},
+		{"example.com:443", "/admin/x", "example.com/"},
+		{"example.com", "/admin/x", "example.com/"},
+		{"other.com:8443", "/admin/x", "/"},
+		{"[::1]:8443", "/", "[::1]:8443/"},
+		{"[0::1]:8443", "/", "[::1]:8443/"},
+		{"[::1]:80", "/", "/"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Host = test.host
+		mux.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s%s: got %q, want %q", test.host, test.path, got, test.want)
+		}
+	}
+
+	// Without MatchPorts, patterns with a port never match.
+	mux.MatchPorts = false
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/admin/x", nil)
+	r.Host = "example.com:8443"
+	mux.ServeHTTP(w, r)
+	if got, want := w.Body.String(), "example.com/"; got != want {
+		t.Errorf("without MatchPorts: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+package shortmux
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPSPolicy makes hosts and routes of a mux HTTPS-only, set as
+// [ServeMux.HTTPS]. Plaintext requests for them are redirected to HTTPS
+// with 308 Permanent Redirect, which keeps the method and body, and HTTPS
+// responses for them get a Strict-Transport-Security header, if
+// configured.
+type HTTPSPolicy struct {
+	// Hosts lists the HTTPS-only hosts, or "*" for every host. Their
+	// requests are redirected before being routed. Routes can also be
+	// made HTTPS-only with WithHTTPSOnly.
+	Hosts []string
+
+	// HSTSMaxAge, if positive, is the max-age of the
+	// Strict-Transport-Security header, in whole seconds.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+
+	// TrustedProxies lists the addresses of the reverse proxies whose
+	// Forwarded and X-Forwarded-Proto headers tell whether the client
+	// used HTTPS. Requests from other addresses are HTTPS only if they
+	// were received over TLS.
+	TrustedProxies []netip.Prefix
+
+	// Port, if not zero, is the port of the HTTPS redirect URLs.
+	Port int
+}
+
+// WithHTTPSOnly makes the route HTTPS-only, as described in
+// [HTTPSPolicy], whether or not the mux has one.
+func WithHTTPSOnly() RouteOption {
+	return func(c *routeConfig) {
+		c.httpsOnly = true
+	}
+}
+
+// IsHTTPS reports whether r was made over HTTPS by the client, trusting
+// the headers of the proxies listed in p, if p is not nil.
+func (p *HTTPSPolicy) IsHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if p == nil || !p.trusted(r.RemoteAddr) {
+		return false
+	}
+	// The first proxy records the protocol of the client.
+	if f := r.Header.Get("Forwarded"); f != "" {
+		first, _, _ := strings.Cut(f, ",")
+		for pair := range strings.SplitSeq(first, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(k, "proto") {
+				return strings.EqualFold(strings.Trim(v, `"`), "https")
+			}
+		}
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+func (p *HTTPSPolicy) trusted(remoteAddr string) bool {
+	if len(p.TrustedProxies) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, pfx := range p.TrustedProxies {
+		if pfx.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpsHost reports whether host is HTTPS-only.
+func (p *HTTPSPolicy) httpsHost(host string) bool {
+	for _, h := range p.Hosts {
+		if h == "*" || strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceHTTPS enforces HTTPS on r, for a host or a route that is
+// HTTPS-only, and reports whether it redirected the request.
+func (mux *ServeMux) enforceHTTPS(w http.ResponseWriter, r *http.Request) bool {
+	p := mux.HTTPS
+	if p.IsHTTPS(r) {
+		if p != nil && p.HSTSMaxAge > 0 {
+			v := "max-age=" + strconv.FormatInt(int64(p.HSTSMaxAge/time.Second), 10)
+			if p.HSTSIncludeSubdomains {
+				v += "; includeSubDomains"
+			}
+			w.Header().Set("Strict-Transport-Security", v)
+		}
+		return false
+	}
+	// An IPv6 literal keeps its brackets, which JoinHostPort adds.
+	host := stripHostPort(r.Host)
+	if p != nil && p.Port != 0 && p.Port != 443 {
+		host = net.JoinHostPort(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"), strconv.Itoa(p.Port))
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	return true
+}
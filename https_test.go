@@ -0,0 +1,89 @@
+package shortmux
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestHTTPSPolicy(t *testing.T) {
+	mux := NewServeMux()
+	mux.HTTPS = &HTTPSPolicy{
+		Hosts:          []string{"secure.example.com"},
+		HSTSMaxAge:     365 * 24 * time.Hour,
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}
+	h := http.NotFoundHandler()
+	mux.Handle("/", h)
+	mux.Handle("/login", h, WithHTTPSOnly())
+
+	for _, test := range []struct {
+		name     string
+		target   string
+		remote   string
+		header   map[string]string
+		tls      bool
+		status   int
+		location string
+		hsts     bool
+	}{
+		{"plain host", "http://secure.example.com/a?b=c", "", nil, false, 308, "https://secure.example.com/a?b=c", false},
+		{"plain other host", "http://www.example.com/a", "", nil, false, 404, "", false},
+		{"plain route", "http://www.example.com/login", "", nil, false, 308, "https://www.example.com/login", false},
+		{"tls route", "https://www.example.com/login", "", nil, true, 404, "", true},
+		{"tls host", "https://secure.example.com/", "", nil, true, 404, "", true},
+		{"trusted proxy", "http://secure.example.com/", "10.1.2.3:5000", map[string]string{"X-Forwarded-Proto": "https"}, false, 404, "", true},
+		{"trusted Forwarded", "http://secure.example.com/", "10.1.2.3:5000", map[string]string{"Forwarded": `for=1.2.3.4;proto="https", for=10.0.0.1;proto=http`}, false, 404, "", true},
+		{"untrusted proxy", "http://secure.example.com/", "192.0.2.1:5000", map[string]string{"X-Forwarded-Proto": "https"}, false, 308, "https://secure.example.com/", false},
+	} {
+		r := httptest.NewRequest("POST", test.target, nil)
+		if test.remote != "" {
+			r.RemoteAddr = test.remote
+		}
+		if !test.tls {
+			r.TLS = nil
+		} else if r.TLS == nil {
+			r.TLS = &tls.ConnectionState{}
+		}
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status || w.Header().Get("Location") != test.location {
+			t.Errorf("%s: status %d, Location %q; want %d, %q", test.name, w.Code, w.Header().Get("Location"), test.status, test.location)
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); (got != "") != test.hsts {
+			t.Errorf("%s: Strict-Transport-Security %q", test.name, got)
+		} else if test.hsts && got != "max-age=31536000" {
+			t.Errorf("%s: Strict-Transport-Security %q", test.name, got)
+		}
+	}
+}
+
+func TestHTTPSPolicyIPv6(t *testing.T) {
+	for _, test := range []struct {
+		port           int
+		host, location string
+	}{
+		{0, "[::1]:8080", "https://[::1]/x"},
+		{0, "[::1]", "https://[::1]/x"},
+		{8443, "[::1]:8080", "https://[::1]:8443/x"},
+		{8443, "[fe80::1%25eth0]", "https://[fe80::1%25eth0]:8443/x"},
+		{8443, "example.com:8080", "https://example.com:8443/x"},
+	} {
+		mux := NewServeMux()
+		mux.HTTPS = &HTTPSPolicy{Hosts: []string{"*"}, Port: test.port}
+		mux.Handle("/", http.NotFoundHandler())
+		r := httptest.NewRequest("GET", "/x", nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if got := w.Header().Get("Location"); w.Code != 308 || got != test.location {
+			t.Errorf("%s, port %d: got %d to %q, want 308 to %q", test.host, test.port, w.Code, got, test.location)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package shortmux
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrBodyNotConsumed is passed to [BodyInspector.Done] when the handler
+// returns or closes the request body before reading it to the end.
+var ErrBodyNotConsumed = errors.New("shortmux: request body not read to EOF")
+
+// A BodyInspector observes a request body as the handler reads it, without
+// buffering it, e.g. to account for sizes or to feed a malware scanner.
+// A new BodyInspector is created for each request; see [WithBodyInspection].
+type BodyInspector interface {
+	// Inspect is called with each chunk of the body, in order.
+	// If it returns an error, the read fails with that error, and so do
+	// all subsequent reads.
+	Inspect(p []byte) error
+
+	// Done is called once, with the number of bytes read, when the body
+	// is read to EOF (err is nil), when a read fails (err is the error),
+	// or when the handler is done with the body before reading it to the
+	// end (err is ErrBodyNotConsumed).
+	// At EOF, an error returned by Done makes the final read fail with it
+	// instead of io.EOF, e.g. to reject short bodies after looking at all
+	// of them. The returned error is ignored otherwise.
+	Done(n int64, err error) error
+}
+
+// WithBodyInspection wraps the body of the requests matching the route so
+// that the inspectors created by each of the factories observe it as the
+// handler reads it. Inspectors run in order; the first error aborts the read.
+//
+// If limit is positive, reading more than limit bytes fails with
+// [http.MaxBytesError], like with [http.MaxBytesReader].
+func WithBodyInspection(limit int64, factories ...func(*http.Request) BodyInspector) RouteOption {
+	return func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Body == nil || r.Body == http.NoBody {
+					next.ServeHTTP(w, r)
+					return
+				}
+				ib := &inspectedBody{rc: r.Body}
+				if limit > 0 {
+					ib.rc = http.MaxBytesReader(w, r.Body, limit)
+				}
+				for _, f := range factories {
+					ib.inspectors = append(ib.inspectors, f(r))
+				}
+				r.Body = ib
+				defer ib.done(ErrBodyNotConsumed)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// inspectedBody is a request body observed by inspectors.
+type inspectedBody struct {
+	rc         io.ReadCloser
+	inspectors []BodyInspector
+
+	mu       sync.Mutex
+	n        int64
+	err      error // sticky inspection error
+	finished bool
+}
+
+func (b *inspectedBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	if n > 0 {
+		for _, in := range b.inspectors {
+			if ierr := in.Inspect(p[:n]); ierr != nil {
+				b.err = ierr
+				_ = b.finish(ierr)
+				return n, ierr
+			}
+		}
+	}
+	if err == io.EOF {
+		if ierr := b.finish(nil); ierr != nil {
+			b.err = ierr
+			return n, ierr
+		}
+	} else if err != nil {
+		_ = b.finish(err)
+	}
+	return n, err
+}
+
+func (b *inspectedBody) Close() error {
+	b.done(ErrBodyNotConsumed)
+	return b.rc.Close()
+}
+
+func (b *inspectedBody) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_ = b.finish(err)
+}
+
+// finish calls Done on the inspectors, once, and returns the first error
+// they return. b.mu must be held.
+func (b *inspectedBody) finish(err error) error {
+	if b.finished {
+		return nil
+	}
+	b.finished = true
+	var first error
+	for _, in := range b.inspectors {
+		if derr := in.Done(b.n, err); derr != nil && first == nil {
+			first = derr
+		}
+	}
+	return first
+}
+
+// SniffBody returns a [BodyInspector] factory that calls check with the
+// content type detected by [http.DetectContentType] from the first 512
+// bytes of the body, or from the whole body if it is shorter.
+// If check returns an error, the read fails with it.
+func SniffBody(check func(r *http.Request, contentType string) error) func(*http.Request) BodyInspector {
+	return func(r *http.Request) BodyInspector {
+		return &sniffer{r: r, check: check}
+	}
+}
+
+type sniffer struct {
+	r       *http.Request
+	check   func(*http.Request, string) error
+	buf     []byte
+	checked bool
+}
+
+const sniffLen = 512
+
+func (s *sniffer) Inspect(p []byte) error {
+	if s.checked {
+		return nil
+	}
+	s.buf = append(s.buf, p[:min(len(p), sniffLen-len(s.buf))]...)
+	if len(s.buf) < sniffLen {
+		return nil
+	}
+	return s.sniff()
+}
+
+func (s *sniffer) Done(n int64, err error) error {
+	if err == nil && !s.checked {
+		// Bodies shorter than sniffLen are checked at EOF.
+		return s.sniff()
+	}
+	return nil
+}
+
+func (s *sniffer) sniff() error {
+	s.checked = true
+	err := s.check(s.r, http.DetectContentType(s.buf))
+	s.buf = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package shortmux
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type countingInspector struct {
+	n    *int64
+	err  *error
+	deny string
+}
+
+func (c countingInspector) Inspect(p []byte) error {
+	if c.deny != "" && strings.Contains(string(p), c.deny) {
+		return errors.New("infected")
+	}
+	return nil
+}
+
+func (c countingInspector) Done(n int64, err error) error {
+	*c.n, *c.err = n, err
+	return nil
+}
+
+func TestWithBodyInspection(t *testing.T) {
+	var n int64
+	var doneErr error
+	counter := func(*http.Request) BodyInspector {
+		return countingInspector{n: &n, err: &doneErr, deny: "EICAR"}
+	}
+	onlyText := SniffBody(func(r *http.Request, ct string) error {
+		if !strings.HasPrefix(ct, "text/plain") {
+			return errors.New("unsupported " + ct)
+		}
+		return nil
+	})
+	mux := NewServeMux()
+	mux.HandleFunc("POST /upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("skip") {
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(b)
+	}, WithBodyInspection(16, counter, onlyText))
+
+	for _, test := range []struct {
+		target, body string
+		wantCode     int
+		wantBody     string
+		wantN        int64
+		wantDoneErr  string
+	}{
+		{"/upload", "hello", 200, "hello", 5, ""},
+		{"/upload", "<html></html>", 400, "unsupported text/html; charset=utf-8\n", 13, ""},
+		{"/upload", "x EICAR x", 400, "infected\n", 9, "infected"},
+		{"/upload", strings.Repeat("a", 20), 400, "http: request body too large\n", 16, "http: request body too large"},
+		{"/upload?skip", "hello", 200, "", 0, ErrBodyNotConsumed.Error()},
+	} {
+		n, doneErr = -1, nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", test.target, strings.NewReader(test.body)))
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody {
+			t.Errorf("%s %q: got %d %q, want %d %q", test.target, test.body, w.Code, w.Body.String(), test.wantCode, test.wantBody)
+		}
+		gotErr := ""
+		if doneErr != nil {
+			gotErr = doneErr.Error()
+		}
+		if n != test.wantN || gotErr != test.wantDoneErr {
+			t.Errorf("%s %q: Done(%d, %q), want Done(%d, %q)", test.target, test.body, n, gotErr, test.wantN, test.wantDoneErr)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package shortmux
+
+import (
+	"net/http"
+	"sync"
+)
+
+// HandleLazy registers a handler for the given pattern that is built by
+// factory when the route serves its first request, so that expensive
+// handlers (compiling templates, loading models) don't slow down startup.
+//
+// The pattern is parsed and checked immediately, as with Handle.
+// The factory is called at most once, even under concurrent requests.
+// If it panics or returns nil, every request for the route panics.
+func (mux *ServeMux) HandleLazy(pattern string, factory func() http.Handler, opts ...RouteOption) {
+	if factory == nil {
+		panic("http: nil handler")
+	}
+	mux.register(pattern, &lazyHandler{resolve: sync.OnceValue(func() http.Handler {
+		h := factory()
+		if h == nil {
+			panic("shortmux: handler factory for " + pattern + " returned nil")
+		}
+		return h
+	})}, opts)
+}
+
+// lazyHandler is a handler built on first use.
+type lazyHandler struct {
+	resolve func() http.Handler
+}
+
+func (h *lazyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.resolve().ServeHTTP(w, r)
+}
@@ -0,0 +1,47 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHandleLazy(t *testing.T) {
+	calls := 0
+	mux := NewServeMux()
+	mux.HandleLazy("/report/{id}", func() http.Handler {
+		calls++
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, r.PathValue("id"))
+		})
+	})
+	if calls != 0 {
+		t.Fatalf("factory called %d times at registration", calls)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/report/7", nil))
+			if w.Body.String() != "7" {
+				t.Errorf("got %q, want %q", w.Body.String(), "7")
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("factory called %d times, want 1", calls)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an invalid pattern did not panic")
+		}
+	}()
+	mux.HandleLazy("/{x", func() http.Handler { return nil })
+}
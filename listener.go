@@ -0,0 +1,85 @@
+package shortmux
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// NamedListener returns l with its connections attributed to name, for
+// [MatchListener]. The server must use [ConnContext].
+func NamedListener(name string, l net.Listener) net.Listener {
+	return &namedListener{Listener: l, name: name}
+}
+
+type namedListener struct {
+	net.Listener
+	name string
+}
+
+func (l *namedListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &namedConn{Conn: c, name: l.name}, nil
+}
+
+type namedConn struct {
+	net.Conn
+	name string
+}
+
+// NetConn returns the underlying connection.
+func (c *namedConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+type listenerKey struct{}
+
+// ConnContext is meant for [http.Server.ConnContext]. It records the
+// listener of c in ctx, for [ListenerOf] and [MatchListener]: the name
+// given to [NamedListener], or else the network of the local address of
+// c, such as "tcp" or "unix".
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, listenerKey{}, listenerName(c))
+}
+
+// listenerName returns the name of the listener of c, unwrapping
+// connections such as [*tls.Conn].
+func listenerName(c net.Conn) string {
+	for {
+		if nc, ok := c.(*namedConn); ok {
+			return nc.name
+		}
+		u, ok := c.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		c = u.NetConn()
+	}
+	if addr := c.LocalAddr(); addr != nil {
+		return addr.Network()
+	}
+	return ""
+}
+
+// ListenerOf returns the listener r was received on, as recorded by
+// [ConnContext], or "" if the server doesn't use it.
+func ListenerOf(r *http.Request) string {
+	name, _ := r.Context().Value(listenerKey{}).(string)
+	return name
+}
+
+// MatchListener returns a matcher accepting requests received on one of
+// the listeners names, as recorded by [ConnContext]. For example, with
+// an administration server listening on a Unix socket,
+// MatchListener("unix") restricts a route to local clients with access
+// to the socket file.
+func MatchListener(names ...string) Matcher {
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		name, ok := r.Context().Value(listenerKey{}).(string)
+		return ok && slices.Contains(names, name)
+	})
+}
@@ -0,0 +1,71 @@
+package shortmux
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchListener(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/admin", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "admin via "+ListenerOf(r))
+	}, WithMatcher(MatchListener("unix", "internal")))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "public via "+ListenerOf(r))
+	})
+
+	dir, err := os.MkdirTemp("", "shortmux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "admin.sock")
+	ul, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	il, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux, ConnContext: ConnContext}
+	defer srv.Close()
+	go srv.Serve(ul)
+	go srv.Serve(tl)
+	go srv.Serve(NamedListener("internal", il))
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+	for _, test := range []struct {
+		client *http.Client
+		addr   string
+		want   string
+	}{
+		{unixClient, "unix", "admin via unix"},
+		{http.DefaultClient, tl.Addr().String(), "public via tcp"},
+		{http.DefaultClient, il.Addr().String(), "admin via internal"},
+	} {
+		res, err := test.client.Get("http://" + test.addr + "/admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if got := string(b); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.addr, got, test.want)
+		}
+	}
+}
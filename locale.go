@@ -0,0 +1,184 @@
+package shortmux
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// localeKey is the context key for the negotiated locale.
+type localeKey struct{}
+
+// Locale returns the locale chosen for r by [WithLocales], or the empty
+// string if there is none.
+func Locale(r *http.Request) string {
+	l, _ := r.Context().Value(localeKey{}).(string)
+	return l
+}
+
+// LocalePath returns path prefixed with the locale chosen for r,
+// e.g. "/pt/about" for the path "/about".
+// If no locale was chosen, path is returned unchanged.
+func LocalePath(r *http.Request, path string) string {
+	l := Locale(r)
+	if l == "" {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "/" + url.PathEscape(l) + path
+}
+
+// WithLocales negotiates the locale of each request among the supported
+// ones and stores it in the request context, where [Locale] retrieves it.
+//
+// If the route has a wildcard named "locale" whose value is a supported
+// locale, that locale is used. Otherwise, the locale is negotiated from the
+// Accept-Language header, falling back to the first supported locale.
+func WithLocales(supported ...string) RouteOption {
+	if len(supported) == 0 {
+		panic("shortmux: no supported locales")
+	}
+	return func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				l := r.PathValue("locale")
+				if !slices.Contains(supported, l) {
+					l = NegotiateLocale(r.Header.Get("Accept-Language"), supported)
+					w.Header().Add("Vary", "Accept-Language")
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, l)))
+			})
+		})
+	}
+}
+
+// WithLocaleRedirect redirects requests to the same path prefixed with the
+// locale negotiated among the supported ones, e.g. from "/about" to
+// "/pt/about", with 302 Found. The route handler is never called.
+func WithLocaleRedirect(supported ...string) RouteOption {
+	if len(supported) == 0 {
+		panic("shortmux: no supported locales")
+	}
+	return func(c *routeConfig) {
+		c.use(func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				l := NegotiateLocale(r.Header.Get("Accept-Language"), supported)
+				u := &url.URL{Path: "/" + l + r.URL.Path, RawQuery: r.URL.RawQuery}
+				w.Header().Add("Vary", "Accept-Language")
+				http.Redirect(w, r, u.String(), http.StatusFound)
+			})
+		})
+	}
+}
+
+// NegotiateLocale returns the supported locale that best matches the
+// Accept-Language header value acceptLanguage (RFC 9110, section 12.5.4).
+//
+// A language range matches a locale case-insensitively if they are equal
+// or if one is a prefix of the other followed by "-", so "pt-BR" matches "pt"
+// and "en" matches "en-US". Exact matches win over prefix matches of the
+// same quality. If nothing matches, the first supported locale is returned.
+func NegotiateLocale(acceptLanguage string, supported []string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	best, bestQ, bestExact := supported[0], 0.0, false
+	for _, lr := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(lr), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for p := range strings.SplitSeq(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil || f < 0 || f > 1 {
+					f = 0
+				}
+				q = f
+			}
+		}
+		if q == 0 || q < bestQ {
+			continue
+		}
+		for _, s := range supported {
+			exact := strings.EqualFold(tag, s)
+			if !exact && tag != "*" && !langPrefix(tag, s) && !langPrefix(s, tag) {
+				continue
+			}
+			if q > bestQ || (exact && !bestExact) {
+				best, bestQ, bestExact = s, q, exact
+			}
+			if exact {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// langPrefix reports whether the language tag prefix is a prefix of tag.
+func langPrefix(prefix, tag string) bool {
+	return len(tag) > len(prefix) && tag[len(prefix)] == '-' && strings.EqualFold(tag[:len(prefix)], prefix)
+}
+
+// HandleLocalized registers handler for pattern prefixed with each of the
+// locales, and a redirect from the bare pattern to the negotiated locale.
+// For example,
+//
+//	mux.HandleLocalized("GET /about", h, "en", "pt")
+//
+// registers "GET /en/about" and "GET /pt/about" for h, and "GET /about"
+// redirecting to one of them based on the Accept-Language header.
+//
+// The handler finds the locale with r.PathValue("locale") or [Locale].
+// Like Handle, HandleLocalized panics if any of the patterns is invalid or
+// already registered, in which case none of them is registered.
+func (mux *ServeMux) HandleLocalized(pattern string, handler http.Handler, locales ...string) {
+	if len(locales) == 0 {
+		panic("shortmux: no supported locales")
+	}
+	if handler == nil {
+		panic("http: nil handler")
+	}
+	i := strings.IndexByte(pattern, '/')
+	if i < 0 {
+		panic(fmt.Sprintf("parsing %q: host/path missing /", pattern))
+	}
+	prefix, path := pattern[:i], pattern[i:]
+	changes := make([]RouteChange, 0, len(locales)+1)
+	for _, l := range locales {
+		changes = append(changes, RouteChange{Pattern: prefix + "/" + url.PathEscape(l) + path, Handler: localized(l, handler)})
+	}
+	changes = append(changes, RouteChange{Pattern: pattern, Handler: handler, Options: []RouteOption{WithLocaleRedirect(locales...)}})
+	if err := mux.apply(changes, callerLocation(1)); err != nil {
+		panic(err)
+	}
+}
+
+// localized returns a handler that serves h with the locale l.
+func localized(l string, h http.Handler) http.Handler {
+	return &localizedHandler{locale: l, next: h}
+}
+
+type localizedHandler struct {
+	locale string
+	next   http.Handler
+}
+
+func (h *localizedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("locale", h.locale)
+	h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), localeKey{}, h.locale)))
+}
+
+// Unwrap implements [Unwrapper].
+func (h *localizedHandler) Unwrap() http.Handler {
+	return h.next
+}
@@ -0,0 +1,115 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en", "pt-BR", "pt", "de"}
+	for _, test := range []struct {
+		header, want string
+	}{
+		{"", "en"},
+		{"fr", "en"},
+		{"pt-BR", "pt-BR"},
+		{"pt-PT", "pt"},
+		{"de;q=0.5, pt;q=0.8", "pt"},
+		{"DE", "de"},
+		{"*;q=0.1, fr", "en"},
+		{"de;q=0", "en"},
+		{"pt-br;q=0.9, pt;q=0.9", "pt-BR"},
+	} {
+		if got := NegotiateLocale(test.header, supported); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestWithLocales(t *testing.T) {
+	mux := NewServeMux()
+	show := func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, Locale(r)+" "+LocalePath(r, "/about"))
+	}
+	mux.HandleFunc("/{locale}/home", show, WithLocales("en", "pt"))
+	mux.HandleFunc("/home", show, WithLocaleRedirect("en", "pt"))
+
+	for _, test := range []struct {
+		path, accept       string
+		wantCode           int
+		wantBody, wantLocn string
+	}{
+		{"/pt/home", "en", 200, "pt /pt/about", ""},
+		{"/fr/home", "pt-BR", 200, "pt /pt/about", ""},
+		{"/home?x=1", "pt;q=1, en;q=0.5", 302, "", "/pt/home?x=1"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Header.Set("Accept-Language", test.accept)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, test.wantCode)
+		}
+		if test.wantBody != "" && w.Body.String() != test.wantBody {
+			t.Errorf("%s: got body %q, want %q", test.path, w.Body.String(), test.wantBody)
+		}
+		if got := w.Header().Get("Location"); got != test.wantLocn {
+			t.Errorf("%s: got Location %q, want %q", test.path, got, test.wantLocn)
+		}
+	}
+}
+
+func TestHandleLocalized(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleLocalized("GET /about", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.PathValue("locale")+" "+Locale(r))
+	}), "en", "pt-BR")
+
+	for _, test := range []struct {
+		path, accept       string
+		wantCode           int
+		wantBody, wantLocn string
+	}{
+		{"/en/about", "", 200, "en en", ""},
+		{"/pt-BR/about", "", 200, "pt-BR pt-BR", ""},
+		{"/fr/about", "", 404, "", ""},
+		{"/about", "pt", 302, "", "/pt-BR/about"},
+		{"/about", "", 302, "", "/en/about"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Header.Set("Accept-Language", test.accept)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, test.wantCode)
+		}
+		if test.wantBody != "" && w.Body.String() != test.wantBody {
+			t.Errorf("%s: got body %q, want %q", test.path, w.Body.String(), test.wantBody)
+		}
+		if got := w.Header().Get("Location"); got != test.wantLocn {
+			t.Errorf("%s: got Location %q, want %q", test.path, got, test.wantLocn)
+		}
+	}
+}
+
+func TestHandleLocalizedAtomic(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("GET /pt/about", http.NotFoundHandler())
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if err == nil || !strings.Contains(err.Error(), "locale_test.go") {
+				t.Errorf("got panic %v, want an error at the caller", err)
+			}
+		}()
+		mux.HandleLocalized("GET /about", http.NotFoundHandler(), "en", "pt")
+	}()
+	for _, path := range []string{"/en/about", "/about"} {
+		if _, pattern := mux.Handler(httptest.NewRequest("GET", path, nil)); pattern != "" {
+			t.Errorf("%s: registered as %q after a failed HandleLocalized", path, pattern)
+		}
+	}
+}
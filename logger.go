@@ -0,0 +1,51 @@
+package shortmux
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// loggerKey is the context key of the logger set with WithLogger.
+type loggerKey struct{}
+
+// WithLogger sets the logger of the route, returned by [LoggerOf] for the
+// requests it serves, so that its handlers log consistently. The logger
+// has the attributes attrs, as for [slog.Logger.With], then the pattern of
+// the route, as "pattern", and the attributes returned by the mux
+// LogAttrs hook for the request, such as a request ID or a tenant. If l is
+// nil, [slog.Default] is used.
+//
+// To configure several routes alike, pass them the same option.
+func WithLogger(l *slog.Logger, attrs ...any) RouteOption {
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				base := l
+				if base == nil {
+					base = slog.Default()
+				}
+				args := append(attrs[:len(attrs):len(attrs)], "pattern", r.Pattern)
+				if mux.LogAttrs != nil {
+					args = append(args, mux.LogAttrs(r)...)
+				}
+				ctx := context.WithValue(r.Context(), loggerKey{}, base.With(args...))
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		})
+	}
+}
+
+// LoggerOf returns the logger of the request r, set for its route with
+// [WithLogger]. For other requests, it returns [slog.Default], with the
+// matched pattern, if any, as "pattern".
+func LoggerOf(r *http.Request) *slog.Logger {
+	if l, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	if r.Pattern == "" {
+		return slog.Default()
+	}
+	return slog.Default().With("pattern", r.Pattern)
+}
@@ -0,0 +1,44 @@
+package shortmux
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	mux := NewServeMux()
+	mux.LogAttrs = func(r *http.Request) []any {
+		return []any{"request_id", r.Header.Get("X-Request-Id"), "tenant", r.PathValue("tenant")}
+	}
+	mux.HandleFunc("/t/{tenant}/items", func(w http.ResponseWriter, r *http.Request) {
+		LoggerOf(r).Info("listing")
+	}, WithLogger(l, "service", "items"))
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		if LoggerOf(r) == nil {
+			t.Error("nil logger")
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/t/acme/items", nil)
+	r.Header.Set("X-Request-Id", "42")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/plain", nil))
+
+	want := `level=INFO msg=listing service=items pattern=/t/{tenant}/items request_id=42 tenant=acme`
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
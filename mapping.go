@@ -4,6 +4,8 @@
 
 package shortmux
 
+import "slices"
+
 // A mapping is a collection of key-value pairs where the keys are unique.
 // A zero mapping is empty and ready to use.
 // A mapping tries to pick a representation that makes [mapping.find] most efficient.
@@ -76,3 +78,20 @@ func (h *mapping[K, V]) eachPair(f func(k K, v V) bool) {
 		}
 	}
 }
+
+// remove removes the pair with the given key, if any.
+func (h *mapping[K, V]) remove(k K) {
+	if h.m != nil {
+		delete(h.m, k)
+		return
+	}
+	h.s = slices.DeleteFunc(h.s, func(e entry[K, V]) bool { return e.key == k })
+}
+
+// len returns the number of pairs in the mapping.
+func (h *mapping[K, V]) len() int {
+	if h.m != nil {
+		return len(h.m)
+	}
+	return len(h.s)
+}
@@ -0,0 +1,157 @@
+package shortmux
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// matchCacheShards is the maximum number of shards of a matchCache, each
+// with its own lock, so that concurrent lookups of different keys rarely
+// contend.
+const matchCacheShards = 16
+
+// matchCache is a bounded cache of the leaves matched by requests, by
+// method, host and path, for ServeMux.MatchCacheSize. It only holds leaves
+// matched without wildcard values or matchers, whose match depends on
+// nothing else, and is emptied when the routes change.
+//
+// Lookups only take the read lock of a shard: rather than keeping the
+// entries in least recently used order, which needs the write lock, they
+// mark the entries they hit, and eviction skips marked entries once, as
+// in the CLOCK algorithm.
+type matchCache struct {
+	shards [matchCacheShards]cacheShard
+
+	hits, misses atomic.Uint64
+}
+
+// A cacheShard holds the entries of a matchCache whose keys hash to it.
+type cacheShard struct {
+	mu      sync.RWMutex
+	version uint64 // of the routes of the cached leaves
+	entries map[staticKey]*cacheEntry
+	ring    []*cacheEntry // entries in eviction order, from hand
+	hand    int           // index in ring of the next entry to consider
+}
+
+type cacheEntry struct {
+	key  staticKey
+	leaf *routingNode
+	used atomic.Bool // whether it was hit since the hand last passed it
+}
+
+// shard returns the shard of key, in a cache of size entries, and its
+// capacity. There are at most size shards, so that the capacities add up
+// to size.
+func (c *matchCache) shard(key staticKey, size int) (*cacheShard, int) {
+	n := min(size, matchCacheShards)
+	// FNV-1a.
+	h := uint32(2166136261)
+	for _, s := range [...]string{key.method, key.host, key.path} {
+		for i := 0; i < len(s); i++ {
+			h = (h ^ uint32(s[i])) * 16777619
+		}
+		h *= 16777619 // separate the strings
+	}
+	i := int(h % uint32(n))
+	capacity := size / n
+	if i < size%n {
+		capacity++
+	}
+	return &c.shards[i], capacity
+}
+
+// get returns the leaf cached for key with the routes at version, in a
+// cache of size entries, or nil.
+func (c *matchCache) get(key staticKey, version uint64, size int) *routingNode {
+	s, _ := c.shard(key, size)
+	s.mu.RLock()
+	var e *cacheEntry
+	if s.version == version {
+		e = s.entries[key]
+	}
+	s.mu.RUnlock()
+	if e == nil {
+		c.misses.Add(1)
+		return nil
+	}
+	// Avoid writing to the entries hit over and over.
+	if !e.used.Load() {
+		e.used.Store(true)
+	}
+	c.hits.Add(1)
+	return e.leaf
+}
+
+// put caches leaf for key with the routes at version, in a cache of size
+// entries, evicting an entry that wasn't hit recently if the shard of key
+// is full.
+func (c *matchCache) put(key staticKey, leaf *routingNode, version uint64, size int) {
+	s, capacity := c.shard(key, size)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version < s.version {
+		// Matched before the routes changed.
+		return
+	}
+	if s.version != version || s.entries == nil || len(s.ring) > capacity {
+		s.version = version
+		s.entries = make(map[staticKey]*cacheEntry, capacity)
+		s.ring = make([]*cacheEntry, 0, capacity)
+		s.hand = 0
+	}
+	if _, ok := s.entries[key]; ok {
+		// Cached concurrently, for the same routes.
+		return
+	}
+	e := &cacheEntry{key: key, leaf: leaf}
+	s.entries[key] = e
+	if len(s.ring) < capacity {
+		s.ring = append(s.ring, e)
+		return
+	}
+	for {
+		old := s.ring[s.hand]
+		if !old.used.Swap(false) {
+			delete(s.entries, old.key)
+			s.ring[s.hand] = e
+			s.hand = (s.hand + 1) % len(s.ring)
+			return
+		}
+		s.hand = (s.hand + 1) % len(s.ring)
+	}
+}
+
+// MatchCacheStats reports the effectiveness of the match cache of a
+// [ServeMux], enabled with MatchCacheSize.
+type MatchCacheStats struct {
+	Hits    uint64 // lookups answered by the cache
+	Misses  uint64 // lookups that walked the routing tree
+	Entries int    // cached matches
+}
+
+// HitRate returns the fraction of the lookups answered by the cache, or 0
+// if there were none.
+func (s MatchCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// MatchCacheStats reports the effectiveness of the match cache of mux,
+// since it was created.
+func (mux *ServeMux) MatchCacheStats() MatchCacheStats {
+	c := &mux.cache
+	version := mux.version.Load()
+	n := 0
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.RLock()
+		if s.version == version {
+			n += len(s.entries)
+		}
+		s.mu.RUnlock()
+	}
+	return MatchCacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: n}
+}
@@ -0,0 +1,137 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchCache(t *testing.T) {
+	mux := NewServeMux()
+	mux.MatchCacheSize = 2
+	for _, pat := range []string{"/", "GET /a/", "GET /w/{x}"} {
+		mux.Handle(pat, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, pat, " ", r.PathValue("x"))
+		}))
+	}
+	mux.Handle("GET /m/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "matched")
+	}), WithMatcher(MatchQuery("q", "1")))
+
+	serve := func(target string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w.Body.String()
+	}
+	for _, test := range []struct {
+		target, want string
+	}{
+		{"/a/b", "GET /a/ "},
+		{"/a/b", "GET /a/ "},
+		{"/w/1", "GET /w/{x} 1"},
+		{"/w/2", "GET /w/{x} 2"},
+		{"/m/?q=1", "matched"},
+		{"/m/?q=2", "/ "},
+		{"/m/?q=1", "matched"},
+	} {
+		if got := serve(test.target); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.target, got, test.want)
+		}
+	}
+	st := mux.MatchCacheStats()
+	// Only /a/b is cached: /w/{x} has a wildcard value and /m/ a matcher.
+	if st.Hits != 1 || st.Entries != 1 {
+		t.Errorf("got %+v, want 1 hit and 1 entry", st)
+	}
+
+	// Entries beyond the size are evicted.
+	for i := range 10 {
+		serve(fmt.Sprintf("/x%d", i))
+	}
+	if st := mux.MatchCacheStats(); st.Entries < 1 || st.Entries > 2 {
+		t.Errorf("got %d entries, want at most 2", st.Entries)
+	}
+
+	// Changing the routes empties the cache.
+	mux.Handle("GET /a/b", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "new")
+	}))
+	if st := mux.MatchCacheStats(); st.Entries != 0 {
+		t.Errorf("got %d entries after a change, want 0", st.Entries)
+	}
+	if got := serve("/a/b"); got != "new" {
+		t.Errorf("after a change, got %q, want %q", got, "new")
+	}
+}
+
+func TestMatchCacheEviction(t *testing.T) {
+	var c matchCache
+	leaf := &routingNode{}
+	key := func(i int) staticKey { return staticKey{"GET", "", fmt.Sprintf("/%d", i)} }
+	// A single shard of 4 entries.
+	for i := range 4 {
+		c.put(key(i), leaf, 1, 1)
+	}
+	s, _ := c.shard(key(0), 1)
+	if len(s.entries) != 1 {
+		t.Fatalf("got %d entries in a cache of 1, want 1", len(s.entries))
+	}
+
+	c = matchCache{}
+	const size = 4 * matchCacheShards
+	for i := range 1000 {
+		c.put(key(i), leaf, 1, size)
+		// Keep key 0 in use.
+		if c.get(key(0), 1, size) == nil {
+			t.Fatalf("after %d puts, the entry in use was evicted", i+1)
+		}
+	}
+	n := 0
+	for i := range c.shards {
+		n += len(c.shards[i].entries)
+	}
+	if n > size {
+		t.Errorf("got %d entries in a cache of %d", n, size)
+	}
+
+	// Entries of old routes are dropped, and not cached anymore.
+	c.put(key(1), leaf, 2, size)
+	c.put(key(2), leaf, 1, size)
+	if c.get(key(0), 2, size) != nil || c.get(key(2), 2, size) != nil || c.get(key(1), 2, size) == nil {
+		t.Error("got entries of old routes")
+	}
+}
+
+// BenchmarkMatchCache compares concurrent matches of requests that
+// backtrack in the routing tree, with and without the match cache.
+func BenchmarkMatchCache(b *testing.B) {
+	for _, size := range []int{0, 1024} {
+		mux := NewServeMux()
+		mux.MatchCacheSize = size
+		h := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+		for i := range 50 {
+			mux.Handle(fmt.Sprintf("GET /{a}/{b}/{c}/x%d", i), h)
+			mux.Handle(fmt.Sprintf("GET /{a}/{b}/y%d/", i), h)
+			mux.Handle(fmt.Sprintf("GET /assets/v%d/", i), h)
+		}
+		mux.Handle("/", h)
+		mux.Freeze()
+		var reqs []*http.Request
+		for i := range 100 {
+			reqs = append(reqs, httptest.NewRequest("GET", fmt.Sprintf("/assets/v%d/css/site%d.css", i%50, i), nil))
+			reqs = append(reqs, httptest.NewRequest("GET", fmt.Sprintf("/p%d/q/r/s/t", i), nil))
+		}
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					mux.findHandler(reqs[i%len(reqs)])
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package shortmux
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// A Matcher is a predicate on requests attached to a route with
+// [WithMatcher], for routing on more than the method, host and path.
+//
+// Matchers are evaluated after the path selects a candidate route. If any
+// of them rejects the request, matching falls through to the next
+// candidate, as if the route wasn't registered: the next most specific
+// pattern, then patterns with no method and patterns with no host, and
+// lastly a 405 Method Not Allowed or 404 Not Found response.
+//
+// Matchers run while the routes of the mux are locked, possibly several
+// times per request, so they must be fast and must not use the mux.
+type Matcher interface {
+	Match(r *http.Request, s *MatchState) bool
+}
+
+// MatcherFunc is an adapter to use a function as a [Matcher].
+type MatcherFunc func(*http.Request, *MatchState) bool
+
+// Match calls f(r, s).
+func (f MatcherFunc) Match(r *http.Request, s *MatchState) bool {
+	return f(r, s)
+}
+
+// A MatchState describes the candidate route evaluated by a [Matcher].
+type MatchState struct {
+	Pattern string      // pattern of the candidate route
+	Params  []PathParam // wildcard values of the candidate route, in order
+
+	values []keyValue
+}
+
+type keyValue struct {
+	key, val any
+}
+
+// SetValue records a value, such as a decision the matcher made, to be
+// added to the request context under key if the candidate route serves the
+// request.
+func (s *MatchState) SetValue(key, val any) {
+	s.values = append(s.values, keyValue{key, val})
+}
+
+// apply returns r with the values set by the matchers in its context.
+func (s *MatchState) apply(r *http.Request) *http.Request {
+	if len(s.values) == 0 {
+		return r
+	}
+	ctx := r.Context()
+	for _, kv := range s.values {
+		ctx = context.WithValue(ctx, kv.key, kv.val)
+	}
+	return r.WithContext(ctx)
+}
+
+// WithMatcher attaches matchers to the route; the route only serves
+// requests that all of them accept. See [Matcher].
+func WithMatcher(ms ...Matcher) RouteOption {
+	return func(c *routeConfig) {
+		c.matchers = append(c.matchers, ms...)
+	}
+}
+
+// matchRequest is a request being matched against the routing tree, for
+// evaluating the matchers of candidate routes.
+type matchRequest struct {
+	r     *http.Request
+	path  string       // path being matched
+	scope *matchScope  // of the request, or nil
+	leaf  *routingNode // last leaf accepted by its matchers
+	state *MatchState  // state of the matchers of leaf
+
+	// head is set while GET patterns are matched for a HEAD request,
+	// so that those not matching HEAD, as set by strictHEAD and
+	// WithImplicitHEAD, are passed over.
+	head       bool
+	strictHEAD bool
+}
+
+// A matchScope holds the state shared by the matches done for a request.
+type matchScope struct {
+	skip           []*routingNode // leaves passed with Next, or listed by Candidates
+	limit          int            // maximum number of nodes visited, if positive
+	visited        int            // number of nodes visited
+	exceeded       bool           // whether visited exceeded limit
+	consulted      bool           // whether route matchers were evaluated
+	ignoreMatchers bool           // whether leaves match whatever their matchers
+}
+
+// newScope returns the scope of the matches done for r, or nil if
+// matching is not limited.
+func (mux *ServeMux) newScope(r *http.Request) *matchScope {
+	skip := mux.passed(r)
+	if skip == nil && mux.MatchLimit <= 0 && mux.MatchCacheSize <= 0 {
+		return nil
+	}
+	return &matchScope{skip: skip, limit: mux.MatchLimit}
+}
+
+// newMatchRequest returns a matchRequest for r, or nil if r is nil, in
+// which case matchers are not evaluated.
+func newMatchRequest(r *http.Request, path string, sc *matchScope) *matchRequest {
+	if r == nil {
+		return nil
+	}
+	return &matchRequest{r: r, path: path, scope: sc}
+}
+
+// spend records the visit of a node, and reports whether the limit of
+// the scope was exceeded, in which case matching stops.
+func (mr *matchRequest) spend() bool {
+	if mr == nil || mr.scope == nil || mr.scope.limit <= 0 {
+		return false
+	}
+	sc := mr.scope
+	sc.visited++
+	if sc.visited > sc.limit {
+		sc.exceeded = true
+	}
+	return sc.exceeded
+}
+
+// stopped reports whether matching stopped because the limit of the
+// scope was exceeded.
+func (mr *matchRequest) stopped() bool {
+	return mr != nil && mr.scope != nil && mr.scope.exceeded
+}
+
+// accepts reports whether the matchers of the route of the leaf n, if
+// any, accept the request, and n wasn't passed with Next. matches are the
+// wildcard values recorded by matchPath.
+func (mr *matchRequest) accepts(n *routingNode, matches []string) bool {
+	if mr == nil {
+		return true
+	}
+	if mr.head && !n.implicitHEAD(mr.strictHEAD) {
+		return false
+	}
+	if mr.scope != nil && len(mr.scope.skip) > 0 && slices.Contains(mr.scope.skip, n) {
+		return false
+	}
+	if mr.r == nil || n.route == nil || len(n.route.cfg.matchers) == 0 {
+		return true
+	}
+	if mr.scope != nil && mr.scope.ignoreMatchers {
+		return true
+	}
+	if mr.scope != nil {
+		mr.scope.consulted = true
+	}
+	p := n.pattern
+	if p.constrained {
+		matches = p.captures(mr.path)
+	}
+	s := &MatchState{Pattern: p.str, Params: p.params(matches)}
+	for _, m := range n.route.cfg.matchers {
+		if !m.Match(mr.r, s) {
+			return false
+		}
+	}
+	mr.leaf, mr.state = n, s
+	return true
+}
+
+// stateOf returns the state of the matchers of the leaf n, if they were
+// evaluated.
+func (mr *matchRequest) stateOf(n *routingNode) *MatchState {
+	if mr == nil || n == nil || mr.leaf != n {
+		return nil
+	}
+	return mr.state
+}
+
+// MatchQuery returns a matcher accepting requests with the query parameter
+// key, with the given value if value is not empty.
+func MatchQuery(key, value string) Matcher {
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		vs, ok := r.URL.Query()[key]
+		return ok && (value == "" || slices.Contains(vs, value))
+	})
+}
+
+// MatchHeader returns a matcher accepting requests with the header key,
+// with the given value if value is not empty.
+func MatchHeader(key, value string) Matcher {
+	key = http.CanonicalHeaderKey(key)
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		vs, ok := r.Header[key]
+		return ok && (value == "" || slices.Contains(vs, value))
+	})
+}
+
+// MatchCookie returns a matcher accepting requests with the cookie name,
+// with the given value if value is not empty.
+func MatchCookie(name, value string) Matcher {
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		c, err := r.Cookie(name)
+		return err == nil && (value == "" || c.Value == value)
+	})
+}
+
+// MatchTLS returns a matcher accepting requests received over TLS.
+func MatchTLS() Matcher {
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		return r.TLS != nil
+	})
+}
+
+// MatchTimeOfDay returns a matcher accepting requests received between
+// start and end, as durations since midnight in loc, or in local time if
+// loc is nil. If end is before start, the window spans midnight.
+func MatchTimeOfDay(start, end time.Duration, loc *time.Location) Matcher {
+	if loc == nil {
+		loc = time.Local
+	}
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		now := nowFor(r.Context()).In(loc)
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		d := now.Sub(midnight)
+		if start <= end {
+			return d >= start && d < end
+		}
+		return d >= start || d < end
+	})
+}
+
+// AllOf returns a matcher accepting requests accepted by all of ms.
+func AllOf(ms ...Matcher) Matcher {
+	return MatcherFunc(func(r *http.Request, s *MatchState) bool {
+		for _, m := range ms {
+			if !m.Match(r, s) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// AnyOf returns a matcher accepting requests accepted by any of ms.
+func AnyOf(ms ...Matcher) Matcher {
+	return MatcherFunc(func(r *http.Request, s *MatchState) bool {
+		for _, m := range ms {
+			if m.Match(r, s) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not returns a matcher accepting requests m rejects.
+func Not(m Matcher) Matcher {
+	return MatcherFunc(func(r *http.Request, s *MatchState) bool {
+		return !m.Match(r, s)
+	})
+}
@@ -0,0 +1,77 @@
+package shortmux
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithMatcher(t *testing.T) {
+	type tierKey struct{}
+	mux := NewServeMux()
+	show := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %v", r.Pattern, r.Context().Value(tierKey{}))
+	}
+	mux.HandleFunc("/items/{id}", show, WithMatcher(MatchQuery("v", "2"), MatcherFunc(func(r *http.Request, s *MatchState) bool {
+		if s.Pattern != "/items/{id}" || len(s.Params) != 1 || s.Params[0] != (PathParam{"id", r.URL.Query().Get("id")}) {
+			return false
+		}
+		s.SetValue(tierKey{}, "v2")
+		return true
+	})))
+	mux.HandleFunc("/items/", show)
+	mux.HandleFunc("GET /beta/", show, WithMatcher(AnyOf(MatchHeader("X-Beta", ""), MatchCookie("beta", "on"))))
+	mux.HandleFunc("/secure", show, WithMatcher(MatchTLS()))
+	mux.HandleFunc("/never", show, WithMatcher(Not(MatchTimeOfDay(0, 24*time.Hour, time.UTC))))
+
+	for _, test := range []struct {
+		path   string
+		header string
+		cookie string
+		tls    bool
+		want   string
+	}{
+		{"/items/3?v=2&id=3", "", "", false, "/items/{id} v2"},
+		{"/items/3?v=1", "", "", false, "/items/ <nil>"},
+		{"/items/3?v=2&id=4", "", "", false, "/items/ <nil>"},
+		{"/beta/x", "X-Beta", "", false, "GET /beta/ <nil>"},
+		{"/beta/x", "", "on", false, "GET /beta/ <nil>"},
+		{"/beta/x", "", "off", false, "404 page not found\n"},
+		{"/secure", "", "", true, "/secure <nil>"},
+		{"/secure", "", "", false, "404 page not found\n"},
+		{"/never", "", "", false, "404 page not found\n"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.header != "" {
+			r.Header.Set(test.header, "1")
+		}
+		if test.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "beta", Value: test.cookie})
+		}
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	// MatchTimeOfDay windows wrapping around midnight.
+	if !MatchTimeOfDay(time.Hour, time.Hour/2, nil).Match(httptest.NewRequest("GET", "/", nil), nil) &&
+		!MatchTimeOfDay(time.Hour/2, time.Hour, nil).Match(httptest.NewRequest("GET", "/", nil), nil) {
+		t.Error("a time is neither in a window nor in its complement")
+	}
+
+	// Rejected methods are not allowed.
+	mux.HandleFunc("POST /form", show, WithMatcher(MatchHeader("Content-Type", "application/x-www-form-urlencoded")))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/form", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("POST /form without content type: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,286 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseMatcher parses a matcher expression, a predicate on the headers,
+// query parameters and cookies of requests, such as
+//
+//	header("X-Env") == "staging" && query("beta") == "1"
+//
+// The expression is made of comparisons of the form
+// ATTR("name") == "value" or ATTR("name") != "value", where ATTR is
+// header, query or cookie, combined with &&, || and ! and grouped with
+// parentheses. ATTR("name") alone tests that the attribute is present.
+// Strings are Go string literals. An absent attribute compares as the
+// empty string.
+//
+// The returned matcher doesn't allocate, except to unescape query
+// parameters.
+func ParseMatcher(expr string) (Matcher, error) {
+	p := &exprParser{s: expr}
+	e, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("parsing matcher %q: %w", expr, err)
+	}
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		return e.eval(r)
+	}), nil
+}
+
+// MustParseMatcher is like [ParseMatcher] but panics if the expression
+// cannot be parsed.
+func MustParseMatcher(expr string) Matcher {
+	m, err := ParseMatcher(expr)
+	if err != nil {
+		panic("shortmux: " + err.Error())
+	}
+	return m
+}
+
+// A matchExpr is a node of a parsed matcher expression.
+type matchExpr interface {
+	eval(r *http.Request) bool
+}
+
+type (
+	andExpr struct{ x, y matchExpr }
+	orExpr  struct{ x, y matchExpr }
+	notExpr struct{ x matchExpr }
+
+	// attrExpr compares an attribute of the request with value, or tests
+	// its presence if op is empty.
+	attrExpr struct {
+		lookup func(r *http.Request, name string) (string, bool)
+		name   string
+		op     string // "", "==" or "!="
+		value  string
+	}
+)
+
+func (e *andExpr) eval(r *http.Request) bool { return e.x.eval(r) && e.y.eval(r) }
+func (e *orExpr) eval(r *http.Request) bool  { return e.x.eval(r) || e.y.eval(r) }
+func (e *notExpr) eval(r *http.Request) bool { return !e.x.eval(r) }
+
+func (e *attrExpr) eval(r *http.Request) bool {
+	v, ok := e.lookup(r, e.name)
+	switch e.op {
+	case "==":
+		return v == e.value
+	case "!=":
+		return v != e.value
+	}
+	return ok
+}
+
+// exprAttrs are the attributes of requests available to matcher
+// expressions.
+var exprAttrs = map[string]func(r *http.Request, name string) (string, bool){
+	"header": headerAttr,
+	"query":  queryAttr,
+	"cookie": cookieAttr,
+}
+
+// headerAttr returns the first value of the header name, which must be
+// in canonical form.
+func headerAttr(r *http.Request, name string) (string, bool) {
+	vs := r.Header[name]
+	if len(vs) == 0 {
+		return "", false
+	}
+	return vs[0], true
+}
+
+// queryAttr returns the first value of the query parameter name.
+func queryAttr(r *http.Request, name string) (string, bool) {
+	q := r.URL.RawQuery
+	for q != "" {
+		var kv string
+		kv, q, _ = strings.Cut(q, "&")
+		k, v, _ := strings.Cut(kv, "=")
+		if k != name {
+			if !strings.ContainsAny(k, "%+") {
+				continue
+			}
+			if uk, err := url.QueryUnescape(k); err != nil || uk != name {
+				continue
+			}
+		}
+		if strings.ContainsAny(v, "%+") {
+			uv, err := url.QueryUnescape(v)
+			if err != nil {
+				continue
+			}
+			v = uv
+		}
+		return v, true
+	}
+	return "", false
+}
+
+// cookieAttr returns the value of the first cookie name.
+func cookieAttr(r *http.Request, name string) (string, bool) {
+	for _, line := range r.Header["Cookie"] {
+		for line != "" {
+			var part string
+			part, line, _ = strings.Cut(line, ";")
+			k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if !ok || k != name {
+				continue
+			}
+			if len(v) > 1 && v[0] == '"' && v[len(v)-1] == '"' {
+				v = v[1 : len(v)-1]
+			}
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// exprParser is a recursive descent parser of matcher expressions:
+//
+//	expr  = and { "||" and }
+//	and   = unary { "&&" unary }
+//	unary = "!" unary | "(" expr ")" | ATTR "(" string ")" [ ("==" | "!=") string ]
+type exprParser struct {
+	s   string
+	off int
+}
+
+func (p *exprParser) parse() (matchExpr, error) {
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.off < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.off:])
+	}
+	return e, nil
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.off, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) skipSpace() {
+	for p.off < len(p.s) && (p.s[p.off] == ' ' || p.s[p.off] == '\t' || p.s[p.off] == '\n') {
+		p.off++
+	}
+}
+
+// accept consumes tok if it comes next.
+func (p *exprParser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.off:], tok) {
+		p.off += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) or() (matchExpr, error) {
+	x, err := p.and()
+	for err == nil && p.accept("||") {
+		var y matchExpr
+		if y, err = p.and(); err == nil {
+			x = &orExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) and() (matchExpr, error) {
+	x, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var y matchExpr
+		if y, err = p.unary(); err == nil {
+			x = &andExpr{x, y}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) unary() (matchExpr, error) {
+	switch {
+	case p.accept("!"):
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{x}, nil
+	case p.accept("("):
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	}
+	return p.attr()
+}
+
+func (p *exprParser) attr() (matchExpr, error) {
+	p.skipSpace()
+	start := p.off
+	for p.off < len(p.s) && 'a' <= p.s[p.off] && p.s[p.off] <= 'z' {
+		p.off++
+	}
+	ident := p.s[start:p.off]
+	lookup, ok := exprAttrs[ident]
+	if !ok {
+		p.off = start
+		if ident == "" {
+			return nil, p.errorf("expected attribute")
+		}
+		return nil, p.errorf("unknown attribute %q", ident)
+	}
+	if !p.accept("(") {
+		return nil, p.errorf("missing ( after %s", ident)
+	}
+	name, err := p.str()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(")") {
+		return nil, p.errorf("missing )")
+	}
+	if ident == "header" {
+		name = http.CanonicalHeaderKey(name)
+	}
+	e := &attrExpr{lookup: lookup, name: name}
+	for _, op := range []string{"==", "!="} {
+		if p.accept(op) {
+			e.op = op
+			if e.value, err = p.str(); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return e, nil
+}
+
+// str parses a Go string literal.
+func (p *exprParser) str() (string, error) {
+	p.skipSpace()
+	if p.off == len(p.s) || (p.s[p.off] != '"' && p.s[p.off] != '`') {
+		return "", p.errorf("expected string")
+	}
+	q, err := strconv.QuotedPrefix(p.s[p.off:])
+	if err != nil {
+		return "", p.errorf("invalid string")
+	}
+	s, err := strconv.Unquote(q)
+	if err != nil {
+		return "", p.errorf("invalid string")
+	}
+	p.off += len(q)
+	return s, nil
+}
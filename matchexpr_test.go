@@ -0,0 +1,86 @@
+package shortmux
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseMatcher(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?beta=1&name=a+b&e%78=y&empty=", nil)
+	r.Header.Set("X-Env", "staging")
+	r.Header.Add("Cookie", "theme=dark; id=\"42\"")
+
+	for _, test := range []struct {
+		expr string
+		want bool
+	}{
+		{`header("X-Env") == "staging"`, true},
+		{`header("x-env") == "staging"`, true},
+		{`header("X-Env") != "staging"`, false},
+		{`header("X-Env")`, true},
+		{`header("X-Other")`, false},
+		{`header("X-Other") == ""`, true},
+		{`query("beta") == "1"`, true},
+		{`query("name") == "a b"`, true},
+		{`query("ex") == "y"`, true},
+		{`query("empty")`, true},
+		{`query("missing")`, false},
+		{`cookie("theme") == "dark"`, true},
+		{`cookie("id") == "42"`, true},
+		{`cookie("missing")`, false},
+		{`header("X-Env") == "staging" && query("beta") == "1"`, true},
+		{`header("X-Env") == "prod" && query("beta") == "1"`, false},
+		{`header("X-Env") == "prod" || query("beta") == "1"`, true},
+		{`!query("beta")`, false},
+		{`!(header("X-Env") == "prod" || cookie("theme") == "light")`, true},
+		{"query(`beta`)==`1`&&!cookie(\"x\")", true},
+		{`query("missing") || query("beta") && header("X-Env") == "prod"`, false},
+	} {
+		m, err := ParseMatcher(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if got := m.Match(r, nil); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseMatcherErrors(t *testing.T) {
+	for _, test := range []struct {
+		expr, wantErr string
+	}{
+		{``, "at offset 0: expected attribute"},
+		{`host("x")`, `at offset 0: unknown attribute "host"`},
+		{`header`, "at offset 6: missing ( after header"},
+		{`header(X)`, "at offset 7: expected string"},
+		{`header("X"`, "at offset 10: missing )"},
+		{`header("X") == `, "at offset 15: expected string"},
+		{`header("X") = "a"`, `at offset 12: unexpected "= \"a\""`},
+		{`(header("X")`, "at offset 12: missing )"},
+		{`header("X") && `, "at offset 15: expected attribute"},
+		{`header("X\q")`, "at offset 7: invalid string"},
+	} {
+		_, err := ParseMatcher(test.expr)
+		if err == nil || !strings.HasSuffix(err.Error(), test.wantErr) {
+			t.Errorf("%q: got error %v, want %q", test.expr, err, test.wantErr)
+		}
+	}
+}
+
+func TestParseMatcherAllocs(t *testing.T) {
+	m := MustParseMatcher(`header("X-Env") == "staging" && query("beta") == "1" && cookie("theme") != "light"`)
+	r := httptest.NewRequest("GET", "/?a=b&beta=1", nil)
+	r.Header.Set("X-Env", "staging")
+	r.Header.Set("Cookie", "a=b; theme=dark")
+	allocs := testing.AllocsPerRun(100, func() {
+		if !m.Match(r, nil) {
+			t.Fatal("no match")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs, want 0", allocs)
+	}
+}
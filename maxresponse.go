@@ -0,0 +1,117 @@
+package shortmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned by the writes of a handler exceeding the
+// response size set with [WithMaxResponseBytes].
+var ErrResponseTooLarge = errors.New("shortmux: response exceeds the route limit")
+
+// WithMaxResponseBytes limits the response body of the route to n bytes,
+// protecting against accidentally unbounded responses.
+//
+// A write that would exceed the limit writes nothing and fails with
+// [ErrResponseTooLarge], as do all the following writes. If the response
+// wasn't committed yet, it is answered with 500 Internal Server Error;
+// otherwise, the response is aborted with [http.ErrAbortHandler] when the
+// handler returns, so that clients can't mistake it for a complete one.
+//
+// Exceeding responses are reported to the mux OnResponseTooLarge hook, or
+// logged with [slog.Default] if it is nil.
+func WithMaxResponseBytes(n int64) RouteOption {
+	if n < 0 {
+		panic(fmt.Sprintf("shortmux: invalid response size limit %d", n))
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				cw := &cappedWriter{ResponseWriter: w, mux: mux, r: r, limit: n, left: n}
+				next.ServeHTTP(cw, r)
+				if cw.exceeded && cw.committed {
+					panic(http.ErrAbortHandler)
+				}
+			})
+		})
+	}
+}
+
+// cappedWriter is a [http.ResponseWriter] failing the writes beyond a
+// limit.
+type cappedWriter struct {
+	http.ResponseWriter
+	mux       *ServeMux
+	r         *http.Request
+	limit     int64
+	left      int64 // bytes that can still be written
+	committed bool  // whether the header was written
+	exceeded  bool
+}
+
+func (w *cappedWriter) WriteHeader(code int) {
+	if w.exceeded {
+		return
+	}
+	if code < 100 || code > 199 || code == http.StatusSwitchingProtocols {
+		w.committed = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if w.exceeded {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > w.left {
+		w.exceed()
+		return 0, ErrResponseTooLarge
+	}
+	w.committed = true
+	w.left -= int64(len(p))
+	return w.ResponseWriter.Write(p)
+}
+
+// exceed handles a write exceeding the limit.
+func (w *cappedWriter) exceed() {
+	w.exceeded = true
+	if !w.committed {
+		h := w.Header()
+		for k := range h {
+			delete(h, k)
+		}
+		w.mux.Error(w.ResponseWriter, w.r, http.StatusInternalServerError)
+	}
+	if w.mux.OnResponseTooLarge != nil {
+		w.mux.OnResponseTooLarge(w.r, w.limit)
+		return
+	}
+	slog.Warn("shortmux: response too large", "pattern", w.r.Pattern, "method", w.r.Method,
+		"path", w.r.URL.Path, "limit", w.limit, "committed", w.committed)
+}
+
+func (w *cappedWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *cappedWriter) FlushError() error {
+	if w.exceeded {
+		return ErrResponseTooLarge
+	}
+	w.committed = true
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *cappedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *cappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,67 @@
+package shortmux
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	var reported []string
+	mux := NewServeMux()
+	mux.OnResponseTooLarge = func(r *http.Request, limit int64) {
+		reported = append(reported, r.URL.Path)
+		if limit != 4 {
+			t.Errorf("got limit %d, want 4", limit)
+		}
+	}
+	var writeErr error
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		for _, s := range strings.Split(r.URL.Query().Get("w"), ",") {
+			if _, writeErr = w.Write([]byte(s)); writeErr != nil {
+				return
+			}
+		}
+	})
+	mux.Handle("/", h, WithMaxResponseBytes(4))
+
+	serve := func(target string) (w *httptest.ResponseRecorder, aborted bool) {
+		t.Helper()
+		defer func() {
+			if v := recover(); v != nil {
+				if v != http.ErrAbortHandler {
+					panic(v)
+				}
+				aborted = true
+			}
+		}()
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w, false
+	}
+
+	w, aborted := serve("/a?w=ab,cd")
+	if aborted || w.Code != 200 || w.Body.String() != "abcd" || writeErr != nil {
+		t.Errorf("within the limit: got %d %q, aborted %v, %v", w.Code, w.Body, aborted, writeErr)
+	}
+
+	w, aborted = serve("/b?w=abcde")
+	if aborted || w.Code != 500 || !errors.Is(writeErr, ErrResponseTooLarge) {
+		t.Errorf("uncommitted: got %d, aborted %v, %v", w.Code, aborted, writeErr)
+	}
+	if ct := w.Header().Get("Content-Type"); ct == "text/csv" {
+		t.Errorf("uncommitted: got the handler Content-Type")
+	}
+
+	w, aborted = serve("/c?w=ab,cde")
+	if !aborted || w.Body.String() != "ab" || !errors.Is(writeErr, ErrResponseTooLarge) {
+		t.Errorf("committed: got %q, aborted %v, %v", w.Body, aborted, writeErr)
+	}
+
+	if want := []string{"/b", "/c"}; strings.Join(reported, " ") != strings.Join(want, " ") {
+		t.Errorf("got reports %q, want %q", reported, want)
+	}
+}
@@ -0,0 +1,54 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// standardMethods are the methods defined by RFC 9110 and RFC 5789, which
+// StrictMethods never rejects.
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// knownMethod reports whether method is a standard method or the method of
+// a registered pattern.
+func (mux *ServeMux) knownMethod(method string) bool {
+	for _, m := range standardMethods {
+		if m == method {
+			return true
+		}
+	}
+	if !mux.frozen.Load() {
+		mux.mu.RLock()
+		defer mux.mu.RUnlock()
+	}
+	return mux.methods[method] > 0
+}
+
+// rejectMethod replies to r with 501 Not Implemented, as described in
+// StrictMethods, if its method is invalid or unknown, and reports whether
+// it did.
+func (mux *ServeMux) rejectMethod(w http.ResponseWriter, r *http.Request) bool {
+	if validMethod(r.Method) && mux.knownMethod(r.Method) {
+		return false
+	}
+	w.Header().Set("Allow", strings.Join(mux.allMethods(), ", "))
+	mux.Error(w, r, http.StatusNotImplemented)
+	return true
+}
+
+// countMethod adjusts by delta the number of registered patterns with the
+// method of p, if it has one.
+func (mux *ServeMux) countMethod(p *pattern, delta int) {
+	if p.method == "" {
+		return
+	}
+	if mux.methods == nil {
+		mux.methods = map[string]int{}
+	}
+	if mux.methods[p.method] += delta; mux.methods[p.method] == 0 {
+		delete(mux.methods, p.method)
+	}
+}
@@ -0,0 +1,55 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStrictMethods(t *testing.T) {
+	mux := NewServeMux()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("GET /a", h)
+	mux.Handle("PURGE /cache/", h)
+	mux.Handle("/any", h)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Method = method
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve("BREW", "/any"); w.Code != 200 {
+		t.Errorf("without StrictMethods: got %d, want 200", w.Code)
+	}
+
+	mux.StrictMethods = true
+	for _, test := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/a", 200},
+		{"PUT", "/a", 405},
+		{"TRACE", "/missing", 404},
+		{"PURGE", "/cache/x", 200},
+		{"PURGE", "/a", 405},
+		{"BREW", "/any", 501},
+		{"BAD METHOD", "/any", 501},
+	} {
+		w := serve(test.method, test.path)
+		if w.Code != test.want {
+			t.Errorf("%s %s: got %d, want %d", test.method, test.path, w.Code, test.want)
+		}
+		if want := "GET, HEAD, OPTIONS, PURGE"; test.want == 501 && w.Header().Get("Allow") != want {
+			t.Errorf("%s %s: got Allow %q, want %q", test.method, test.path, w.Header().Get("Allow"), want)
+		}
+	}
+
+	if err := mux.Remove("PURGE /cache/"); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve("PURGE", "/any"); w.Code != 501 {
+		t.Errorf("after removal: got %d, want 501", w.Code)
+	}
+}
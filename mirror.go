@@ -0,0 +1,137 @@
+package shortmux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MirrorConfig configures request mirroring for [WithMirrorConfig].
+type MirrorConfig struct {
+	// Target receives a copy of the mirrored requests.
+	// Its responses are discarded.
+	Target http.Handler
+
+	// SampleRate is the fraction of requests to mirror, between 0 and 1.
+	// WithMirrorConfig panics if it is out of range.
+	SampleRate float64
+
+	// MaxBodyBytes is the maximum request body size that is buffered for
+	// mirroring. Requests with larger bodies are not mirrored.
+	// Zero means 64 KiB.
+	MaxBodyBytes int64
+
+	// MaxInFlight is the maximum number of mirrored requests served
+	// concurrently. Requests sampled while the limit is reached are dropped
+	// rather than queued, so a slow Target never delays the route.
+	// Zero means 64.
+	MaxInFlight int
+
+	// OnDrop, if non-nil, is called with each sampled request that could
+	// not be mirrored.
+	OnDrop func(*http.Request)
+}
+
+// WithMirror asynchronously replays a sample of the requests matching the
+// route to target, for shadow testing.
+// It is a shorthand for [WithMirrorConfig] with the default limits.
+func WithMirror(target http.Handler, sampleRate float64) RouteOption {
+	return WithMirrorConfig(MirrorConfig{Target: target, SampleRate: sampleRate})
+}
+
+// WithMirrorConfig asynchronously replays a sample of the requests matching
+// the route to cfg.Target, for shadow testing.
+//
+// Requests are mirrored right before the route handler is called, after
+// every other option of the route, so requests rejected by them, such as
+// by authentication or rate limiting, are never mirrored. The mirrored
+// request is a clone of the original with a context that is not canceled
+// when the original request finishes. Panics in Target are recovered and
+// the mirrored request is dropped.
+func WithMirrorConfig(cfg MirrorConfig) RouteOption {
+	if cfg.Target == nil {
+		panic("shortmux: nil mirror target")
+	}
+	if !(cfg.SampleRate >= 0 && cfg.SampleRate <= 1) {
+		panic(fmt.Sprintf("shortmux: invalid mirror sample rate %v", cfg.SampleRate))
+	}
+	if cfg.MaxBodyBytes == 0 {
+		cfg.MaxBodyBytes = 64 << 10
+	}
+	if cfg.MaxInFlight == 0 {
+		cfg.MaxInFlight = 64
+	}
+	sem := make(chan struct{}, cfg.MaxInFlight)
+	return func(c *routeConfig) {
+		c.inner = append(c.inner, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if cfg.SampleRate > 0 && float64For(r.Context()) < cfg.SampleRate {
+					cfg.mirror(sem, r)
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// mirror sends a copy of r to the target, if the limits allow it.
+// The body of r is replaced by an equivalent reader.
+func (cfg *MirrorConfig) mirror(sem chan struct{}, r *http.Request) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.ContentLength > cfg.MaxBodyBytes {
+			cfg.drop(r)
+			return
+		}
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, cfg.MaxBodyBytes+1))
+		// Give the route the bytes read so far followed by the unread rest.
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		if err != nil || int64(len(body)) > cfg.MaxBodyBytes {
+			cfg.drop(r)
+			return
+		}
+	}
+	select {
+	case sem <- struct{}{}:
+	default:
+		cfg.drop(r)
+		return
+	}
+	mr := r.Clone(context.WithoutCancel(r.Context()))
+	mr.Body = io.NopCloser(bytes.NewReader(body))
+	mr.ContentLength = int64(len(body))
+	mr.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	go func() {
+		defer func() {
+			<-sem
+			if recover() != nil {
+				cfg.drop(mr)
+			}
+		}()
+		cfg.Target.ServeHTTP(&discardResponseWriter{header: http.Header{}}, mr)
+	}()
+}
+
+func (cfg *MirrorConfig) drop(r *http.Request) {
+	if cfg.OnDrop != nil {
+		cfg.OnDrop(r)
+	}
+}
+
+// discardResponseWriter is a [http.ResponseWriter] that discards everything
+// written to it.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
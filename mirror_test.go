@@ -0,0 +1,105 @@
+package shortmux
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithMirror(t *testing.T) {
+	mirrored := make(chan string, 1)
+	target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mirrored <- r.Pattern + " " + r.PathValue("id") + " " + string(b)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	var dropped int
+	mux := NewServeMux()
+	mux.HandleFunc("POST /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		io.WriteString(w, string(b))
+	}, WithMirrorConfig(MirrorConfig{
+		Target:       target,
+		SampleRate:   1,
+		MaxBodyBytes: 8,
+		OnDrop:       func(*http.Request) { dropped++ },
+	}))
+	mux.HandleFunc("/never", func(w http.ResponseWriter, r *http.Request) {}, WithMirror(target, 0))
+
+	for _, test := range []struct {
+		body         string
+		wantMirrored string
+		wantDropped  int
+	}{
+		{"hello", "POST /items/{id} 1 hello", 0},
+		{"longer than eight", "", 1},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/items/1", strings.NewReader(test.body)))
+		if got := w.Body.String(); got != test.body {
+			t.Errorf("route got body %q, want %q", got, test.body)
+		}
+		if test.wantMirrored != "" {
+			if got := <-mirrored; got != test.wantMirrored {
+				t.Errorf("mirror got %q, want %q", got, test.wantMirrored)
+			}
+		}
+		if dropped != test.wantDropped {
+			t.Errorf("got %d dropped, want %d", dropped, test.wantDropped)
+		}
+	}
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/never", nil))
+	select {
+	case got := <-mirrored:
+		t.Errorf("unexpected mirrored request %q", got)
+	default:
+	}
+}
+
+func TestWithMirrorInnermost(t *testing.T) {
+	mirrored := make(chan string, 1)
+	target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrored <- r.URL.Path
+	})
+	deny := func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Deny") != "" {
+					http.Error(w, "denied", http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {}, WithMirror(target, 1), deny)
+
+	r := httptest.NewRequest("GET", "/a", nil)
+	r.Header.Set("Deny", "1")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	if got := <-mirrored; got != "/a" {
+		t.Errorf("mirror got %q", got)
+	}
+	select {
+	case <-mirrored:
+		t.Error("mirrored a request rejected by the route middleware")
+	default:
+	}
+
+	for _, rate := range []float64{-0.5, 1.5, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithMirror(target, %v) didn't panic", rate)
+				}
+			}()
+			WithMirror(target, rate)
+		}()
+	}
+}
@@ -0,0 +1,33 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MountHandler registers h, typically a router of another package, to
+// serve the paths under prefix, such as "/legacy" or
+// "GET api.example.com/v1". It registers the pattern prefix + "/{rest...}"
+// and, as [ServeMux.HandleStripped] does, removes the part of the path
+// matched by prefix before calling h, so h routes the requests as if it
+// served the root of a server. Wildcards of prefix remain available with
+// [http.Request.PathValue], and the remaining path with the "rest"
+// wildcard.
+//
+// The mux doesn't know the routes of h: introspection outputs such as
+// [ServeMux.Routes] list the mounted subtree as a single route, with h as
+// its [Route.Mounted] handler.
+func (mux *ServeMux) MountHandler(prefix string, h http.Handler, opts ...RouteOption) {
+	if h == nil {
+		panic("http: nil handler")
+	}
+	pattern := strings.TrimSuffix(prefix, "/") + "/{rest...}"
+	p, err := mux.parsePattern(pattern)
+	if err != nil {
+		panic("shortmux: mounting at " + prefix + ": " + err.Error())
+	}
+	opts = append(opts[:len(opts):len(opts)], func(c *routeConfig) {
+		c.mounted = h
+	})
+	mux.register(pattern, &strippedHandler{mux: mux, segments: len(p.segments) - 1, next: h}, opts)
+}
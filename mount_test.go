@@ -0,0 +1,72 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMountHandler(t *testing.T) {
+	sub := http.NewServeMux()
+	sub.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user %s %s", r.PathValue("id"), r.URL.Path)
+	})
+	sub.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "sub %s", r.URL.Path)
+	})
+	mux := NewServeMux()
+	mux.MountHandler("/legacy/", sub)
+	mux.MountHandler("/tenants/{tenant}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.PathValue("tenant"), r.PathValue("rest"), r.URL.Path)
+	}))
+	mux.HandleFunc("/legacy/status", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "status")
+	})
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/legacy/users/7", "user 7 /users/7"},
+		{"/legacy/", "sub /"},
+		{"/legacy/a/b", "sub /a/b"},
+		{"/legacy/status", "status"},
+		{"/tenants/acme/x/y", "acme x/y /x/y"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/legacy", nil))
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/legacy/" {
+		t.Errorf("/legacy: got %d to %q, want a redirect to /legacy/", w.Code, w.Header().Get("Location"))
+	}
+
+	var mounted []string
+	for _, r := range mux.Routes() {
+		if r.Mounted != nil {
+			mounted = append(mounted, r.Pattern)
+		}
+	}
+	if got, want := strings.Join(mounted, ","), "/legacy/{rest...},/tenants/{tenant}/{rest...}"; got != want {
+		t.Errorf("mounted routes: got %s, want %s", got, want)
+	}
+	if r := mux.Routes()[0]; r.Pattern != "/legacy/status" || r.Mounted != nil {
+		t.Errorf("got first route %s, mounted %v", r.Pattern, r.Mounted)
+	}
+}
+
+func TestMountHandlerInvalid(t *testing.T) {
+	mux := NewServeMux()
+	defer func() {
+		if recover() == nil {
+			t.Error("mounting under a \"...\" wildcard did not panic")
+		}
+	}()
+	mux.MountHandler("/files/{path...}", http.NotFoundHandler())
+}
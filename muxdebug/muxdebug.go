@@ -0,0 +1,118 @@
+// Package muxdebug registers the standard runtime debugging handlers on a
+// [shortmux.ServeMux], along with handlers listing its routes and
+// simulating the routing of requests.
+//
+// It lives in its own package because importing net/http/pprof and expvar
+// registers their handlers on [http.DefaultServeMux] as a side effect.
+package muxdebug
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+// HandlePprof registers the net/http/pprof handlers under prefix, which must
+// end in a slash, e.g. "/debug/pprof/" or "GET admin.example.com/pprof/".
+//
+// Unlike [pprof.Index], profiles are looked up relative to prefix, so any
+// prefix works. A request for the prefix without the trailing slash is
+// redirected by the mux as usual.
+//
+// The options apply to every route; use [shortmux.WithAuth] to restrict
+// access.
+func HandlePprof(mux *shortmux.ServeMux, prefix string, opts ...shortmux.RouteOption) {
+	if !strings.HasSuffix(prefix, "/") {
+		panic(fmt.Sprintf("muxdebug: pprof prefix %q must end in a slash", prefix))
+	}
+	mux.HandleFunc(prefix+"{$}", pprof.Index, opts...)
+	mux.HandleFunc(prefix+"cmdline", pprof.Cmdline, opts...)
+	mux.HandleFunc(prefix+"profile", pprof.Profile, opts...)
+	mux.HandleFunc(prefix+"symbol", pprof.Symbol, opts...)
+	mux.HandleFunc(prefix+"trace", pprof.Trace, opts...)
+	mux.HandleFunc(prefix+"{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+	}, opts...)
+}
+
+// HandleExpvar registers the expvar handler for pattern, e.g. "/debug/vars".
+func HandleExpvar(mux *shortmux.ServeMux, pattern string, opts ...shortmux.RouteOption) {
+	mux.Handle(pattern, expvar.Handler(), opts...)
+}
+
+// HandleRoutes registers for pattern, e.g. "GET /debug/routes", a handler
+// listing the routes registered on mux, in the order of
+// [shortmux.ServeMux.Routes], with the names of their handler chain,
+// outermost first, and the location of their registration.
+//
+// The listing has an ETag derived from its contents, so that dashboards
+// polling it with If-None-Match get a 304 Not Modified response until the
+// routes change, including across restarts of the server.
+func HandleRoutes(mux *shortmux.ServeMux, pattern string, opts ...shortmux.RouteOption) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+		tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "PATTERN\tHANDLER\tLOCATION")
+		for _, rt := range mux.Routes() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", rt.Pattern, strings.Join(rt.Chain, " > "), rt.Location)
+		}
+		tw.Flush()
+		sum := sha256.Sum256(b.Bytes())
+		w.Header().Set("ETag", `"routes-`+hex.EncodeToString(sum[:12])+`"`)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b.Bytes()))
+	}, opts...)
+}
+
+// A SimulateRequest is the body of a request to the handler registered with
+// [HandleSimulate].
+type SimulateRequest struct {
+	Method string      `json:"method"` // defaults to GET
+	Host   string      `json:"host"`
+	Path   string      `json:"path"` // request URI, e.g. "/a/b?c=d"
+	Header http.Header `json:"header,omitempty"`
+}
+
+// HandleSimulate registers for pattern, e.g. "POST /debug/simulate", a
+// handler that reports how mux routes a request described by a JSON
+// [SimulateRequest] in the request body. It responds with the JSON
+// encoding of the [shortmux.Explanation] of the request.
+//
+// Route handlers are not called, so it is safe to simulate any request.
+func HandleSimulate(mux *shortmux.ServeMux, pattern string, opts ...shortmux.RouteOption) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		var sr SimulateRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&sr); err != nil {
+			http.Error(w, "invalid simulate request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if sr.Method == "" {
+			sr.Method = "GET"
+		}
+		if sr.Path == "" {
+			sr.Path = "/"
+		}
+		sim, err := http.NewRequestWithContext(r.Context(), sr.Method, sr.Path, nil)
+		if err != nil {
+			http.Error(w, "invalid simulate request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sim.RequestURI = sr.Path
+		sim.Host = sr.Host
+		if sr.Header != nil {
+			sim.Header = sr.Header
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mux.Explain(sim))
+	}, opts...)
+}
@@ -0,0 +1,161 @@
+package muxdebug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+func TestHandlePprof(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	HandlePprof(mux, "/admin/pprof/", shortmux.WithAuth(func(r *http.Request) bool {
+		return r.Header.Get("X-Admin") == "yes"
+	}))
+	HandleExpvar(mux, "/admin/vars")
+
+	for _, test := range []struct {
+		path     string
+		admin    bool
+		wantCode int
+		wantBody string
+	}{
+		{"/admin/pprof/", true, http.StatusOK, "goroutine"},
+		{"/admin/pprof/", false, http.StatusForbidden, ""},
+		{"/admin/pprof", true, http.StatusMovedPermanently, ""},
+		{"/admin/pprof/cmdline", true, http.StatusOK, ""},
+		{"/admin/pprof/goroutine?debug=1", true, http.StatusOK, "goroutine profile"},
+		{"/admin/pprof/nope", true, http.StatusNotFound, "Unknown profile"},
+		{"/admin/vars", false, http.StatusOK, "memstats"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.admin {
+			r.Header.Set("X-Admin", "yes")
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, test.wantCode)
+		}
+		if !strings.Contains(w.Body.String(), test.wantBody) {
+			t.Errorf("%s: body %q does not contain %q", test.path, w.Body.String(), test.wantBody)
+		}
+	}
+}
+
+func TestHandleSimulate(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	called := false
+	h := func(w http.ResponseWriter, r *http.Request) { called = true }
+	mux.HandleFunc("GET /users/{id}/files/{path...}", h)
+	mux.HandleFunc("POST /users/{id}", h)
+	mux.HandleFunc("/docs/", h)
+	HandleSimulate(mux, "POST /debug/simulate")
+
+	for _, test := range []struct {
+		body string
+		want string
+	}{
+		{
+			`{"path": "/users/3/files/a/b"}`,
+			`{"method":"GET","host":"","path":"/users/3/files/a/b","pattern":"GET /users/{id}/files/{path...}","params":[{"name":"id","value":"3"},{"name":"path","value":"a/b"}],"status":200}`,
+		},
+		{
+			`{"method": "DELETE", "host": "example.com:8080", "path": "/users/3"}`,
+			`{"method":"DELETE","host":"example.com","path":"/users/3","status":405,"allow":["POST"]}`,
+		},
+		{
+			`{"path": "/docs?q=1"}`,
+			`{"method":"GET","host":"","path":"/docs","status":301,"redirect":"/docs/?q=1"}`,
+		},
+		{
+			`{"path": "/nope"}`,
+			`{"method":"GET","host":"","path":"/nope","status":404}`,
+		},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/debug/simulate", strings.NewReader(test.body)))
+		if got := strings.TrimSpace(w.Body.String()); got != test.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.body, got, test.want)
+		}
+	}
+	if called {
+		t.Error("route handler called")
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/debug/simulate", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleRoutes(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", http.NotFound)
+	HandleRoutes(mux, "GET /debug/routes")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "PATTERN") {
+		t.Fatalf("got\n%s", w.Body.String())
+	}
+	if f := strings.Fields(lines[2]); f[0] != "GET" || f[1] != "/users/{id}" || f[2] != "net/http.NotFound" || !strings.Contains(f[3], "muxdebug_test.go") {
+		t.Errorf("got line %q", lines[2])
+	}
+}
+
+func TestHandleRoutesETag(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	HandleRoutes(mux, "GET /debug/routes")
+	get := func(etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/debug/routes", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	etag := get("").Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Errorf("unchanged routes: got status %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	mux.HandleFunc("/new", http.NotFound)
+	w := get(etag)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/new") {
+		t.Errorf("after registering: got status %d, body %q", w.Code, w.Body.String())
+	}
+	etag = w.Header().Get("ETag")
+
+	if err := mux.Remove("/new"); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after removing: got status %d, ETag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestHandleRoutesETagRestart(t *testing.T) {
+	// Muxes at the same version, as after a restart, with different
+	// routes have different ETags.
+	etag := func(pattern string) string {
+		mux := shortmux.NewServeMux()
+		HandleRoutes(mux, "GET /debug/routes")
+		mux.HandleFunc(pattern, http.NotFound)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+		return w.Header().Get("ETag")
+	}
+	if a, b := etag("/a"), etag("/b"); a == b {
+		t.Errorf("got ETag %s for different routes", a)
+	}
+}
@@ -0,0 +1,75 @@
+package shortmux
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithFallthrough lets the handler of the route pass requests to the next
+// most specific matching route with [Next], for example for plugin routes
+// shadowing the default ones only for some requests.
+func WithFallthrough() RouteOption {
+	return func(c *routeConfig) {
+		c.fallsThrough = true
+	}
+}
+
+// nextState is the state of a request served by a WithFallthrough route.
+type nextState struct {
+	mux  *ServeMux
+	leaf *routingNode   // leaf serving the request
+	skip []*routingNode // leaves that passed it
+}
+
+type nextKey struct{}
+
+// withNext returns r served by the leaf n of a WithFallthrough route.
+func (mux *ServeMux) withNext(r *http.Request, n *routingNode) *http.Request {
+	if ns, ok := r.Context().Value(nextKey{}).(*nextState); ok && ns.mux == mux {
+		ns.leaf = n
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), nextKey{}, &nextState{mux: mux, leaf: n}))
+}
+
+// passed returns the leaves that passed r with Next.
+func (mux *ServeMux) passed(r *http.Request) []*routingNode {
+	if r == nil || !mux.passes.Load() {
+		return nil
+	}
+	if ns, ok := r.Context().Value(nextKey{}).(*nextState); ok && ns.mux == mux {
+		return ns.skip
+	}
+	return nil
+}
+
+// Next passes the request to the next most specific route matching it, as
+// if the route serving it wasn't registered, and serves it there, or
+// responds with 404 Not Found or 405 Method Not Allowed if there is none.
+// Only handlers of routes registered with [WithFallthrough] may call it,
+// before writing a response. The path values set for the route passing the
+// request that the next route doesn't set are left unchanged.
+//
+// Routes passed to are served with their options, except for those applied
+// by the mux before dispatching, such as [WithHTTPSOnly] and
+// [WithServerTiming].
+func Next(w http.ResponseWriter, r *http.Request) {
+	ns, ok := r.Context().Value(nextKey{}).(*nextState)
+	if !ok || ns.leaf == nil {
+		panic("shortmux: Next called for a route without WithFallthrough")
+	}
+	ns.skip = append(ns.skip, ns.leaf)
+	ns.leaf = nil
+	h, pattern, n, matches, st := ns.mux.findHandler(r)
+	// Clone r, as a shallow copy would share its path values, which the
+	// passing handler may still read.
+	r = r.Clone(r.Context())
+	r.Pattern = pattern
+	if n != nil {
+		r = setMatch(r, n, matches, st)
+		if n.route != nil && n.route.cfg.fallsThrough {
+			ns.leaf = n
+		}
+	}
+	h.ServeHTTP(w, r)
+}
@@ -0,0 +1,80 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /items/special", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("pass") {
+			Next(w, r)
+			return
+		}
+		io.WriteString(w, "special")
+	}, WithFallthrough())
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pass") == "2" {
+			Next(w, r)
+			return
+		}
+		io.WriteString(w, "item "+r.PathValue("id")+" "+r.Pattern)
+	}, WithFallthrough())
+	mux.HandleFunc("/items/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "default "+r.Pattern)
+	})
+	mux.HandleFunc("GET /only/{x}", func(w http.ResponseWriter, r *http.Request) {
+		Next(w, r)
+	}, WithFallthrough())
+
+	for _, test := range []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/items/special", 200, "special"},
+		{"/items/special?pass", 200, "item special GET /items/{id}"},
+		{"/items/special?pass=2", 200, "default /items/"},
+		{"/items/7?pass=2", 200, "default /items/"},
+		{"/items/7", 200, "item 7 GET /items/{id}"},
+		{"/only/x", 404, "404 page not found\n"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", test.path, w.Code, w.Body, test.wantCode, test.wantBody)
+		}
+	}
+}
+
+func TestNextWithoutFallthrough(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		Next(w, r)
+	})
+	defer func() {
+		if recover() == nil {
+			t.Error("Next didn't panic")
+		}
+	}()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestNextPathValues(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET /a/{x}/b", func(w http.ResponseWriter, r *http.Request) {
+		Next(w, r)
+		io.WriteString(w, " then "+r.PathValue("x")+" "+r.Pattern)
+	}, WithFallthrough())
+	mux.HandleFunc("GET /{x}/{y}/b", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "next "+r.PathValue("x"))
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/a/1/b", nil))
+	if want := "next a then 1 GET /a/{x}/b"; w.Body.String() != want {
+		t.Errorf("got %q, want %q", w.Body, want)
+	}
+}
@@ -0,0 +1,56 @@
+package shortmux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// A CanceledRequest describes a request whose client went away, canceling
+// its context, before its handler completed the response, for example
+// because the client timed out waiting for a slow endpoint.
+type CanceledRequest struct {
+	Pattern  string        // matched pattern
+	Method   string        // request method
+	Path     string        // request path
+	Status   int           // status code written, or 0 if none was
+	Written  int64         // number of body bytes written
+	Start    time.Time     // when the request was dispatched
+	Duration time.Duration // time until the handler returned
+}
+
+// An Observer is notified of the outcome of requests served by a mux, for
+// metrics. It is called synchronously after the handler returns, so
+// implementations that do expensive work should queue events.
+type Observer interface {
+	// Canceled is called for each matched request whose context was
+	// canceled before its handler returned.
+	Canceled(r *http.Request, e *CanceledRequest)
+}
+
+// ObserverFunc is an adapter to use an ordinary function as an
+// [Observer].
+type ObserverFunc func(r *http.Request, e *CanceledRequest)
+
+// Canceled calls f(r, e).
+func (f ObserverFunc) Canceled(r *http.Request, e *CanceledRequest) {
+	f(r, e)
+}
+
+// observe reports the request r, dispatched at start and answered through
+// w, to the mux Observer if it was canceled.
+func (mux *ServeMux) observe(w *instrumentedWriter, r *http.Request, start time.Time) {
+	if !errors.Is(r.Context().Err(), context.Canceled) {
+		return
+	}
+	mux.Observer.Canceled(r, &CanceledRequest{
+		Pattern:  r.Pattern,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Status:   w.status,
+		Written:  w.written,
+		Start:    start,
+		Duration: time.Since(start),
+	})
+}
@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestObserverCanceled(t *testing.T) {
+	events := make(chan *CanceledRequest, 1)
+	mux := NewServeMux()
+	mux.Observer = ObserverFunc(func(r *http.Request, e *CanceledRequest) {
+		events <- e
+	})
+	started := make(chan struct{})
+	mux.HandleFunc("GET /slow/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "done")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/slow/1", nil)
+	go func() {
+		<-started
+		cancel()
+	}()
+	if res, err := http.DefaultClient.Do(req); err == nil {
+		io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	e := <-events
+	if e.Pattern != "GET /slow/{id}" || e.Path != "/slow/1" || e.Status != http.StatusAccepted || e.Written != int64(len("partial")) {
+		t.Errorf("got %+v", e)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+}
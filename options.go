@@ -0,0 +1,149 @@
+package shortmux
+
+import (
+	"net/http"
+	"time"
+)
+
+// A RouteOption configures a single route.
+// Options are passed to [ServeMux.Handle] or [ServeMux.HandleFunc] when the
+// route is registered and are evaluated in order.
+type RouteOption func(*routeConfig)
+
+// routeConfig holds the configuration collected from the options of a route.
+type routeConfig struct {
+	mux *ServeMux
+	pat *pattern
+
+	// middleware wraps the registered handler. The first element is the
+	// outermost wrapper, so it runs first.
+	middleware []func(http.Handler) http.Handler
+
+	// inner wraps the registered handler inside middleware, for options
+	// that only act on the requests the middleware lets through. The
+	// first element is the outermost wrapper.
+	inner []func(http.Handler) http.Handler
+
+	// meta holds the route metadata set with WithMetadata.
+	meta map[any]any
+
+	// budget is the latency budget set with WithBudget.
+	budget time.Duration
+
+	// class is the route class set with WithClass.
+	class string
+
+	// cors is the CORS policy set with WithCORS.
+	cors *CORSPolicy
+
+	// head is set with WithImplicitHEAD.
+	head headMode
+
+	// matchers are set with WithMatcher.
+	matchers []Matcher
+
+	// name is set with WithName.
+	name string
+
+	// signKey is set with WithSignedURL.
+	signKey []byte
+
+	// session is set with WithoutSession and WithSessionRequired.
+	session sessionMode
+
+	// reserved is set for routes registered through a Reservation.
+	reserved bool
+
+	// httpsOnly is set with WithHTTPSOnly.
+	httpsOnly bool
+
+	// absoluteForm is set with WithAbsoluteForm.
+	absoluteForm bool
+
+	// serverTiming is set with WithServerTiming.
+	serverTiming bool
+
+	// fallsThrough is set with WithFallthrough.
+	fallsThrough bool
+
+	// slashAlias is set with WithSlashAlias.
+	slashAlias bool
+
+	// mounted is the handler mounted with MountHandler.
+	mounted http.Handler
+}
+
+// headMode controls whether a GET route also matches HEAD requests.
+type headMode int8
+
+const (
+	headDefault headMode = iota // as set by ServeMux.StrictHEAD
+	headAllow
+	headDeny
+)
+
+// WithImplicitHEAD sets whether a GET pattern also matches HEAD requests,
+// overriding [ServeMux.StrictHEAD] for the route.
+// With allow set to false, HEAD requests fall through to less specific
+// patterns, such as less specific GET patterns matching HEAD or ones with
+// no method, or get 405 Method Not Allowed.
+// A pattern with the HEAD method always takes precedence.
+// The option has no effect on patterns with other methods.
+func WithImplicitHEAD(allow bool) RouteOption {
+	return func(c *routeConfig) {
+		if allow {
+			c.head = headAllow
+		} else {
+			c.head = headDeny
+		}
+	}
+}
+
+// use appends a middleware to the route.
+func (c *routeConfig) use(mw func(http.Handler) http.Handler) {
+	c.middleware = append(c.middleware, mw)
+}
+
+// wrap returns h wrapped by the route middleware.
+func (c *routeConfig) wrap(h http.Handler) http.Handler {
+	for i := len(c.inner) - 1; i >= 0; i-- {
+		h = c.inner[i](h)
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+	return h
+}
+
+// WithMetadata attaches the value val to the route under key, for
+// introspection with [ServeMux.Routes] and [Route.Value].
+// As with context keys, key should be of a type defined by the package
+// that uses it, to avoid collisions.
+func WithMetadata(key, val any) RouteOption {
+	return func(c *routeConfig) {
+		if c.meta == nil {
+			c.meta = map[any]any{}
+		}
+		c.meta[key] = val
+	}
+}
+
+// WithAuth restricts the route to requests for which allow returns true.
+// Other requests are answered with 403 Forbidden.
+func WithAuth(allow func(*http.Request) bool) RouteOption {
+	if allow == nil {
+		panic("shortmux: nil auth predicate")
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !allow(r) {
+					mux.Error(w, r, http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
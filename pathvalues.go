@@ -0,0 +1,107 @@
+package shortmux
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// A capture is the value matched by a wildcard.
+type capture struct {
+	name, value string
+}
+
+// matchKey is the context key for the [matched] pattern of a request.
+type matchKey struct{}
+
+// matched is the pattern that matched a request with named wildcards.
+type matched struct {
+	pattern *pattern
+	caps    []capture // captures in order, if the pattern repeats names
+}
+
+// withMatched returns a shallow copy of r carrying p, the pattern matched
+// with the wildcard values matches, if p has named wildcards.
+func withMatched(r *http.Request, p *pattern, matches []string) *http.Request {
+	m := &matched{pattern: p}
+	if p.repeated {
+		m.caps = make([]capture, 0, len(matches))
+	}
+	named := false
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			named = true
+			if p.repeated {
+				m.caps = append(m.caps, capture{seg.s, matches[len(m.caps)]})
+			}
+		}
+	}
+	if !named {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), matchKey{}, m))
+}
+
+// PathCaptures returns an iterator over the wildcard names and values of
+// the pattern that matched r, in the order the wildcards appear in the
+// pattern.
+//
+// A wildcard name may appear more than once in a pattern, as in
+// "/{a}/to/{a}". Each occurrence is yielded, while [http.Request.PathValue]
+// returns only the last value; use [PathValues] to get all of them.
+func PathCaptures(r *http.Request) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		m, ok := r.Context().Value(matchKey{}).(*matched)
+		// A request matched again, as by Next, may carry the pattern of
+		// a previous match.
+		if !ok || r.Pattern == "" || m.pattern.String() != r.Pattern {
+			return
+		}
+		if m.pattern.repeated {
+			for _, c := range m.caps {
+				if !yield(c.name, c.value) {
+					return
+				}
+			}
+			return
+		}
+		for _, name := range m.pattern.wildcardNames() {
+			if !yield(name, r.PathValue(name)) {
+				return
+			}
+		}
+	}
+}
+
+// PathValues returns the values of every occurrence of the wildcard name in
+// the pattern that matched r, in order, or nil if there is no such wildcard.
+func PathValues(r *http.Request, name string) []string {
+	var values []string
+	for n, v := range PathCaptures(r) {
+		if n == name {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// HostLabelParam is the name under which [Params] reports the label of the
+// host matched by the wildcard of a [VirtualHosts] name, such as "acme"
+// for the host "acme.example.com" and the name "*.example.com".
+const HostLabelParam = "*"
+
+// Params returns the wildcard names and values of the pattern that matched
+// r, as yielded by [PathCaptures], along with the host label matched by
+// [VirtualHosts], if any, under [HostLabelParam], for middleware handling
+// parameters generically.
+func Params(r *http.Request) url.Values {
+	v := url.Values{}
+	if label, ok := r.Context().Value(hostLabelKey{}).(string); ok {
+		v.Add(HostLabelParam, label)
+	}
+	for name, value := range PathCaptures(r) {
+		v.Add(name, value)
+	}
+	return v
+}
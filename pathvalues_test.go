@@ -0,0 +1,72 @@
+package shortmux
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPathValues(t *testing.T) {
+	mux := NewServeMux()
+	show := func(w http.ResponseWriter, r *http.Request) {
+		var caps []string
+		for name, v := range PathCaptures(r) {
+			caps = append(caps, name+"="+v)
+		}
+		fmt.Fprintf(w, "%s %q %q", strings.Join(caps, ","), r.PathValue("a"), PathValues(r, "a"))
+	}
+	mux.HandleFunc("/{a}/to/{a}", show)
+	mux.HandleFunc("/one/{a}/{rest...}", show)
+	mux.HandleFunc("/", show)
+	mux.HandleFunc("/{a}/via/{a}", func(w http.ResponseWriter, r *http.Request) {
+		Next(w, r)
+	}, WithFallthrough())
+	mux.HandleFunc("/{b}/via/{c...}", show)
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/x/to/y", `a=x,a=y "y" ["x" "y"]`},
+		{"/one/x/y/z", `a=x,rest=y/z "x" ["x"]`},
+		{"/none", ` "" []`},
+		{"/x/via/y", `b=x,c=y "y" []`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got, _ := io.ReadAll(w.Body); string(got) != test.want {
+			t.Errorf("%s: got %s, want %s", test.path, got, test.want)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/{org}/repos/{repo...}", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, Params(r).Encode())
+	})
+	mux.HandleFunc("/{a}/to/{a}", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, Params(r).Encode())
+	})
+	vh := &VirtualHosts{Hosts: map[string]*VirtualHost{
+		"*.example.com": {Handler: mux},
+		"example.com":   {Handler: mux},
+	}}
+	for _, test := range []struct {
+		host, path, want string
+	}{
+		{"example.com", "/go/repos/x/y", "org=go&repo=x%2Fy"},
+		{"tenant.example.com:8080", "/go/repos/x", "%2A=tenant&org=go&repo=x"},
+		{"example.com", "/x/to/y", "a=x&a=y"},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Host = test.host
+		w := httptest.NewRecorder()
+		vh.ServeHTTP(w, r)
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s%s: got %q, want %q", test.host, test.path, got, test.want)
+		}
+	}
+}
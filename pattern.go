@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"unicode"
 )
@@ -30,8 +31,15 @@ type pattern struct {
 	// Paths ending in "{$}" are represented with the literal segment "/".
 	// For example, the path "a/{$}" is represented as a literal segment "a" followed
 	// by a literal segment "/".
-	segments []segment
-	loc      string // source location of registering call, for helpful messages
+	segments    []segment
+	loc         string // source location of registering call, for helpful messages
+	repeated    bool   // whether a wildcard name appears more than once
+	constrained bool   // whether a wildcard is constrained to literals
+	excluding   bool   // whether a wildcard excludes literals
+
+	// suffix holds the segments following a "{name...}" wildcard that
+	// isn't last, such as "meta" in "/files/{path...}/meta", if any.
+	suffix []segment
 }
 
 func (p *pattern) String() string { return p.str }
@@ -40,6 +48,17 @@ func (p *pattern) lastSegment() segment {
 	return p.segments[len(p.segments)-1]
 }
 
+// wildcardNames returns the names of the named wildcards of p, in order.
+func (p *pattern) wildcardNames() []string {
+	var names []string
+	for _, seg := range p.segments {
+		if seg.wild && seg.s != "" {
+			names = append(names, seg.s)
+		}
+	}
+	return names
+}
+
 // A segment is a pattern piece that matches one or more path segments, or
 // a trailing slash.
 //
@@ -58,10 +77,22 @@ func (p *pattern) lastSegment() segment {
 // Example:
 //
 //	"{rest...}" => segment{s: "rest", wild: true, multi: true}
+//
+// A single wildcard may be constrained to match one of a set of literals.
+// Example:
+//
+//	"{format:(json|xml)}" => segment{s: "format", wild: true, enum: []string{"json", "xml"}}
+//
+// Or it may exclude some literals.
+// Example:
+//
+//	"{page:!(admin|login)}" => segment{s: "page", wild: true, exclude: []string{"admin", "login"}}
 type segment struct {
-	s     string // literal or wildcard name or "/" for "/{$}".
-	wild  bool
-	multi bool // "..." wildcard
+	s       string // literal or wildcard name or "/" for "/{$}".
+	wild    bool
+	multi   bool     // "..." wildcard
+	enum    []string // literals matched by a constrained wildcard, if any
+	exclude []string // literals not matched by the wildcard, if any
 }
 
 // parsePattern parses a string into a Pattern.
@@ -71,17 +102,29 @@ type segment struct {
 //
 // where:
 //   - METHOD is an HTTP method
-//   - HOST is a hostname
+//   - HOST is a hostname or a bracketed IPv6 literal
 //   - PATH consists of slash-separated segments, where each segment is either
 //     a literal or a wildcard of the form "{name}", "{name...}", or "{$}".
+//     A "{name}" wildcard may be constrained to a set of literals with the
+//     form "{name:(a|b|c)}", or exclude some with "{name:!a}" or
+//     "{name:!(a|b|c)}".
 //
 // METHOD, HOST and PATH are all optional; that is, the string can be "/".
 // If METHOD is present, it must be followed by at least one space or tab.
 // Wildcard names must be valid Go identifiers.
-// The "{$}" and "{name...}" wildcard must occur at the end of PATH.
+// The "{$}" wildcard must occur at the end of PATH. So must the "{name...}"
+// wildcard, except for one followed by literal or "{name}" segments, then
+// optionally by "{$}", a "{name...}" wildcard or a trailing slash.
 // PATH may end with a '/'.
-// Wildcard names in a path must be distinct.
-func parsePattern(s string) (_ *pattern, err error) {
+// A wildcard name may be repeated; see [PathValues].
+func parsePattern(s string) (*pattern, error) {
+	return parsePatternNames(s, nil)
+}
+
+// parsePatternNames is like parsePattern, but if validName is non-nil,
+// wildcard names are unescaped and checked with it instead of having to be
+// Go identifiers.
+func parsePatternNames(s string, validName func(string) bool) (_ *pattern, err error) {
 	if len(s) == 0 {
 		return nil, errors.New("empty pattern")
 	}
@@ -118,6 +161,13 @@ func parsePattern(s string) (_ *pattern, err error) {
 		off += j
 		return nil, errors.New("host contains '{' (missing initial '/'?)")
 	}
+	if strings.HasPrefix(p.host, "[") {
+		ip, ok := canonicalIPv6(p.host)
+		if !ok {
+			return nil, fmt.Errorf("invalid IPv6 host %q", p.host)
+		}
+		p.host = ip
+	}
 	// At this point, rest is the path.
 	off += i
 
@@ -127,13 +177,17 @@ func parsePattern(s string) (_ *pattern, err error) {
 		return nil, errors.New("non-CONNECT pattern with unclean path can never match")
 	}
 
-	seenNames := map[string]bool{} // remember wildcard names to catch dups
+	seenNames := map[string]bool{} // remember wildcard names to catch repeats
+	lazy := -1                     // index of a "{name...}" wildcard that isn't last
 	for len(rest) > 0 {
 		// Invariant: rest[0] == '/'.
 		rest = rest[1:]
 		off = len(s) - len(rest)
 		if len(rest) == 0 {
 			// Trailing slash.
+			if lazy >= 0 && lazy == len(p.segments)-1 {
+				return nil, errors.New("{...} wildcard followed by a trailing slash")
+			}
 			p.segments = append(p.segments, segment{wild: true, multi: true})
 			break
 		}
@@ -163,26 +217,127 @@ func parsePattern(s string) (_ *pattern, err error) {
 				p.segments = append(p.segments, segment{s: "/"})
 				break
 			}
+			name, constraint, constrained := strings.Cut(name, ":")
 			name, multi := strings.CutSuffix(name, "...")
 			if multi && len(rest) != 0 {
-				return nil, errors.New("{...} wildcard not at end")
+				if lazy >= 0 {
+					return nil, errors.New("{...} wildcard not at end")
+				}
+				lazy = len(p.segments)
+			}
+			if multi && lazy >= 0 && lazy == len(p.segments)-1 {
+				return nil, errors.New("{...} wildcard followed by {...} wildcard")
 			}
 			if name == "" {
 				return nil, errors.New("empty wildcard")
 			}
-			if !isValidWildcardName(name) {
+			if validName == nil {
+				if !isValidWildcardName(name) {
+					return nil, fmt.Errorf("bad wildcard name %q", name)
+				}
+			} else if name = pathUnescape(name); name == "" || !validName(name) {
 				return nil, fmt.Errorf("bad wildcard name %q", name)
 			}
 			if seenNames[name] {
-				return nil, fmt.Errorf("duplicate wildcard name %q", name)
+				p.repeated = true
 			}
 			seenNames[name] = true
-			p.segments = append(p.segments, segment{s: name, wild: true, multi: multi})
+			seg := segment{s: name, wild: true, multi: multi}
+			if constrained {
+				if multi {
+					return nil, errors.New("{...} wildcard cannot be constrained")
+				}
+				if exclude, ok := strings.CutPrefix(constraint, "!"); ok {
+					if !strings.HasPrefix(exclude, "(") {
+						exclude = "(" + exclude + ")"
+					}
+					if seg.exclude, err = parseEnum(exclude); err != nil {
+						return nil, err
+					}
+					p.excluding = true
+				} else {
+					if seg.enum, err = parseEnum(constraint); err != nil {
+						return nil, err
+					}
+					p.constrained = true
+				}
+			}
+			p.segments = append(p.segments, seg)
 		}
 	}
+	if lazy >= 0 {
+		p.suffix = p.segments[lazy+1:]
+	}
 	return p, nil
 }
 
+// parseEnum parses the constraint of a wildcard of the form "(a|b|c)" into
+// the literals it allows.
+func parseEnum(s string) ([]string, error) {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, fmt.Errorf("bad wildcard constraint %q (want \"(a|b|...)\")", s)
+	}
+	var enum []string
+	for lit := range strings.SplitSeq(s[1:len(s)-1], "|") {
+		if lit == "" {
+			return nil, fmt.Errorf("empty literal in wildcard constraint %q", s)
+		}
+		lit = pathUnescape(lit)
+		if slices.Contains(enum, lit) {
+			return nil, fmt.Errorf("duplicate literal %q in wildcard constraint", lit)
+		}
+		enum = append(enum, lit)
+	}
+	return enum, nil
+}
+
+// rejects reports whether a wildcard of p excludes its value in matches,
+// as recorded by [routingNode.matchPath]: a value for each single wildcard
+// that isn't constrained to literals, and for a "{name...}" wildcard
+// followed by a suffix, in order.
+func (p *pattern) rejects(matches []string) bool {
+	i := 0
+	for _, seg := range p.segments {
+		if seg.multi && p.suffix != nil {
+			i++
+			continue
+		}
+		if !seg.wild || seg.multi || seg.enum != nil {
+			continue
+		}
+		if i < len(matches) && slices.Contains(seg.exclude, matches[i]) {
+			return true
+		}
+		i++
+	}
+	return false
+}
+
+// captures returns the values for the wildcards of p matching path, as
+// [routingNode.match] does. It assumes p matches path.
+func (p *pattern) captures(path string) []string {
+	var matches []string
+	for _, seg := range p.segments {
+		if seg.multi && p.suffix != nil {
+			vals, _, _ := p.splitSuffix(path, true)
+			matches = append(matches, vals...)
+			break
+		}
+		if seg.multi {
+			if seg.s != "" {
+				matches = append(matches, pathUnescape(path[1:]))
+			}
+			break
+		}
+		var s string
+		s, path = firstSegment(path)
+		if seg.wild {
+			matches = append(matches, s)
+		}
+	}
+	return matches
+}
+
 func isValidWildcardName(s string) bool {
 	if s == "" {
 		return false
@@ -0,0 +1,105 @@
+package shortmux
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// The headers carrying the route of a parent request to child requests
+// built with [PropagateParams] or [NewChildRequest].
+const (
+	ParentPatternHeader = "Shortmux-Parent-Pattern" // pattern of the parent route
+	ParentParamsHeader  = "Shortmux-Parent-Params"  // wildcard values, URL-encoded
+)
+
+// parentKey is the context key for the parent route of a child request.
+type parentKey struct{}
+
+// parentRoute is the route of a parent request, as seen by a child.
+type parentRoute struct {
+	pattern string
+	params  url.Values
+}
+
+// parentOf returns the route of parent, keeping only the wildcards in
+// names, or all of them if names is empty.
+func parentOf(parent *http.Request, names []string) *parentRoute {
+	pr := &parentRoute{pattern: parent.Pattern, params: url.Values{}}
+	for name, value := range PathCaptures(parent) {
+		if len(names) == 0 || slices.Contains(names, name) {
+			pr.params.Add(name, value)
+		}
+	}
+	return pr
+}
+
+// PropagateParams sets the headers of an outbound request, e.g. in the
+// Rewrite function of an [httputil.ReverseProxy], to carry the pattern of
+// the route that matched parent and the values of its wildcards in names,
+// or of all of them if names is empty. A shortmux server can read them
+// with [ParentParams] on routes registered with [WithParentParams].
+func PropagateParams(h http.Header, parent *http.Request, names ...string) {
+	pr := parentOf(parent, names)
+	h.Set(ParentPatternHeader, pr.pattern)
+	h.Set(ParentParamsHeader, pr.params.Encode())
+}
+
+// NewChildRequest returns a request for fan-out from the handler of
+// parent, with the context of parent, carrying the route of parent as
+// [PropagateParams] does. The route is also in the context of the request,
+// so that [ParentParams] works when it is dispatched in process, for
+// example with [ServeMux.ServeHTTP].
+func NewChildRequest(parent *http.Request, method, url string, body io.Reader, names ...string) (*http.Request, error) {
+	pr := parentOf(parent, names)
+	r, err := http.NewRequestWithContext(context.WithValue(parent.Context(), parentKey{}, pr), method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(ParentPatternHeader, pr.pattern)
+	r.Header.Set(ParentParamsHeader, pr.params.Encode())
+	return r, nil
+}
+
+// WithParentParams makes the route trust the headers set by
+// [PropagateParams] and [NewChildRequest], which are then removed from
+// the request and available with [ParentParams]. Routes without this
+// option ignore the headers, as any client can send them; register it
+// only on routes reachable from trusted peers.
+func WithParentParams() RouteOption {
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				pattern, encoded := r.Header.Get(ParentPatternHeader), r.Header.Get(ParentParamsHeader)
+				r.Header.Del(ParentPatternHeader)
+				r.Header.Del(ParentParamsHeader)
+				if pattern == "" && encoded == "" {
+					next.ServeHTTP(w, r)
+					return
+				}
+				params, err := url.ParseQuery(encoded)
+				if err != nil {
+					mux.errorText(w, r, http.StatusBadRequest, "invalid "+ParentParamsHeader+" header")
+					return
+				}
+				pr := &parentRoute{pattern: pattern, params: params}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), parentKey{}, pr)))
+			})
+		})
+	}
+}
+
+// ParentParams returns the pattern and wildcard values of the parent
+// request r was made for, if r was built with [NewChildRequest] and
+// dispatched in process, or if it was received on a route registered
+// with [WithParentParams].
+func ParentParams(r *http.Request) (pattern string, params url.Values, ok bool) {
+	pr, ok := r.Context().Value(parentKey{}).(*parentRoute)
+	if !ok {
+		return "", nil, false
+	}
+	return pr.pattern, pr.params, true
+}
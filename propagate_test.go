@@ -0,0 +1,75 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParentParams(t *testing.T) {
+	backend := NewServeMux()
+	show := func(w http.ResponseWriter, r *http.Request) {
+		pattern, params, ok := ParentParams(r)
+		fmt.Fprintf(w, "%t %q %s header=%q", ok, pattern, params.Encode(), r.Header.Get(ParentParamsHeader))
+	}
+	backend.HandleFunc("/trusted", show, WithParentParams())
+	backend.HandleFunc("/public", show)
+	srv := httptest.NewServer(backend)
+	defer srv.Close()
+
+	var got []string
+	front := NewServeMux()
+	front.HandleFunc("/orgs/{org}/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range []string{"/trusted", "/public"} {
+			child, err := NewChildRequest(r, "GET", srv.URL+path, nil, "org")
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.DefaultClient.Do(child)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b [256]byte
+			n, _ := res.Body.Read(b[:])
+			res.Body.Close()
+			got = append(got, string(b[:n]))
+		}
+
+		// In process, the context carries the parent route.
+		child, _ := NewChildRequest(r, "GET", "/public", nil)
+		w2 := httptest.NewRecorder()
+		backend.ServeHTTP(w2, child)
+		got = append(got, w2.Body.String())
+
+		// For proxies.
+		h := http.Header{}
+		PropagateParams(h, r, "id")
+		got = append(got, h.Get(ParentParamsHeader))
+	})
+	front.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orgs/acme/users/7", nil))
+
+	want := []string{
+		`true "/orgs/{org}/users/{id}" org=acme header=""`,
+		`false ""  header="org=acme"`,
+		`true "/orgs/{org}/users/{id}" id=7&org=acme header="id=7&org=acme"`,
+		"id=7",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %q, want %q", got[i], want[i])
+		}
+	}
+
+	// Forged headers must be well formed.
+	r := httptest.NewRequest("GET", "/trusted", nil)
+	r.Header.Set(ParentParamsHeader, "%zz")
+	w := httptest.NewRecorder()
+	backend.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("malformed header: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
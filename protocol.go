@@ -0,0 +1,56 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// WithTLS restricts the route to requests received over TLS.
+// Plaintext requests, including h2c, are answered with
+// 426 Upgrade Required.
+func WithTLS() RouteOption {
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.TLS == nil {
+					w.Header().Set("Upgrade", "TLS/1.2, HTTP/1.1")
+					if r.ProtoMajor < 2 {
+						// Connection-specific headers are not allowed on HTTP/2.
+						w.Header().Set("Connection", "Upgrade")
+					}
+					mux.Error(w, r, http.StatusUpgradeRequired)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// WithMinProto restricts the route to requests whose protocol version is
+// at least major.minor.
+// Other requests are answered with 505 HTTP Version Not Supported.
+//
+// For example, gRPC methods are only served over HTTP/2:
+//
+//	mux.Handle("POST /pkg.Service/Method", h, shortmux.WithMinProto(2, 0))
+//
+// Combine it with [WithTLS] to reject h2c.
+func WithMinProto(major, minor int) RouteOption {
+	if major < 0 || minor < 0 {
+		panic(fmt.Sprintf("shortmux: invalid protocol version %d.%d", major, minor))
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !r.ProtoAtLeast(major, minor) {
+					mux.Error(w, r, http.StatusHTTPVersionNotSupported)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
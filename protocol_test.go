@@ -0,0 +1,46 @@
+package shortmux
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProtocolOptions(t *testing.T) {
+	mux := NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("/tls", ok, WithTLS())
+	mux.HandleFunc("POST /pkg.Service/Method", ok, WithMinProto(2, 0))
+	mux.HandleFunc("/h2", ok, WithTLS(), WithMinProto(2, 0))
+
+	for _, test := range []struct {
+		method string
+		path   string
+		major  int
+		tls    bool
+		want   int
+	}{
+		{"GET", "/tls", 1, false, http.StatusUpgradeRequired},
+		{"GET", "/tls", 1, true, http.StatusOK},
+		{"POST", "/pkg.Service/Method", 1, false, http.StatusHTTPVersionNotSupported},
+		{"POST", "/pkg.Service/Method", 2, false, http.StatusOK},
+		{"GET", "/h2", 2, false, http.StatusUpgradeRequired},
+		{"GET", "/h2", 1, true, http.StatusHTTPVersionNotSupported},
+		{"GET", "/h2", 2, true, http.StatusOK},
+	} {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		r.ProtoMajor, r.ProtoMinor = test.major, 0
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Errorf("%s %s HTTP/%d tls=%t: got %d, want %d", test.method, test.path, test.major, test.tls, w.Code, test.want)
+		}
+		if w.Code == http.StatusUpgradeRequired && w.Header().Get("Upgrade") == "" {
+			t.Errorf("%s %s: missing Upgrade header", test.method, test.path)
+		}
+	}
+}
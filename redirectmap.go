@@ -0,0 +1,110 @@
+package shortmux
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// redirectMap holds the redirects added with ServeMux.RedirectMap, in a
+// single map rather than as routes, so that it scales to many thousands of
+// legacy URLs.
+type redirectMap struct {
+	mu      sync.RWMutex
+	entries map[string]redirectEntry // by path or host and path
+}
+
+type redirectEntry struct {
+	to   string
+	code int
+}
+
+// RedirectMap adds redirects from each key of m to its value, with the
+// given 3xx status code, such as [http.StatusMovedPermanently]. Keys are
+// paths, as in "/old/page", or hosts and paths, as in
+// "example.com/old/page", and are matched exactly against the escaped,
+// cleaned path of requests. Values are URLs or paths; the query of the
+// request is kept if the value has none.
+//
+// Redirects are checked before the registered patterns, and take precedence
+// over them. Redirects for the same key replace each other.
+// Use [ParseRedirectsCSV] and [ParseRedirectsJSON] to load m from a file.
+func (mux *ServeMux) RedirectMap(m map[string]string, code int) error {
+	if code < 300 || code > 399 {
+		return fmt.Errorf("shortmux: invalid redirect status code %d", code)
+	}
+	for from, to := range m {
+		if !strings.Contains(from, "/") || to == "" {
+			return fmt.Errorf("shortmux: invalid redirect from %q to %q", from, to)
+		}
+	}
+	rm := &mux.redirects
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.entries == nil {
+		rm.entries = make(map[string]redirectEntry, len(m))
+	}
+	for from, to := range m {
+		rm.entries[from] = redirectEntry{to: to, code: code}
+	}
+	return nil
+}
+
+// handler returns the handler redirecting requests for host and path,
+// along with the matching key, or nil.
+func (rm *redirectMap) handler(host, path string) (http.Handler, string) {
+	key := host + path
+	rm.mu.RLock()
+	e, ok := rm.entries[key]
+	if !ok {
+		key = path
+		e, ok = rm.entries[key]
+	}
+	rm.mu.RUnlock()
+	if !ok {
+		return nil, ""
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		to := e.to
+		if r.URL.RawQuery != "" && !strings.Contains(to, "?") {
+			to += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, to, e.code)
+	}), key
+}
+
+// ParseRedirectsCSV reads redirects for [ServeMux.RedirectMap] from CSV
+// records with two fields, the source and the target. Lines starting with
+// "#" are ignored.
+func ParseRedirectsCSV(r io.Reader) (map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.ReuseRecord = true
+	m := map[string]string{}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[strings.TrimSpace(rec[0])] = strings.TrimSpace(rec[1])
+	}
+}
+
+// ParseRedirectsJSON reads redirects for [ServeMux.RedirectMap] from a
+// JSON object mapping sources to targets.
+func ParseRedirectsJSON(r io.Reader) (map[string]string, error) {
+	var m map[string]string
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
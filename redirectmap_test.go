@@ -0,0 +1,58 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectMap(t *testing.T) {
+	m, err := ParseRedirectsCSV(strings.NewReader(`# legacy blog
+/blog/2010/hello.html, /posts/hello
+/about.php,/about
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+	if err := mux.RedirectMap(m, http.StatusMovedPermanently); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ParseRedirectsJSON(strings.NewReader(`{"old.example.com/about.php": "https://example.com/about", "/promo": "/sale?ref=promo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mux.RedirectMap(m, http.StatusFound); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		target string
+		code   int
+		loc    string
+	}{
+		{"http://example.com/blog/2010/hello.html", 301, "/posts/hello"},
+		{"http://example.com/about.php?x=1", 301, "/about?x=1"},
+		{"http://old.example.com/about.php", 302, "https://example.com/about"},
+		{"http://example.com/promo?x=1", 302, "/sale?ref=promo"},
+		{"http://example.com/blog/2010/other.html", 200, ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.target, nil))
+		if w.Code != test.code || w.Header().Get("Location") != test.loc {
+			t.Errorf("%s: status %d, Location %q; want %d, %q", test.target, w.Code, w.Header().Get("Location"), test.code, test.loc)
+		}
+	}
+
+	if err := mux.RedirectMap(map[string]string{"/a": "/b"}, 200); err == nil {
+		t.Error("RedirectMap accepted status 200")
+	}
+	if err := mux.RedirectMap(map[string]string{"a": "/b"}, 301); err == nil {
+		t.Error("RedirectMap accepted a source without a path")
+	}
+	if _, err := ParseRedirectsCSV(strings.NewReader("/a,/b,/c\n")); err == nil {
+		t.Error("ParseRedirectsCSV accepted a record with three fields")
+	}
+}
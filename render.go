@@ -0,0 +1,138 @@
+package shortmux
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// A Renderer renders named views, such as HTML pages, for [Render].
+type Renderer interface {
+	Render(w io.Writer, r *http.Request, name string, data any) error
+}
+
+// WithRenderer sets the renderer used by [Render] for the requests of the
+// route. Registering a group of routes, such as the pages of an admin
+// area, with a [ServeMux.Group] with the option gives them a common set of
+// templates and layout.
+func WithRenderer(rd Renderer) RouteOption {
+	if rd == nil {
+		panic("shortmux: nil renderer")
+	}
+	return func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rc := &renderContext{mux: c.mux, rd: rd, name: c.name}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), renderKey{}, rc)))
+			})
+		})
+	}
+}
+
+type renderKey struct{}
+
+// renderContext is the rendering configuration of a route.
+type renderContext struct {
+	mux  *ServeMux
+	rd   Renderer
+	name string // name of the route, for views
+}
+
+// Render renders the view name with data using the renderer of the route
+// of r, set with [WithRenderer], and writes it to w as HTML. If rendering
+// fails, nothing is written but a 500 Internal Server Error response, and
+// the error is returned. It panics if the route has no renderer.
+func Render(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	rc, ok := r.Context().Value(renderKey{}).(*renderContext)
+	if !ok {
+		panic("shortmux: route " + r.Pattern + " has no renderer")
+	}
+	var b bytes.Buffer
+	if err := rc.rd.Render(&b, r, name, data); err != nil {
+		slog.Error("shortmux: rendering view", "pattern", r.Pattern, "view", name, "error", err)
+		rc.mux.Error(w, r, http.StatusInternalServerError)
+		return err
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// A View is the data a [TemplateRenderer] passes to its templates.
+type View struct {
+	Data    any           // data passed to Render
+	Pattern string        // pattern of the matched route
+	Route   string        // name of the matched route set with WithName, or empty
+	Path    string        // request path
+	Content template.HTML // rendered view, for the layout
+
+	r *http.Request
+}
+
+// PathValue returns the value of the wildcard name of the matched route.
+func (v *View) PathValue(name string) string {
+	return v.r.PathValue(name)
+}
+
+// IsCurrent reports whether the matched route is named name, for
+// highlighting the current item of the navigation.
+func (v *View) IsCurrent(name string) bool {
+	return v.Route != "" && v.Route == name
+}
+
+// A Crumb is an item of the breadcrumbs of a [View].
+type Crumb struct {
+	Label string // last segment of URL, or "/"
+	URL   string
+}
+
+// Breadcrumbs returns an item for each path prefix of the request, from
+// the root to the request path.
+func (v *View) Breadcrumbs() []Crumb {
+	crumbs := []Crumb{{Label: "/", URL: "/"}}
+	p := strings.Trim(v.r.URL.EscapedPath(), "/")
+	if p == "" {
+		return crumbs
+	}
+	segs := strings.Split(p, "/")
+	unescaped := strings.Split(strings.Trim(v.Path, "/"), "/")
+	for i, seg := range segs {
+		label := seg
+		if len(unescaped) == len(segs) {
+			label = unescaped[i]
+		}
+		crumbs = append(crumbs, Crumb{Label: label, URL: "/" + strings.Join(segs[:i+1], "/")})
+	}
+	return crumbs
+}
+
+// TemplateRenderer is a [Renderer] executing the template with the name of
+// the view from Templates with a [View], then the Layout template, if set,
+// with the same View, whose Content is the output of the first template.
+type TemplateRenderer struct {
+	Templates *template.Template
+	Layout    string
+}
+
+// Render implements [Renderer].
+func (t *TemplateRenderer) Render(w io.Writer, r *http.Request, name string, data any) error {
+	v := &View{Data: data, Pattern: r.Pattern, Path: r.URL.Path, r: r}
+	if rc, ok := r.Context().Value(renderKey{}).(*renderContext); ok {
+		v.Route = rc.name
+	}
+	if t.Layout == "" {
+		return t.Templates.ExecuteTemplate(w, name, v)
+	}
+	var b bytes.Buffer
+	if err := t.Templates.ExecuteTemplate(&b, name, v); err != nil {
+		return err
+	}
+	v.Content = template.HTML(b.String())
+	return t.Templates.ExecuteTemplate(w, t.Layout, v)
+}
@@ -0,0 +1,45 @@
+package shortmux
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tmpl := template.Must(template.New("").Parse(`
+{{- define "layout" -}}
+<nav>{{range .Breadcrumbs}}<a href="{{.URL}}">{{.Label}}</a>{{end}}</nav>
+{{- if .IsCurrent "users"}}<b>Users</b>{{else}}<a>Users</a>{{end}}
+<main>{{.Content}}</main>
+{{- end -}}
+{{- define "user" -}}<h1>{{.Data}} #{{.PathValue "id"}}</h1>{{- end -}}
+{{- define "broken" -}}{{.Data.Missing}}{{- end -}}
+`))
+	admin := WithRenderer(&TemplateRenderer{Templates: tmpl, Layout: "layout"})
+	mux := NewServeMux()
+	mux.HandleFunc("GET /admin/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, "user", "<Gopher>")
+	}, admin, WithName("users"))
+	mux.HandleFunc("GET /admin/broken", func(w http.ResponseWriter, r *http.Request) {
+		Render(w, r, "broken", 1)
+	}, admin)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/users/7", nil))
+	want := `<nav><a href="/">/</a><a href="/admin">admin</a><a href="/admin/users">users</a><a href="/admin/users/7">7</a></nav><b>Users</b>
+<main><h1>&lt;Gopher&gt; #7</h1></main>`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/admin/broken", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("broken view: status %d", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package shortmux
+
+import (
+	"iter"
+	"net/http"
+	"slices"
+)
+
+// A Request describes a request to route without serving it, such as a
+// request parsed from an access log, for [ServeMux.Replay].
+type Request struct {
+	Method string // defaults to GET
+	Host   string
+	Path   string // request URI, e.g. "/a/b?c=d"
+}
+
+// httpRequest returns an equivalent request as received by a server.
+func (req Request) httpRequest() (*http.Request, error) {
+	method, path := req.Method, req.Path
+	if method == "" {
+		method = "GET"
+	}
+	if path == "" {
+		path = "/"
+	}
+	r, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.RequestURI = path
+	r.Host = req.Host
+	return r, nil
+}
+
+// A ReplayReport is the distribution of the routing decisions of a mux for
+// a set of requests, as computed by [ServeMux.Replay].
+type ReplayReport struct {
+	Requests  int            // number of requests replayed
+	Matched   map[string]int // requests dispatched to a route, by pattern
+	Redirects map[string]int // requests redirected by the mux, by Location
+	Unmatched map[int]int    // other requests answered by the mux, by status code, e.g. 404
+	Invalid   int            // requests that aren't valid HTTP requests
+
+	// Unused lists the patterns of the routes no request matched, sorted
+	// with CompareRoutes.
+	Unused []string
+}
+
+// Replay routes each of reqs, without calling any route handler, and
+// reports the distribution of the routing decisions, for validating a new
+// routing table against the traffic of the old one before the cutover.
+func (mux *ServeMux) Replay(reqs iter.Seq[Request]) *ReplayReport {
+	rep := &ReplayReport{Matched: map[string]int{}, Redirects: map[string]int{}, Unmatched: map[int]int{}}
+	for req := range reqs {
+		rep.Requests++
+		r, err := req.httpRequest()
+		if err != nil {
+			rep.Invalid++
+			continue
+		}
+		e := mux.Explain(r)
+		switch {
+		case e.Pattern != "":
+			rep.Matched[e.Pattern]++
+		case e.Redirect != "":
+			rep.Redirects[e.Redirect]++
+		default:
+			rep.Unmatched[e.Status]++
+		}
+	}
+	for _, rt := range mux.Routes() {
+		if rep.Matched[rt.Pattern] == 0 {
+			rep.Unused = append(rep.Unused, rt.Pattern)
+		}
+	}
+	return rep
+}
+
+// Requests returns a sequence of the given requests, for [ServeMux.Replay]
+// and [CompareMuxes].
+func Requests(reqs ...Request) iter.Seq[Request] {
+	return slices.Values(reqs)
+}
@@ -0,0 +1,43 @@
+package shortmux
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("GET /users/{id}", h)
+	mux.Handle("/docs/", h)
+	mux.Handle("POST /orders", h)
+	mux.Handle("/legacy", h)
+
+	rep := mux.Replay(Requests(
+		Request{Path: "/users/1"},
+		Request{Host: "example.com", Path: "/users/2?x=y"},
+		Request{Method: "DELETE", Path: "/users/2"},
+		Request{Path: "/docs"},
+		Request{Path: "/docs/a"},
+		Request{Path: "/missing"},
+		Request{Method: "GET", Path: "/orders"},
+		Request{Method: "BAD METHOD", Path: "/"},
+	))
+	if rep.Requests != 8 || rep.Invalid != 1 {
+		t.Errorf("Requests = %d, Invalid = %d", rep.Requests, rep.Invalid)
+	}
+	if want := map[string]int{"GET /users/{id}": 2, "/docs/": 1}; !maps.Equal(rep.Matched, want) {
+		t.Errorf("Matched = %v, want %v", rep.Matched, want)
+	}
+	if want := map[string]int{"/docs/": 1}; !maps.Equal(rep.Redirects, want) {
+		t.Errorf("Redirects = %v, want %v", rep.Redirects, want)
+	}
+	if want := map[int]int{404: 1, 405: 2}; !maps.Equal(rep.Unmatched, want) {
+		t.Errorf("Unmatched = %v, want %v", rep.Unmatched, want)
+	}
+	if want := []string{"/legacy", "POST /orders"}; !slices.Equal(rep.Unused, want) {
+		t.Errorf("Unused = %q, want %q", rep.Unused, want)
+	}
+}
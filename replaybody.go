@@ -0,0 +1,68 @@
+package shortmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WithReplayableBody reads the request bodies of the route into memory
+// before the options that follow it and the handler run, so that they can
+// all read them: r.GetBody returns a new copy of the body, and
+// [RewindBody] resets r.Body to its start, for example after verifying a
+// signature over it. Requests with bodies larger than maxBytes are
+// answered with 413 Content Too Large, and requests whose body fails to
+// be read with 400 Bad Request.
+func WithReplayableBody(maxBytes int64) RouteOption {
+	if maxBytes < 0 {
+		panic(fmt.Sprintf("shortmux: invalid body limit %d", maxBytes))
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Body == nil || r.Body == http.NoBody {
+					next.ServeHTTP(w, r)
+					return
+				}
+				if r.ContentLength > maxBytes {
+					mux.Error(w, r, http.StatusRequestEntityTooLarge)
+					return
+				}
+				b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+				if err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						mux.Error(w, r, http.StatusRequestEntityTooLarge)
+					} else {
+						mux.Error(w, r, http.StatusBadRequest)
+					}
+					return
+				}
+				r2 := r.Clone(r.Context())
+				r2.ContentLength = int64(len(b))
+				r2.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(b)), nil
+				}
+				r2.Body, _ = r2.GetBody()
+				next.ServeHTTP(w, r2)
+			})
+		})
+	}
+}
+
+// RewindBody resets the body of r to its start, with r.GetBody, as set by
+// [WithReplayableBody]. It reports an error if r.GetBody is nil.
+func RewindBody(r *http.Request) error {
+	if r.GetBody == nil {
+		return errors.New("shortmux: request body cannot be rewound")
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	return nil
+}
@@ -0,0 +1,63 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithReplayableBody(t *testing.T) {
+	var verified string
+	verify := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			verified = string(b)
+			if err := RewindBody(r); err != nil {
+				t.Error(err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("POST /hook", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body, _ := r.GetBody()
+		again, _ := io.ReadAll(body)
+		io.WriteString(w, string(b)+"|"+string(again))
+	}, WithReplayableBody(8), func(c *routeConfig) { c.use(verify) })
+
+	for _, test := range []struct {
+		body     string
+		chunked  bool
+		wantCode int
+		wantBody string
+	}{
+		{"payload", false, 200, "payload|payload"},
+		{"payload", true, 200, "payload|payload"},
+		{"too large!", false, 413, "Request Entity Too Large\n"},
+		{"too large!", true, 413, "Request Entity Too Large\n"},
+	} {
+		verified = ""
+		r := httptest.NewRequest("POST", "/hook", strings.NewReader(test.body))
+		if test.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody {
+			t.Errorf("%q (chunked %t): got %d %q, want %d %q", test.body, test.chunked, w.Code, w.Body, test.wantCode, test.wantBody)
+		}
+		if test.wantCode == 200 && verified != test.body {
+			t.Errorf("%q: middleware read %q", test.body, verified)
+		}
+	}
+}
+
+func TestRewindBodyWithoutGetBody(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("x"))
+	if err := RewindBody(r); err == nil {
+		t.Error("got nil error")
+	}
+}
@@ -0,0 +1,66 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Reserve reserves paths for a subsystem of the application, such as
+// "/.well-known/" or "/healthz", so that registering a pattern for them,
+// which would shadow the subsystem, fails with an error explaining the
+// reservation. Prefixes ending in a slash reserve a subtree; the others
+// reserve a path and its subtree. Prefixes may start with a host, as in
+// "example.com/admin/".
+//
+// The subsystem registers its routes through the returned Reservation.
+// Patterns registered before the reservation are not affected.
+func (mux *ServeMux) Reserve(prefixes ...string) *Reservation {
+	for _, p := range prefixes {
+		if !strings.Contains(p, "/") {
+			panic(fmt.Sprintf("shortmux: invalid reserved prefix %q", p))
+		}
+	}
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.reserved = append(mux.reserved, prefixes...)
+	return &Reservation{mux: mux}
+}
+
+// A Reservation registers routes on paths reserved with [ServeMux.Reserve].
+type Reservation struct {
+	mux *ServeMux
+}
+
+// Handle registers handler for pattern, as [ServeMux.Handle] does, even if
+// the pattern is reserved.
+func (rv *Reservation) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	rv.mux.register(pattern, handler, append(opts[:len(opts):len(opts)], withReservation))
+}
+
+// HandleFunc registers handler for pattern, as [ServeMux.HandleFunc] does,
+// even if the pattern is reserved.
+func (rv *Reservation) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	rv.mux.register(pattern, http.HandlerFunc(handler), append(opts[:len(opts):len(opts)], withReservation))
+}
+
+// withReservation lets a route be registered on reserved paths.
+func withReservation(c *routeConfig) {
+	c.reserved = true
+}
+
+// reservation returns the reserved prefix p is registered under, if any.
+func (mux *ServeMux) reservation(p *pattern) (string, bool) {
+	path := p.str[strings.IndexByte(p.str, '/'):]
+	for _, rp := range mux.reserved {
+		i := strings.IndexByte(rp, '/')
+		if host := rp[:i]; host != "" && p.host != "" && !strings.EqualFold(host, p.host) {
+			continue
+		}
+		prefix := rp[i:]
+		if path == prefix || strings.HasPrefix(path, prefix) && (strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/') {
+			return rp, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,43 @@
+package shortmux
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("/healthz/old", h) // registered before the reservation
+	rv := mux.Reserve("/.well-known/", "/healthz", "admin.example.com/internal/")
+
+	for _, test := range []struct {
+		pattern  string
+		reserved bool
+	}{
+		{"/.well-known/security.txt", true},
+		{"GET /.well-known/{name}", true},
+		{"/.well-known", false},
+		{"/healthz", true},
+		{"/healthz/live", true},
+		{"/healthzz", false},
+		{"admin.example.com/internal/x", true},
+		{"/internal/y", true},
+		{"other.example.com/internal/z", false},
+		{"/", false},
+	} {
+		err := mux.registerErr(test.pattern, h)
+		if got := err != nil && strings.Contains(err.Error(), "reserved"); got != test.reserved {
+			t.Errorf("registering %q: error %v, want reserved %t", test.pattern, err, test.reserved)
+		}
+	}
+
+	rv.HandleFunc("GET /.well-known/security.txt", func(http.ResponseWriter, *http.Request) {})
+	rv.Handle("/healthz", h)
+	for _, rt := range mux.Routes() {
+		if rt.Pattern == "/healthz" && !strings.Contains(rt.Location, "reserve_test.go") {
+			t.Errorf("location of a reserved route = %q", rt.Location)
+		}
+	}
+}
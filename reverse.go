@@ -0,0 +1,107 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// WithName names the route, so that URLs for it can be built with
+// [ServeMux.URL] instead of being spelled out by hand. Names must be unique
+// within a mux.
+func WithName(name string) RouteOption {
+	if name == "" {
+		panic("shortmux: empty route name")
+	}
+	return func(c *routeConfig) {
+		c.name = name
+	}
+}
+
+// URL returns the URL of the route named name with [WithName], with its
+// wildcards set to the values in params, given as name and value pairs:
+//
+//	mux.HandleFunc("GET /b/{bucket}/o/{object...}", getObject, shortmux.WithName("object"))
+//	u, err := mux.URL("object", "bucket", "photos", "object", "2024/cat.jpg")
+//	// u.String() == "/b/photos/o/2024/cat.jpg"
+//
+// Values are escaped, except for the slashes of "..." wildcards. The URL
+// has the host of the pattern, if any, with no scheme.
+//
+// URL returns an error if no route has the name, if a wildcard has no
+// value or a value it wouldn't match, or if params has values for other
+// names.
+func (mux *ServeMux) URL(name string, params ...string) (*url.URL, error) {
+	rt := mux.named(name)
+	if rt == nil {
+		return nil, fmt.Errorf("shortmux: no route named %q", name)
+	}
+	return rt.pat.build(params)
+}
+
+// named returns the route with the given name, or nil.
+func (mux *ServeMux) named(name string) *route {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	for _, rt := range mux.routes {
+		if rt.cfg.name == name {
+			return rt
+		}
+	}
+	return nil
+}
+
+// build returns the URL matching p with the wildcard values in params, as
+// described in [ServeMux.URL].
+func (p *pattern) build(params []string) (*url.URL, error) {
+	if len(params)%2 != 0 {
+		return nil, fmt.Errorf("shortmux: odd number of params for %q", p.str)
+	}
+	values := map[string]string{}
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+	var path, raw strings.Builder
+	add := func(s string) {
+		path.WriteString("/" + s)
+		raw.WriteString("/" + url.PathEscape(s))
+	}
+	for i, seg := range p.segments {
+		switch {
+		case !seg.wild && seg.s == "/" && i == len(p.segments)-1:
+			path.WriteByte('/')
+			raw.WriteByte('/')
+		case !seg.wild:
+			add(seg.s)
+		case seg.s == "":
+			// Trailing slash.
+			path.WriteByte('/')
+			raw.WriteByte('/')
+		default:
+			v, ok := values[seg.s]
+			if !ok {
+				return nil, fmt.Errorf("shortmux: missing value for wildcard %q of %q", seg.s, p.str)
+			}
+			delete(values, seg.s)
+			if seg.multi {
+				// The slashes separate segments, as when matching.
+				parts := strings.Split(v, "/")
+				for j, part := range parts {
+					parts[j] = url.PathEscape(part)
+				}
+				path.WriteString("/" + v)
+				raw.WriteString("/" + strings.Join(parts, "/"))
+				continue
+			}
+			if v == "" || seg.enum != nil && !slices.Contains(seg.enum, v) || slices.Contains(seg.exclude, v) {
+				return nil, fmt.Errorf("shortmux: value %q for wildcard %q doesn't match %q", v, seg.s, p.str)
+			}
+			add(v)
+		}
+	}
+	for name := range values {
+		return nil, fmt.Errorf("shortmux: %q has no wildcard %q", p.str, name)
+	}
+	return &url.URL{Host: p.host, Path: path.String(), RawPath: raw.String()}, nil
+}
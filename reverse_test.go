@@ -0,0 +1,62 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("GET /b/{bucket}/o/{object...}", h, WithName("object"))
+	mux.Handle("/export/{format:(json|csv)}", h, WithName("export"))
+	mux.Handle("/{page:!admin}", h, WithName("page"))
+	mux.Handle("api.example.com/users/{id}/{$}", h, WithName("user"))
+	mux.Handle("/static/", h, WithName("static"))
+
+	for _, test := range []struct {
+		name   string
+		params []string
+		want   string // or "" for an error
+	}{
+		{"object", []string{"bucket", "photos", "object", "2024/cat pic.jpg"}, "/b/photos/o/2024/cat%20pic.jpg"},
+		{"object", []string{"bucket", "a/b", "object", ""}, "/b/a%2Fb/o/"},
+		{"export", []string{"format", "csv"}, "/export/csv"},
+		{"export", []string{"format", "xml"}, ""},
+		{"page", []string{"page", "about"}, "/about"},
+		{"page", []string{"page", "admin"}, ""},
+		{"page", []string{"page", ""}, ""},
+		{"user", []string{"id", "7"}, "//api.example.com/users/7/"},
+		{"static", nil, "/static/"},
+		{"object", []string{"bucket", "photos"}, ""},
+		{"static", []string{"x", "y"}, ""},
+		{"static", []string{"x"}, ""},
+		{"missing", nil, ""},
+	} {
+		u, err := mux.URL(test.name, test.params...)
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("URL(%q, %q) = %v, want error", test.name, test.params, u)
+		case test.want != "" && err != nil:
+			t.Errorf("URL(%q, %q): %v", test.name, test.params, err)
+		case test.want != "" && u.String() != test.want:
+			t.Errorf("URL(%q, %q) = %v, want %s", test.name, test.params, u, test.want)
+		}
+	}
+
+	// The built URL matches the route, with the given values.
+	u, _ := mux.URL("object", "bucket", "a/b", "object", "c d/e")
+	r := httptest.NewRequest("GET", u.String(), nil)
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	if r.Pattern != "GET /b/{bucket}/o/{object...}" || r.PathValue("bucket") != "a/b" || r.PathValue("object") != "c d/e" {
+		t.Errorf("%v matched %q with bucket %q and object %q", u, r.Pattern, r.PathValue("bucket"), r.PathValue("object"))
+	}
+
+	if err := mux.registerErr("/other", h, WithName("page")); err == nil {
+		t.Error("duplicate route name registered")
+	}
+	if got := mux.Routes()[0].Name; got != "user" {
+		t.Errorf("Routes()[0].Name = %q, want user", got)
+	}
+}
@@ -0,0 +1,153 @@
+// Package routefile implements a [shortmux.RouteSource] backed by a JSON
+// file mapping patterns to handler names, for routing tables managed
+// outside of the program, e.g. by configuration management:
+//
+//	{
+//		"GET /users/{id}": "users",
+//		"/static/": "static"
+//	}
+//
+// The file is polled for changes, and each change is applied to the mux as
+// a single transaction:
+//
+//	src := &routefile.Source{
+//		Path: "/etc/gateway/routes.json",
+//		Resolve: func(name string) (http.Handler, error) {
+//			h, ok := handlers[name]
+//			if !ok {
+//				return nil, fmt.Errorf("unknown handler %q", name)
+//			}
+//			return h, nil
+//		},
+//	}
+//	go mux.Sync(ctx, src)
+package routefile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+// DefaultInterval is the polling interval used when Source.Interval is zero.
+const DefaultInterval = 5 * time.Second
+
+// Source is a [shortmux.RouteSource] reading routes from a JSON file.
+type Source struct {
+	// Path is the file to read the routes from.
+	Path string
+
+	// Resolve returns the handler for a handler name used in the file.
+	Resolve func(name string) (http.Handler, error)
+
+	// Options, if any, are applied to every route.
+	Options []shortmux.RouteOption
+
+	// Interval is how often the file is checked for changes.
+	// If zero, DefaultInterval is used.
+	Interval time.Duration
+
+	// OnError, if non-nil, is called when a change to the file can't be
+	// read or applied. The routes are left as they were until the file
+	// changes again.
+	OnError func(error)
+}
+
+// Watch implements [shortmux.RouteSource]. Errors reading or applying the
+// file for the first time are returned; later errors are reported to
+// OnError.
+func (s *Source) Watch(ctx context.Context, apply func([]shortmux.RouteChange) error) error {
+	if s.Resolve == nil {
+		return errors.New("routefile: nil Resolve")
+	}
+	interval := s.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	w := &watcher{src: s, apply: apply}
+	if err := w.poll(); err != nil {
+		return err
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			if err := w.poll(); err != nil && s.OnError != nil {
+				s.OnError(err)
+			}
+		}
+	}
+}
+
+// watcher holds the state of a Watch call.
+type watcher struct {
+	src     *Source
+	apply   func([]shortmux.RouteChange) error
+	last    []byte            // last file contents seen
+	current map[string]string // applied routes: pattern to handler name
+}
+
+// poll reads the file and applies its changes, if any.
+func (w *watcher) poll() error {
+	b, err := os.ReadFile(w.src.Path)
+	if err != nil {
+		return fmt.Errorf("routefile: %w", err)
+	}
+	if w.last != nil && bytes.Equal(b, w.last) {
+		return nil
+	}
+	w.last = b
+
+	var next map[string]string
+	if err := json.Unmarshal(b, &next); err != nil {
+		return fmt.Errorf("routefile: parsing %s: %w", w.src.Path, err)
+	}
+	changes, err := w.diff(next)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := w.apply(changes); err != nil {
+		return fmt.Errorf("routefile: %s: %w", w.src.Path, err)
+	}
+	w.current = next
+	return nil
+}
+
+// diff returns the changes turning the current routes into next.
+// Routes whose handler name changed are removed and added again.
+func (w *watcher) diff(next map[string]string) ([]shortmux.RouteChange, error) {
+	var changes []shortmux.RouteChange
+	for _, pattern := range slices.Sorted(maps.Keys(w.current)) {
+		if name, ok := next[pattern]; !ok || name != w.current[pattern] {
+			changes = append(changes, shortmux.RouteChange{Remove: true, Pattern: pattern})
+		}
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(next)) {
+		name := next[pattern]
+		if old, ok := w.current[pattern]; ok && old == name {
+			continue
+		}
+		h, err := w.src.Resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("routefile: %q: %w", pattern, err)
+		}
+		changes = append(changes, shortmux.RouteChange{Pattern: pattern, Handler: h, Options: w.src.Options})
+	}
+	return changes, nil
+}
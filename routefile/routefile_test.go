@@ -0,0 +1,88 @@
+package routefile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+func TestSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"GET /a": "one", "GET /b": "two"}`)
+
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, name) })
+	}
+	errs := make(chan error, 10)
+	src := &Source{
+		Path: path,
+		Resolve: func(name string) (http.Handler, error) {
+			if name == "bad" {
+				return nil, errors.New("unknown handler")
+			}
+			return named(name), nil
+		},
+		Interval: time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	}
+
+	mux := shortmux.NewServeMux()
+	mux.Handle("/", named("static"))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- mux.Sync(ctx, src) }()
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+	waitFor := func(path, want string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if get(path) == want {
+				return
+			}
+		}
+		t.Fatalf("GET %s: got %q, want %q", path, get(path), want)
+	}
+
+	waitFor("/a", "one")
+	waitFor("/b", "two")
+
+	write(`{"GET /a": "three", "GET /c": "four"}`)
+	waitFor("/c", "four")
+	if got := get("/a"); got != "three" {
+		t.Errorf("GET /a: got %q, want %q", got, "three")
+	}
+	if got := get("/b"); got != "static" {
+		t.Errorf("GET /b: got %q, want %q", got, "static")
+	}
+
+	write(`{"GET /a": "one", "GET /d": "bad"}`)
+	if err := <-errs; err == nil {
+		t.Error("got no error for unknown handler")
+	}
+	if got := get("/a"); got != "three" {
+		t.Errorf("after failed update, GET /a: got %q, want %q", got, "three")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Sync: got %v, want %v", err, context.Canceled)
+	}
+}
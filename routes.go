@@ -0,0 +1,176 @@
+package shortmux
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// route is a registered pattern with its handler and configuration.
+type route struct {
+	pat     *pattern
+	handler http.Handler // as registered, before options are applied
+	wrapped http.Handler // with options applied, as served
+	cfg     *routeConfig
+
+	alias   *route // route registered with WithSlashAlias, if any
+	aliasOf *route // route this is the slash alias of, if any
+}
+
+// A Route describes a pattern registered on a [ServeMux].
+type Route struct {
+	Pattern     string        // the pattern as registered, e.g. "GET example.com/a/{b}"
+	Name        string        // name set with WithName, or empty
+	Method      string        // method part of the pattern, or empty
+	Host        string        // host part of the pattern, or empty
+	Path        string        // path part of the pattern, e.g. "/a/{b}"
+	Handler     http.Handler  // handler as registered, without the route options
+	HandlerName string        // name of the code serving the route; see HandlerName
+	Chain       []string      // names of the handlers in HandlerChain(Handler)
+	Location    string        // source location of the registering call
+	Budget      time.Duration // latency budget set with WithBudget, or zero
+	Class       string        // route class set with WithClass, or empty
+	Mounted     http.Handler  // handler mounted with MountHandler, or nil
+
+	meta map[any]any
+	pat  *pattern
+}
+
+// Value returns the metadata attached to the route under key with
+// [WithMetadata], or nil.
+func (r Route) Value(key any) any {
+	return r.meta[key]
+}
+
+// Routes returns the routes registered on mux, sorted with [CompareRoutes]
+// so that the order doesn't depend on the order of registration.
+func (mux *ServeMux) Routes() []Route {
+	mux.mu.RLock()
+	routes := make([]Route, 0, len(mux.routes))
+	for _, rt := range mux.routes {
+		routes = append(routes, rt.export())
+	}
+	mux.mu.RUnlock()
+	slices.SortFunc(routes, CompareRoutes)
+	return routes
+}
+
+// CompareRoutes returns -1, 0 or +1 depending on whether a sorts before,
+// as, or after b in the order of the introspection outputs of a mux, such
+// as [ServeMux.Routes]. Routes are ordered by:
+//
+//  1. Host, alphabetically, with routes for any host last.
+//  2. Path, segment by segment: literals first, alphabetically, then
+//     constrained wildcards, then single wildcards, then "..." wildcards
+//     and trailing slashes. A path that is a prefix of the other one
+//     comes first, so that "/a" sorts before "/a/b".
+//  3. Method, alphabetically, with routes for any method last.
+//  4. Pattern, for equivalent patterns such as "/{a}" and "/{b}".
+//
+// Roughly, more specific routes come first.
+func CompareRoutes(a, b Route) int {
+	if c := compareEmptyLast(a.Host, b.Host); c != 0 {
+		return c
+	}
+	if c := comparePaths(a.pattern(), b.pattern()); c != 0 {
+		return c
+	}
+	if c := compareEmptyLast(a.Method, b.Method); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Pattern, b.Pattern)
+}
+
+// pattern returns the parsed pattern of r, or nil if it doesn't parse.
+func (r Route) pattern() *pattern {
+	if r.pat != nil {
+		return r.pat
+	}
+	p, _ := parsePattern(r.Pattern)
+	return p
+}
+
+func compareEmptyLast(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// comparePaths compares the paths of p1 and p2 as described in
+// [CompareRoutes]. Patterns that didn't parse sort last.
+func comparePaths(p1, p2 *pattern) int {
+	switch {
+	case p1 == nil && p2 == nil:
+		return 0
+	case p1 == nil:
+		return 1
+	case p2 == nil:
+		return -1
+	}
+	for i, s1 := range p1.segments {
+		if i >= len(p2.segments) {
+			return 1
+		}
+		s2 := p2.segments[i]
+		if c := cmp.Compare(s1.rank(), s2.rank()); c != 0 {
+			return c
+		}
+		if !s1.wild {
+			if c := strings.Compare(s1.s, s2.s); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(p1.segments), len(p2.segments))
+}
+
+// rank orders the kinds of segments from the most specific to the least.
+func (s segment) rank() int {
+	switch {
+	case !s.wild:
+		return 0
+	case s.enum != nil:
+		return 1
+	case !s.multi:
+		return 2
+	}
+	return 3
+}
+
+func (rt *route) export() Route {
+	p := rt.pat
+	return Route{
+		Pattern:     p.str,
+		Name:        rt.cfg.name,
+		Method:      p.method,
+		Host:        p.host,
+		Path:        p.str[strings.IndexByte(p.str, '/'):],
+		Handler:     rt.handler,
+		HandlerName: HandlerName(rt.handler),
+		Chain:       chainNames(rt.handler),
+		Location:    p.loc,
+		Budget:      rt.cfg.budget,
+		Class:       rt.cfg.class,
+		Mounted:     rt.cfg.mounted,
+		meta:        rt.cfg.meta,
+		pat:         p,
+	}
+}
+
+// chainNames returns the names of the handlers in the chain of h.
+func chainNames(h http.Handler) []string {
+	chain := HandlerChain(h)
+	names := make([]string, len(chain))
+	for i, h := range chain {
+		names[i] = name(h)
+	}
+	return names
+}
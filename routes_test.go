@@ -0,0 +1,194 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	type key struct{}
+	h := &handler{1}
+	mux := NewServeMux()
+	mux.Handle("GET example.com/a/{b}", h, WithMetadata(key{}, "meta"), WithAuth(func(*http.Request) bool { return true }))
+	mux.Handle("/", h)
+
+	routes := mux.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+	r := routes[0]
+	if r.Pattern != "GET example.com/a/{b}" || r.Method != "GET" || r.Host != "example.com" || r.Path != "/a/{b}" {
+		t.Errorf("got %+v", r)
+	}
+	if r.Handler != h {
+		t.Errorf("got handler %#v, want the registered one", r.Handler)
+	}
+	if !strings.Contains(r.Location, "routes_test.go") {
+		t.Errorf("got location %q", r.Location)
+	}
+	if got := r.Value(key{}); got != "meta" {
+		t.Errorf("got metadata %v, want %q", got, "meta")
+	}
+	if got := routes[1].Value(key{}); got != nil {
+		t.Errorf("got metadata %v, want nil", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	var events []string
+	mux := NewServeMux()
+	mux.OnRegister = func(r Route) { events = append(events, "+"+r.Pattern) }
+	mux.OnRemove = func(r Route) { events = append(events, "-"+r.Pattern) }
+	patterns := []string{"/", "GET /a/{x}", "example.com/a/b/", "/a/{x}/c", "POST /a/b/{$}"}
+	for i, p := range patterns {
+		mux.Handle(p, &handler{i})
+	}
+	for _, p := range patterns[1:] {
+		if err := mux.Remove(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mux.Remove("/a/{x}/c"); err == nil {
+		t.Error("removing twice: got nil error")
+	}
+
+	// Only the root pattern is left.
+	for _, path := range []string{"/a/1", "/a/1/c", "/a/b/"} {
+		r := httptest.NewRequest("GET", "http://example.com"+path, nil)
+		if _, p := mux.Handler(r); p != "/" {
+			t.Errorf("%s: got pattern %q, want %q", path, p, "/")
+		}
+	}
+	if mux.tree.findChild("").findChild("").children.len() != 0 || mux.tree.findChild("example.com") != nil {
+		t.Error("tree not pruned")
+	}
+	if got := len(mux.Routes()); got != 1 {
+		t.Errorf("got %d routes, want 1", got)
+	}
+	if len(mux.index.multis) != 1 || len(mux.index.segments) != 0 {
+		t.Errorf("index not cleaned: %d multis, %d segments", len(mux.index.multis), len(mux.index.segments))
+	}
+
+	// Patterns can be registered again.
+	mux.Handle("GET /a/{x}", &handler{9})
+	want := "+/ +GET /a/{x} +example.com/a/b/ +/a/{x}/c +POST /a/b/{$} -GET /a/{x} -example.com/a/b/ -/a/{x}/c -POST /a/b/{$} +GET /a/{x}"
+	if got := strings.Join(events, " "); got != want {
+		t.Errorf("got events\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompareRoutes(t *testing.T) {
+	want := []string{
+		"api.example.com/users/{id}",
+		"example.com/",
+		"/users",
+		"/users/{$}",
+		"GET /users/new",
+		"/users/{f:(json|xml)}",
+		"DELETE /users/{id}",
+		"GET /users/{id}",
+		"/users/{id}",
+		"GET /users/{name}/posts",
+		"/users/{id}/posts",
+		"/users/",
+		"/{x}/y",
+		"/",
+	}
+	mux := NewServeMux()
+	for _, i := range []int{13, 2, 7, 0, 11, 5, 9, 1, 12, 3, 8, 6, 4, 10} {
+		mux.Handle(want[i], http.NotFoundHandler())
+	}
+	var got []string
+	for _, r := range mux.Routes() {
+		got = append(got, r.Pattern)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// Routes built by hand are parsed on demand.
+	if c := CompareRoutes(Route{Pattern: "/a"}, Route{Pattern: "/{x}"}); c != -1 {
+		t.Errorf("CompareRoutes(/a, /{x}) = %d, want -1", c)
+	}
+}
+
+type namedHandler struct{}
+
+func (namedHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func (namedHandler) method(http.ResponseWriter, *http.Request) {}
+
+// unwrapper is a middleware handler that exposes the handler it wraps.
+type unwrapper struct{ next http.Handler }
+
+func (u unwrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) { u.next.ServeHTTP(w, r) }
+
+func (u unwrapper) Unwrap() http.Handler { return u.next }
+
+func TestHandlerName(t *testing.T) {
+	for _, test := range []struct {
+		h    http.Handler
+		want string
+	}{
+		{http.HandlerFunc(http.NotFound), "net/http.NotFound"},
+		{http.HandlerFunc(namedHandler{}.method), "github.com/henvic/shortmux.namedHandler.method"},
+		{namedHandler{}, "github.com/henvic/shortmux.namedHandler"},
+		{&namedHandler{}, "*github.com/henvic/shortmux.namedHandler"},
+		{unwrapper{unwrapper{http.HandlerFunc(http.NotFound)}}, "net/http.NotFound"},
+		{http.FileServer(http.Dir(".")), "*net/http.fileHandler"},
+	} {
+		if got := HandlerName(test.h); got != test.want {
+			t.Errorf("HandlerName(%T): got %q, want %q", test.h, got, test.want)
+		}
+	}
+
+	mux := NewServeMux()
+	mux.HandleFunc("/", http.NotFound)
+	if got := mux.Routes()[0].HandlerName; got != "net/http.NotFound" {
+		t.Errorf("Route.HandlerName: got %q", got)
+	}
+}
+
+func TestHandlerChain(t *testing.T) {
+	inner := http.HandlerFunc(http.NotFound)
+	mw := unwrapper{inner}
+	if chain := HandlerChain(mw); len(chain) != 2 {
+		t.Errorf("HandlerChain: got %v", chain)
+	}
+
+	mux := NewServeMux()
+	mux.HandleLocalized("/about", unwrapper{inner}, "en")
+	for _, r := range mux.Routes() {
+		want := []string{"github.com/henvic/shortmux.unwrapper", "net/http.NotFound"}
+		if r.Pattern == "/en/about" {
+			want = append([]string{"*github.com/henvic/shortmux.localizedHandler"}, want...)
+		}
+		if !slices.Equal(r.Chain, want) || r.HandlerName != "net/http.NotFound" {
+			t.Errorf("%s: got chain %q, name %q, want chain %q", r.Pattern, r.Chain, r.HandlerName, want)
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	mux := NewServeMux()
+	if v := mux.Version(); v != 0 {
+		t.Errorf("new mux: got version %d, want 0", v)
+	}
+	mux.HandleFunc("/a", http.NotFound)
+	mux.HandleFunc("/b", http.NotFound)
+	mux.Remove("/a")
+	mux.Apply([]RouteChange{{Pattern: "/c", Handler: http.NotFoundHandler()}, {Remove: true, Pattern: "/b"}})
+	if v := mux.Version(); v != 4 {
+		t.Errorf("got version %d, want 4", v)
+	}
+	// Failed changes don't count.
+	mux.Remove("/nope")
+	mux.registerErr("/c", http.NotFoundHandler())
+	mux.Apply([]RouteChange{{Remove: true, Pattern: "/c"}, {Remove: true, Pattern: "/nope"}})
+	if v := mux.Version(); v != 4 {
+		t.Errorf("after failed changes: got version %d, want 4", v)
+	}
+}
@@ -4,6 +4,8 @@
 
 package shortmux
 
+import "slices"
+
 // A routingIndex optimizes conflict detection by indexing patterns.
 //
 // The basic idea is to rule out patterns that cannot conflict with a given
@@ -27,7 +29,7 @@ type routingIndexKey struct {
 }
 
 func (idx *routingIndex) addPattern(pat *pattern) {
-	if pat.lastSegment().multi {
+	if pat.lastSegment().multi || pat.suffix != nil {
 		idx.multis = append(idx.multis, pat)
 	} else {
 		if idx.segments == nil {
@@ -43,6 +45,26 @@ func (idx *routingIndex) addPattern(pat *pattern) {
 	}
 }
 
+// removePattern removes pat, which must have been added, from the index.
+func (idx *routingIndex) removePattern(pat *pattern) {
+	if pat.lastSegment().multi || pat.suffix != nil {
+		idx.multis = slices.DeleteFunc(idx.multis, func(p *pattern) bool { return p == pat })
+		return
+	}
+	for pos, seg := range pat.segments {
+		key := routingIndexKey{pos: pos, s: ""}
+		if !seg.wild {
+			key.s = seg.s
+		}
+		pats := slices.DeleteFunc(idx.segments[key], func(p *pattern) bool { return p == pat })
+		if len(pats) == 0 {
+			delete(idx.segments, key)
+		} else {
+			idx.segments[key] = pats
+		}
+	}
+}
+
 // hasPattern returns true if the pattern is already registered
 func (idx *routingIndex) hasPattern(p *pattern) bool {
 	// Check multis first
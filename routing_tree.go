@@ -20,16 +20,18 @@ package shortmux
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 )
 
 // A routingNode is a node in the decision tree.
 // The same struct is used for leaf and interior nodes.
 type routingNode struct {
-	// A leaf node holds a single pattern and the Handler it was registered
-	// with.
+	// A leaf node holds a single pattern, the Handler it was registered
+	// with, and the route it belongs to.
 	pattern *pattern
 	handler http.Handler
+	route   *route
 
 	// An interior node maps parts of the incoming request to child nodes.
 	// special children keys:
@@ -38,50 +40,183 @@ type routingNode struct {
 	children   mapping[string, *routingNode]
 	multiChild *routingNode // child with multi wildcard
 	emptyChild *routingNode // optimization: child with key ""
+
+	// suffixed holds the leaves of the patterns with a multi wildcard
+	// followed by a suffix at this position, in order of precedence.
+	suffixed []*routingNode
 }
 
-// addPattern adds a pattern and its associated Handler to the tree
+// addPattern adds a pattern and its associated Handler and route to the tree
 // at root.
-func (root *routingNode) addPattern(p *pattern, h http.Handler) {
+func (root *routingNode) addPattern(p *pattern, h http.Handler, rt *route) {
 	// First level of tree is host.
 	n := root.addChild(p.host)
 	// Second level of tree is method.
 	n = n.addChild(p.method)
 	// Remaining levels are path.
-	n.addSegments(p.segments, p, h)
+	n.addSegments(p.segments, p, h, rt)
 }
 
 // addSegments adds the given segments to the tree rooted at n.
 // If there are no segments, then n is a leaf node that holds
-// the given pattern and handler.
-func (n *routingNode) addSegments(segs []segment, p *pattern, h http.Handler) {
+// the given pattern, handler and route.
+// A constrained wildcard is added as each of its literals, so that the
+// pattern may have several leaves.
+func (n *routingNode) addSegments(segs []segment, p *pattern, h http.Handler, rt *route) {
 	if len(segs) == 0 {
-		n.set(p, h)
+		n.set(p, h, rt)
 		return
 	}
 	seg := segs[0]
-	if seg.multi {
-		if len(segs) != 1 {
-			panic("multi wildcard not last")
+	if seg.multi && len(segs) > 1 {
+		c := &routingNode{}
+		c.set(p, h, rt)
+		i := 0
+		for i < len(n.suffixed) && !suffixPrecedes(segs[1:], n.suffixed[i].pattern.suffix) {
+			i++
 		}
+		n.suffixed = slices.Insert(n.suffixed, i, c)
+	} else if seg.multi {
 		c := &routingNode{}
 		n.multiChild = c
-		c.set(p, h)
+		c.set(p, h, rt)
+	} else if seg.enum != nil {
+		for _, lit := range seg.enum {
+			n.addChild(lit).addSegments(segs[1:], p, h, rt)
+		}
 	} else if seg.wild {
-		n.addChild("").addSegments(segs[1:], p, h)
+		n.addChild("").addSegments(segs[1:], p, h, rt)
 	} else {
-		n.addChild(seg.s).addSegments(segs[1:], p, h)
+		n.addChild(seg.s).addSegments(segs[1:], p, h, rt)
+	}
+}
+
+// occupant returns the pattern of a leaf under root that p would replace,
+// or nil if there is none.
+// Such patterns are equivalent to p, or to a part of it for constrained
+// wildcards, so they can't be registered together.
+func (root *routingNode) occupant(p *pattern) *pattern {
+	hn := root.findChild(p.host)
+	if hn == nil {
+		return nil
+	}
+	return hn.findChild(p.method).occupantSegments(p.segments)
+}
+
+func (n *routingNode) occupantSegments(segs []segment) *pattern {
+	if n == nil {
+		return nil
+	}
+	if len(segs) == 0 {
+		return n.pattern
+	}
+	seg := segs[0]
+	switch {
+	case seg.multi && len(segs) > 1:
+		for _, c := range n.suffixed {
+			if sameSegments(c.pattern.suffix, segs[1:]) {
+				return c.pattern
+			}
+		}
+		return nil
+	case seg.multi:
+		if n.multiChild != nil {
+			return n.multiChild.pattern
+		}
+		return nil
+	case seg.enum != nil:
+		for _, lit := range seg.enum {
+			if q := n.findChild(lit).occupantSegments(segs[1:]); q != nil {
+				return q
+			}
+		}
+		return nil
+	case seg.wild:
+		return n.emptyChild.occupantSegments(segs[1:])
+	default:
+		return n.findChild(seg.s).occupantSegments(segs[1:])
+	}
+}
+
+// removePattern removes the leaf holding p from the tree at root, along with
+// the interior nodes that become empty.
+func (root *routingNode) removePattern(p *pattern) {
+	hn := root.findChild(p.host)
+	if hn == nil {
+		return
+	}
+	if mn := hn.findChild(p.method); mn != nil {
+		mn.removeSegments(p.segments)
+		if mn.isEmpty() {
+			hn.removeChild(p.method)
+		}
+	}
+	if hn.isEmpty() {
+		root.removeChild(p.host)
+	}
+}
+
+// removeSegments removes the leaf for the given segments from the tree
+// rooted at n, pruning empty nodes below n.
+func (n *routingNode) removeSegments(segs []segment) {
+	if len(segs) == 0 {
+		n.pattern, n.handler, n.route = nil, nil, nil
+		return
+	}
+	seg := segs[0]
+	if seg.multi && len(segs) > 1 {
+		n.suffixed = slices.DeleteFunc(n.suffixed, func(c *routingNode) bool {
+			return sameSegments(c.pattern.suffix, segs[1:])
+		})
+		return
+	}
+	if seg.multi {
+		n.multiChild = nil
+		return
+	}
+	if seg.enum != nil {
+		for _, lit := range seg.enum {
+			n.removeSegments(append([]segment{{s: lit}}, segs[1:]...))
+		}
+		return
+	}
+	key := seg.s
+	if seg.wild {
+		key = ""
+	}
+	c := n.findChild(key)
+	if c == nil {
+		return
+	}
+	c.removeSegments(segs[1:])
+	if c.isEmpty() {
+		n.removeChild(key)
 	}
 }
 
-// set sets the pattern and handler for n, which
+// isEmpty reports whether n holds no pattern and has no children.
+func (n *routingNode) isEmpty() bool {
+	return n.pattern == nil && n.children.len() == 0 && n.multiChild == nil && n.emptyChild == nil && len(n.suffixed) == 0
+}
+
+// removeChild removes the child of n with the given key.
+func (n *routingNode) removeChild(key string) {
+	if key == "" {
+		n.emptyChild = nil
+		return
+	}
+	n.children.remove(key)
+}
+
+// set sets the pattern, handler and route for n, which
 // must be a leaf node.
-func (n *routingNode) set(p *pattern, h http.Handler) {
+func (n *routingNode) set(p *pattern, h http.Handler, rt *route) {
 	if n.pattern != nil || n.handler != nil {
 		panic("non-nil leaf fields")
 	}
 	n.pattern = p
 	n.handler = h
+	n.route = rt
 }
 
 // addChild adds a child node with the given key to n
@@ -115,45 +250,93 @@ func (n *routingNode) findChild(key string) *routingNode {
 // of values for pattern wildcards in the order that the wildcards appear.
 // For example, if the request path is "/a/b/c" and the pattern is "/{x}/b/{y}",
 // then the second return value will be []string{"a", "c"}.
-func (root *routingNode) match(host, method, path string) (*routingNode, []string) {
+// If strictHEAD is true, GET patterns only match HEAD requests if their
+// route explicitly allows it.
+// If r is non-nil, leaves whose route has matchers only match if the
+// matchers accept r, and the last return value is the state of the
+// matchers of the returned leaf, if any, and the matching is limited by
+// the scope sc, if non-nil.
+func (root *routingNode) match(host, method, path string, strictHEAD bool, r *http.Request, sc *matchScope) (*routingNode, []string, *MatchState) {
+	mr := newMatchRequest(r, path, sc)
+	l, m := root.matchHost(host, method, path, strictHEAD, mr)
+	if l != nil && l.pattern.constrained {
+		// Constrained wildcards are matched as literals, which record no
+		// values.
+		m = l.pattern.captures(path)
+	}
+	return l, m, mr.stateOf(l)
+}
+
+func (root *routingNode) matchHost(host, method, path string, strictHEAD bool, mr *matchRequest) (*routingNode, []string) {
 	if host != "" {
 		// There is a host. If there is a pattern that specifies that host and it
 		// matches, we are done. If the pattern doesn't match, fall through to
 		// try patterns with no host.
-		if l, m := root.findChild(host).matchMethodAndPath(method, path); l != nil {
+		if l, m := root.findChild(host).matchMethodAndPath(method, path, strictHEAD, mr); l != nil {
 			return l, m
 		}
+		// A host with a port, as given with ServeMux.MatchPorts, also
+		// matches patterns with the host and no port.
+		if h := portlessHost(host, method); h != host {
+			if l, m := root.findChild(h).matchMethodAndPath(method, path, strictHEAD, mr); l != nil {
+				return l, m
+			}
+		}
 	}
-	return root.emptyChild.matchMethodAndPath(method, path)
+	return root.emptyChild.matchMethodAndPath(method, path, strictHEAD, mr)
 }
 
 // matchMethodAndPath matches the method and path.
 // Its return values are the same as [routingNode.match].
 // The receiver should be a child of the root.
-func (n *routingNode) matchMethodAndPath(method, path string) (*routingNode, []string) {
+func (n *routingNode) matchMethodAndPath(method, path string, strictHEAD bool, mr *matchRequest) (*routingNode, []string) {
 	if n == nil {
 		return nil, nil
 	}
-	if l, m := n.findChild(method).matchPath(path, nil); l != nil {
+	if l, m := n.findChild(method).matchPath(path, nil, mr); l != nil {
 		// Exact match of method name.
 		return l, m
 	}
 	if method == "HEAD" {
-		// GET matches HEAD too.
-		if l, m := n.findChild("GET").matchPath(path, nil); l != nil {
+		// GET matches HEAD too, unless configured otherwise. GET patterns
+		// that don't are passed over for less specific ones.
+		if mr == nil {
+			mr = &matchRequest{path: path}
+		}
+		mr.head, mr.strictHEAD = true, strictHEAD
+		l, m := n.findChild("GET").matchPath(path, nil, mr)
+		mr.head = false
+		if l != nil {
 			return l, m
 		}
 	}
 	// No exact match; try patterns with no method.
-	return n.emptyChild.matchPath(path, nil)
+	return n.emptyChild.matchPath(path, nil, mr)
+}
+
+// implicitHEAD reports whether the GET pattern of the leaf n matches HEAD
+// requests.
+func (n *routingNode) implicitHEAD(strictHEAD bool) bool {
+	if n.route == nil {
+		return !strictHEAD
+	}
+	switch n.route.cfg.head {
+	case headAllow:
+		return true
+	case headDeny:
+		return false
+	}
+	return !strictHEAD
 }
 
 // matchPath matches a path.
 // Its return values are the same as [routingNode.match].
 // matchPath calls itself recursively. The matches argument holds the wildcard matches
 // found so far.
-func (n *routingNode) matchPath(path string, matches []string) (*routingNode, []string) {
-	if n == nil {
+// A leaf whose route matchers reject the request doesn't match, so that
+// matching falls through to the next candidate.
+func (n *routingNode) matchPath(path string, matches []string, mr *matchRequest) (*routingNode, []string) {
+	if n == nil || mr.spend() {
 		return nil, nil
 	}
 	// If path is empty, then we are done.
@@ -161,7 +344,7 @@ func (n *routingNode) matchPath(path string, matches []string) (*routingNode, []
 	// If n is an interior node (which means it has a nil pattern),
 	// then we failed to match.
 	if path == "" {
-		if n.pattern == nil {
+		if n.pattern == nil || n.pattern.excluding && n.pattern.rejects(matches) || !mr.accepts(n, matches) {
 			return nil, nil
 		}
 		return n, matches
@@ -172,7 +355,7 @@ func (n *routingNode) matchPath(path string, matches []string) (*routingNode, []
 	// We know by construction that such patterns are more specific than those
 	// with a wildcard at this position (they are either more specific, equivalent,
 	// or overlap, and we ruled out the first two when the patterns were registered).
-	if n, m := n.findChild(seg).matchPath(rest, matches); n != nil {
+	if n, m := n.findChild(seg).matchPath(rest, matches, mr); n != nil {
 		return n, m
 	}
 	// If matching a literal fails, try again with patterns that have a single
@@ -182,23 +365,57 @@ func (n *routingNode) matchPath(path string, matches []string) (*routingNode, []
 	// We skip this step if the segment is a trailing slash, because single wildcards
 	// don't match trailing slashes.
 	if seg != "/" {
-		if n, m := n.emptyChild.matchPath(rest, append(matches, seg)); n != nil {
+		if n, m := n.emptyChild.matchPath(rest, append(matches, seg), mr); n != nil {
 			return n, m
 		}
 	}
+	// Then try patterns with a multi wildcard in this position followed by
+	// a suffix, which are more specific than the one without.
+	for _, c := range n.suffixed {
+		if mr.stopped() {
+			break
+		}
+		if m, ok := c.matchSuffix(path, matches, mr); ok {
+			return c, m
+		}
+	}
 	// Lastly, match the pattern (there can be at most one) that has a multi
 	// wildcard in this position to the rest of the path.
-	if c := n.multiChild; c != nil {
+	if c := n.multiChild; c != nil && !mr.stopped() {
+		if c.pattern.excluding && c.pattern.rejects(matches) {
+			return nil, nil
+		}
 		// Don't record a match for a nameless wildcard (which arises from a
 		// trailing slash in the pattern).
 		if c.pattern.lastSegment().s != "" {
 			matches = append(matches, pathUnescape(path[1:])) // remove initial slash
 		}
+		if !mr.accepts(c, matches) {
+			return nil, nil
+		}
 		return c, matches
 	}
 	return nil, nil
 }
 
+// matchSuffix matches path against the multi wildcard of the pattern of
+// the leaf n followed by its suffix, and returns matches with the values
+// of the wildcards, and whether it matched.
+func (n *routingNode) matchSuffix(path string, matches []string, mr *matchRequest) ([]string, bool) {
+	if mr.spend() {
+		return nil, false
+	}
+	vals, _, ok := n.pattern.splitSuffix(path, false)
+	if !ok {
+		return nil, false
+	}
+	m := append(matches, vals...)
+	if n.pattern.excluding && n.pattern.rejects(m) || !mr.accepts(n, m) {
+		return nil, false
+	}
+	return m, true
+}
+
 // firstSegment splits path into its first segment, and the rest.
 // The path must begin with "/".
 // If path consists of only a slash, firstSegment returns ("/", "").
@@ -217,23 +434,32 @@ func firstSegment(path string) (seg, rest string) {
 
 // matchingMethods adds to methodSet all the methods that would result in a
 // match if passed to routingNode.match with the given host and path.
-func (root *routingNode) matchingMethods(host, path string, methodSet map[string]bool) {
+// If r is non-nil, route matchers are evaluated as by match.
+func (root *routingNode) matchingMethods(host, path string, methodSet map[string]bool, strictHEAD bool, r *http.Request, sc *matchScope) {
+	mr := newMatchRequest(r, path, sc)
 	if host != "" {
-		root.findChild(host).matchingMethodsPath(path, methodSet)
-	}
-	root.emptyChild.matchingMethodsPath(path, methodSet)
-	if methodSet["GET"] {
-		methodSet["HEAD"] = true
+		root.findChild(host).matchingMethodsPath(path, methodSet, strictHEAD, mr)
+		method := ""
+		if r != nil {
+			method = r.Method
+		}
+		if h := portlessHost(host, method); h != host {
+			root.findChild(h).matchingMethodsPath(path, methodSet, strictHEAD, mr)
+		}
 	}
+	root.emptyChild.matchingMethodsPath(path, methodSet, strictHEAD, mr)
 }
 
-func (n *routingNode) matchingMethodsPath(path string, set map[string]bool) {
+func (n *routingNode) matchingMethodsPath(path string, set map[string]bool, strictHEAD bool, mr *matchRequest) {
 	if n == nil {
 		return
 	}
 	n.children.eachPair(func(method string, c *routingNode) bool {
-		if p, _ := c.matchPath(path, nil); p != nil {
+		if l, _ := c.matchPath(path, nil, mr); l != nil {
 			set[method] = true
+			if method == "GET" && l.implicitHEAD(strictHEAD) {
+				set["HEAD"] = true
+			}
 		}
 		return true
 	})
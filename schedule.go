@@ -0,0 +1,30 @@
+package shortmux
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithSchedule limits the route to requests received from start, inclusive,
+// to end, exclusive, such as for a promotion. A zero start or end leaves the
+// window open on that side. Outside the window, requests fall through to
+// the next matching pattern, or get 404 Not Found, as described in
+// [Matcher].
+//
+// For recurring windows, use [WithMatcher] with [MatchTimeOfDay].
+func WithSchedule(start, end time.Time) RouteOption {
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		panic("shortmux: schedule must start before it ends")
+	}
+	return WithMatcher(MatchWindow(start, end))
+}
+
+// MatchWindow returns a matcher accepting requests received from start,
+// inclusive, to end, exclusive. A zero start or end leaves the window open
+// on that side.
+func MatchWindow(start, end time.Time) Matcher {
+	return MatcherFunc(func(r *http.Request, _ *MatchState) bool {
+		now := nowFor(r.Context())
+		return (start.IsZero() || !now.Before(start)) && (end.IsZero() || now.Before(end))
+	})
+}
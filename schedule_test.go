@@ -0,0 +1,42 @@
+package shortmux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithSchedule(t *testing.T) {
+	now := time.Now()
+	mux := NewServeMux()
+	text := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, s) }
+	}
+	mux.Handle("/promo/current", text("current"), WithSchedule(now.Add(-time.Hour), now.Add(time.Hour)))
+	mux.Handle("/promo/past", text("past"), WithSchedule(time.Time{}, now.Add(-time.Hour)))
+	mux.Handle("/promo/future", text("future"), WithSchedule(now.Add(time.Hour), time.Time{}))
+	mux.Handle("/promo/{name}", text("fallback"))
+	mux.Handle("/open", text("open"), WithSchedule(time.Time{}, time.Time{}))
+
+	for path, want := range map[string]string{
+		"/promo/current": "current",
+		"/promo/past":    "fallback",
+		"/promo/future":  "fallback",
+		"/open":          "open",
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got := w.Body.String(); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("WithSchedule with end before start did not panic")
+		}
+	}()
+	WithSchedule(now, now.Add(-time.Second))
+}
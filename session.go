@@ -0,0 +1,208 @@
+package shortmux
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// A SessionManager loads and saves the sessions of requests for the mux,
+// set as [ServeMux.Sessions], typically from and to a cookie or a store
+// keyed by a cookie.
+type SessionManager interface {
+	// Load returns the session of r, or a new session with IsNew set if r
+	// has none or an invalid one. An error fails the request with 500
+	// Internal Server Error.
+	Load(r *http.Request) (*Session, error)
+
+	// Save saves s, which the handler modified, for example by setting a
+	// cookie on w. It is called right before the response headers are
+	// written, or after the handler returns if it wrote nothing, and again
+	// if s is modified after the headers were written, in which case
+	// changes to the headers are lost.
+	Save(w http.ResponseWriter, r *http.Request, s *Session) error
+}
+
+// A Session holds the values associated with a client across requests.
+// Its methods may be called concurrently.
+type Session struct {
+	ID    string // as set by the SessionManager
+	IsNew bool   // whether the client had no session
+
+	mu       sync.Mutex
+	values   map[string]any
+	modified bool
+}
+
+// NewSession returns a session with the given ID and values, for use by
+// implementations of [SessionManager].
+func NewSession(id string, isNew bool, values map[string]any) *Session {
+	if values == nil {
+		values = map[string]any{}
+	}
+	return &Session{ID: id, IsNew: isNew, values: values}
+}
+
+// Get returns the value of key, or nil.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set sets the value of key.
+func (s *Session) Set(key string, val any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = val
+	s.modified = true
+}
+
+// Delete deletes the value of key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Values returns a copy of the values of the session.
+func (s *Session) Values() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]any, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+// Modified reports whether the session was modified since it was loaded or
+// last saved.
+func (s *Session) Modified() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.modified
+}
+
+type sessionKey struct{}
+
+// SessionOf returns the session of r, or nil if the mux has no
+// SessionManager or the route was registered with [WithoutSession].
+func SessionOf(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey{}).(*Session)
+	return s
+}
+
+// sessionMode controls the handling of sessions by a route.
+type sessionMode int8
+
+const (
+	sessionDefault sessionMode = iota
+	sessionOff
+	sessionRequired
+)
+
+// WithoutSession stops the mux SessionManager from loading and saving the
+// sessions of the requests of the route, such as for static assets or
+// health checks.
+func WithoutSession() RouteOption {
+	return func(c *routeConfig) {
+		c.session = sessionOff
+	}
+}
+
+// WithSessionRequired restricts the route to requests with an existing
+// session; requests whose session IsNew are answered with 401
+// Unauthorized. It has no effect if the mux has no SessionManager.
+func WithSessionRequired() RouteOption {
+	return func(c *routeConfig) {
+		c.session = sessionRequired
+	}
+}
+
+// serveSession serves r with h, with the session of r loaded and saved by
+// the mux SessionManager.
+func (mux *ServeMux) serveSession(w http.ResponseWriter, r *http.Request, h http.Handler, mode sessionMode) {
+	s, err := mux.Sessions.Load(r)
+	if err != nil {
+		slog.Error("shortmux: loading session", "pattern", r.Pattern, "error", err)
+		mux.Error(w, r, http.StatusInternalServerError)
+		return
+	}
+	if mode == sessionRequired && s.IsNew {
+		mux.Error(w, r, http.StatusUnauthorized)
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), sessionKey{}, s))
+	sw := &sessionWriter{ResponseWriter: w, mux: mux, r: r, s: s}
+	h.ServeHTTP(sw, r)
+	if !sw.saved || s.Modified() {
+		sw.save()
+	}
+}
+
+// sessionWriter is a [http.ResponseWriter] saving the session right before
+// the response headers are written.
+type sessionWriter struct {
+	http.ResponseWriter
+	mux   *ServeMux
+	r     *http.Request
+	s     *Session
+	saved bool // the headers were written
+}
+
+// save saves the session if it was modified.
+func (w *sessionWriter) save() {
+	w.saved = true
+	w.s.mu.Lock()
+	modified := w.s.modified
+	w.s.modified = false
+	w.s.mu.Unlock()
+	if !modified {
+		return
+	}
+	if err := w.mux.Sessions.Save(w.ResponseWriter, w.r, w.s); err != nil {
+		slog.Error("shortmux: saving session", "pattern", w.r.Pattern, "error", err)
+	}
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	if !w.saved && (code < 100 || code > 199 || code == http.StatusSwitchingProtocols) {
+		w.save()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.saved {
+		w.save()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *sessionWriter) FlushError() error {
+	if !w.saved {
+		w.save()
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.saved = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
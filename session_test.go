@@ -0,0 +1,105 @@
+package shortmux
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// memorySessions is a SessionManager keeping sessions in memory, keyed by
+// the "sid" cookie.
+type memorySessions struct {
+	mu       sync.Mutex
+	sessions map[string]map[string]any
+	saves    int
+}
+
+func (m *memorySessions) Load(r *http.Request) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, err := r.Cookie("sid"); err == nil {
+		if values, ok := m.sessions[c.Value]; ok {
+			return NewSession(c.Value, false, values), nil
+		}
+	}
+	return NewSession(strconv.Itoa(len(m.sessions)+1), true, nil), nil
+}
+
+func (m *memorySessions) Save(w http.ResponseWriter, r *http.Request, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves++
+	m.sessions[s.ID] = s.Values()
+	http.SetCookie(w, &http.Cookie{Name: "sid", Value: s.ID})
+	return nil
+}
+
+func TestSessions(t *testing.T) {
+	sm := &memorySessions{sessions: map[string]map[string]any{}}
+	mux := NewServeMux()
+	mux.Sessions = sm
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		SessionOf(r).Set("user", r.FormValue("user"))
+		io.WriteString(w, "welcome")
+	})
+	mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, SessionOf(r).Get("user"))
+	}, WithSessionRequired())
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, SessionOf(r) == nil)
+	}, WithoutSession())
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/me", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /me without a session: status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/login?user=gopher", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || sm.saves != 1 {
+		t.Fatalf("POST /login: cookies %v, %d saves", cookies, sm.saves)
+	}
+
+	r := httptest.NewRequest("GET", "/me", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != 200 || w.Body.String() != "gopher" {
+		t.Errorf("GET /me: status %d, body %q", w.Code, w.Body)
+	}
+	if sm.saves != 1 {
+		t.Errorf("unmodified session saved")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Body.String() != "true" {
+		t.Errorf("session loaded for a route without sessions")
+	}
+}
+
+func TestSessionSavedBeforeHeaders(t *testing.T) {
+	sm := &memorySessions{sessions: map[string]map[string]any{}}
+	mux := NewServeMux()
+	mux.Sessions = sm
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		SessionOf(r).Set("a", 1)
+		w.WriteHeader(http.StatusAccepted)
+		// Modified after the headers were written: saved again.
+		SessionOf(r).Set("b", 2)
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if len(w.Result().Cookies()) != 1 {
+		t.Errorf("session cookie not set before the headers were written")
+	}
+	if sm.saves != 2 || len(sm.sessions["1"]) != 2 {
+		t.Errorf("%d saves, session %v", sm.saves, sm.sessions["1"])
+	}
+}
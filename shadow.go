@@ -0,0 +1,67 @@
+package shortmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A ShadowReport describes a registered pattern that can never match a
+// request, as reported by [ServeMux.FindShadowed].
+type ShadowReport struct {
+	Pattern  string // pattern of the dead route
+	Location string // where the route was registered
+	By       string // what always wins over the route, such as a redirect key
+	Reason   string
+}
+
+func (s ShadowReport) String() string {
+	return fmt.Sprintf("%s: pattern %q can never match: %s", s.Location, s.Pattern, s.Reason)
+}
+
+// FindShadowed reports the registered patterns that can never match a
+// request, to catch dead routes. The mux rejects patterns equivalent to
+// registered ones, so such routes are shadowed by what the mux checks
+// before the patterns, such as the redirects added with
+// [ServeMux.RedirectMap], or can't match requests at all, such as patterns
+// with a port while MatchPorts is not set. CONNECT requests, which skip
+// the redirects, are not considered. Routes with matchers that never
+// accept a request are not detected. The reports are in registration
+// order.
+func (mux *ServeMux) FindShadowed() []ShadowReport {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	var reports []ShadowReport
+	for _, rt := range mux.routes {
+		if by, reason := mux.shadowed(rt.pat); reason != "" {
+			reports = append(reports, ShadowReport{
+				Pattern:  rt.pat.str,
+				Location: rt.pat.loc,
+				By:       by,
+				Reason:   reason,
+			})
+		}
+	}
+	return reports
+}
+
+// shadowed returns why p can never match, and what shadows it, if
+// anything does.
+func (mux *ServeMux) shadowed(p *pattern) (by, reason string) {
+	if p.method != "" && p.method != "CONNECT" && strings.Contains(p.host, ":") && !strings.HasSuffix(p.host, "]") {
+		if !mux.MatchPorts {
+			return "", "its host has a port, which is only matched with MatchPorts"
+		}
+	}
+	if path, ok := literalPath(p); ok && p.method != "CONNECT" {
+		host := portlessHost(p.host, p.method)
+		rm := &mux.redirects
+		rm.mu.RLock()
+		defer rm.mu.RUnlock()
+		for _, key := range []string{host + path, path} {
+			if _, ok := rm.entries[key]; ok && (host != "" || key == path) {
+				return key, fmt.Sprintf("requests for it are redirected by the redirect map entry %q", key)
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,53 @@
+package shortmux
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindShadowed(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	for _, pat := range []string{
+		"/old",
+		"GET /old/{x}",
+		"GET example.com/moved",
+		"GET other.com/moved",
+		"GET /moved",
+		"GET example.com:8443/admin",
+		"CONNECT example.com:443/",
+		"GET [::1]/ipv6",
+		"/live",
+	} {
+		mux.Handle(pat, h)
+	}
+	if err := mux.RedirectMap(map[string]string{
+		"/old":              "/new",
+		"example.com/moved": "/elsewhere",
+	}, http.StatusMovedPermanently); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range mux.FindShadowed() {
+		if !strings.Contains(s.Location, "shadow_test.go") {
+			t.Errorf("%s: location %q", s.Pattern, s.Location)
+		}
+		got = append(got, s.Pattern+" by "+s.By)
+	}
+	want := []string{
+		"/old by /old",
+		"GET example.com/moved by example.com/moved",
+		"GET example.com:8443/admin by ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	mux.MatchPorts = true
+	if got := mux.FindShadowed(); len(got) != 2 {
+		t.Errorf("with MatchPorts, got %v", got)
+	}
+}
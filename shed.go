@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A LoadShedder decides whether to reject requests while the server is
+// overloaded, e.g. based on the number of in-flight requests, the run-queue
+// length, or an external signal.
+type LoadShedder interface {
+	// Shed is called after a request is matched to a route and before it is
+	// dispatched. The class is the one set on the route with [WithClass],
+	// or empty, so that, for example, health checks can be exempted.
+	//
+	// If shed is true, the request is answered with 503 Service Unavailable
+	// and, if retryAfter is positive, a Retry-After header.
+	Shed(r *http.Request, class string) (retryAfter time.Duration, shed bool)
+}
+
+// LoadShedderFunc is an adapter to use ordinary functions as a [LoadShedder].
+type LoadShedderFunc func(r *http.Request, class string) (retryAfter time.Duration, shed bool)
+
+// Shed calls f(r, class).
+func (f LoadShedderFunc) Shed(r *http.Request, class string) (time.Duration, bool) {
+	return f(r, class)
+}
+
+// WithClass assigns the route to a class, such as "health" or "batch",
+// which subsystems like the mux LoadShedder use to treat groups of routes
+// differently.
+func WithClass(class string) RouteOption {
+	return func(c *routeConfig) {
+		c.class = class
+	}
+}
+
+// shed reports whether the request for the route rt was shed, in which case
+// the response was written to w.
+func (mux *ServeMux) shed(w http.ResponseWriter, r *http.Request, rt *route) bool {
+	if mux.LoadShedder == nil || rt == nil {
+		return false
+	}
+	retryAfter, shed := mux.LoadShedder.Shed(r, rt.cfg.class)
+	if !shed {
+		return false
+	}
+	if retryAfter > 0 {
+		// Retry-After is in whole seconds; round up so clients don't come
+		// back too early.
+		secs := int64((retryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+	mux.Error(w, r, http.StatusServiceUnavailable)
+	return true
+}
@@ -0,0 +1,39 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadShedder(t *testing.T) {
+	overloaded := true
+	mux := NewServeMux()
+	mux.LoadShedder = LoadShedderFunc(func(r *http.Request, class string) (time.Duration, bool) {
+		return 1500 * time.Millisecond, overloaded && class != "health"
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("/healthz", ok, WithClass("health"))
+	mux.HandleFunc("/api", ok)
+
+	for _, test := range []struct {
+		path           string
+		overloaded     bool
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{"/api", true, http.StatusServiceUnavailable, "2"},
+		{"/healthz", true, http.StatusOK, ""},
+		{"/api", false, http.StatusOK, ""},
+		{"/nope", true, http.StatusNotFound, ""},
+	} {
+		overloaded = test.overloaded
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.wantCode || w.Header().Get("Retry-After") != test.wantRetryAfter {
+			t.Errorf("%s overloaded=%t: got %d Retry-After %q, want %d %q", test.path, test.overloaded,
+				w.Code, w.Header().Get("Retry-After"), test.wantCode, test.wantRetryAfter)
+		}
+	}
+}
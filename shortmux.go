@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"math/rand/v2"
 	"net"
 	"net/http"
 	"net/url"
@@ -16,6 +17,8 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // ServeMux is an HTTP request multiplexer.
@@ -51,11 +54,15 @@ import (
 // the corresponding parts of a request case-sensitively.
 //
 // A pattern with no method matches every method. A pattern
-// with the method GET matches both GET and HEAD requests.
+// with the method GET matches both GET and HEAD requests,
+// unless [ServeMux.StrictHEAD] or [WithImplicitHEAD] say otherwise.
 // Otherwise, the method must match exactly.
 //
 // A pattern with no host matches every host.
 // A pattern with a host matches URLs on that host only.
+// An IPv6 literal host is written in brackets, as in "[::1]/", with any
+// zone ID escaped as in "[fe80::1%25eth0]/", and matches any equivalent
+// form of the address.
 //
 // A path can include wildcard segments of the form {NAME} or {NAME...}.
 // For example, "/b/{bucket}/o/{objectname...}".
@@ -67,10 +74,27 @@ import (
 // Normally a wildcard matches only a single path segment,
 // ending at the next literal slash (not %2F) in the request URL.
 // But if the "..." is present, then the wildcard matches the remainder of the URL path, including slashes.
-// (Therefore it is invalid for a "..." wildcard to appear anywhere but at the end of a pattern.)
+// One "..." wildcard may be followed by other segments, as in "/files/{path...}/meta" or
+// "/repo/{path...}/blob/{file}": literals and single wildcards, then optionally {$}, another
+// "..." wildcard or a trailing slash. It then matches the fewest segments, at least one, for
+// which the following segments match the rest of the path, and takes precedence over a "..."
+// wildcard ending the pattern at the same position. Of two such patterns that could match the
+// same path, the one with a fixed number of segments, then the longer, then the more specific
+// takes precedence; patterns for which none of these holds can't be registered together.
 // The match for a wildcard can be obtained by calling [Request.PathValue] with the wildcard's name.
 // A trailing slash in a path acts as an anonymous "..." wildcard.
 //
+// A single-segment wildcard can be constrained to a set of literals, as in
+// "/export/{format:(json|xml|csv)}". It matches only those literals, with
+// the precedence of a literal, and patterns whose literals are disjoint can
+// be registered together: "/export/{kind:(pdf|txt)}" can coexist with the
+// pattern above, but "/export/{f:(csv|tsv)}" cannot.
+//
+// Conversely, a single-segment wildcard can exclude literals, as in
+// "/{page:!admin}" or "/{page:!(admin|api)}". Requests for an excluded
+// literal are matched against the other patterns as if the wildcard
+// pattern wasn't registered, which keeps reserved words out of a catch-all.
+//
 // The special wildcard {$} matches only the end of the URL.
 // For example, the pattern "/{$}" matches only the path "/",
 // whereas the pattern "/" matches every path.
@@ -137,9 +161,199 @@ import (
 //     This change mostly affects how paths with %2F escapes adjacent to slashes are treated.
 //     See https://go.dev/issue/21955 for details.
 type ServeMux struct {
-	mu    sync.RWMutex
-	tree  routingNode
-	index routingIndex
+	mu      sync.RWMutex
+	tree    routingNode
+	index   routingIndex
+	static  staticRoutes   // fast path for literal patterns
+	methods map[string]int // number of registered patterns by method
+	cache   matchCache     // enabled with MatchCacheSize
+	routes  []*route       // in registration order
+	acme    ACMEResponder
+
+	redirects redirectMap // added with RedirectMap
+	reserved  []string    // prefixes reserved with Reserve
+
+	version atomic.Uint64 // incremented by each change to the routes
+	frozen  atomic.Bool   // set by Freeze, after which the routes don't change
+	timed   atomic.Bool   // whether a route was registered with WithServerTiming
+	passes  atomic.Bool   // whether a route was registered with WithFallthrough
+
+	canonical atomic.Pointer[canonicalHost] // set with CanonicalHost
+
+	subsMu sync.Mutex
+	subs   []chan RouteChange // channels returned by Changes
+
+	// The fields below configure optional behavior.
+	// They must be set before the mux starts serving requests.
+
+	// Principal, if non-nil, identifies the client making a request,
+	// e.g. a user ID taken from an authentication token.
+	// It is used to fill in [AuditEvent.Principal].
+	Principal func(*http.Request) string
+
+	// AuditSink, if non-nil, receives the events of routes registered with
+	// [WithAudit].
+	AuditSink AuditSink
+
+	// OnRegister and OnRemove, if non-nil, are called after a route is
+	// registered or removed, so that systems derived from the routing
+	// table can stay in sync incrementally. They may use the mux.
+	OnRegister func(Route)
+	OnRemove   func(Route)
+
+	// StrictHEAD, if true, stops GET patterns from matching HEAD requests,
+	// so that HEAD is only served by patterns with the HEAD method or no
+	// method, or by GET routes registered with WithImplicitHEAD(true).
+	StrictHEAD bool
+
+	// LoadShedder, if non-nil, is consulted before dispatching each matched
+	// request, and may reject it with 503 Service Unavailable.
+	LoadShedder LoadShedder
+
+	// OnSlowRequest, if non-nil, is called from its own goroutine for
+	// requests exceeding the threshold set with [WithSlowThreshold].
+	OnSlowRequest func(*SlowRequest)
+
+	// OnResponseTooLarge, if non-nil, is called for the responses
+	// exceeding the limit set on their route with [WithMaxResponseBytes].
+	OnResponseTooLarge func(r *http.Request, limit int64)
+
+	// OnComplete, if non-nil, is called after each request served by the
+	// mux, including rejected and unmatched ones, so that server-level
+	// accounting can learn the matched pattern without wrapping every
+	// handler.
+	OnComplete func(r *http.Request, c *CompletedRequest)
+
+	// LogAttrs, if non-nil, returns the attributes, as for
+	// [slog.Logger.With], added to the loggers of the requests of routes
+	// registered with [WithLogger], such as a request ID or a tenant.
+	LogAttrs func(r *http.Request) []any
+
+	// VerifyContentLength, if set, checks that the handlers of matched
+	// requests that set the Content-Length header write exactly that many
+	// bytes, catching truncated responses at the router. Writes beyond the
+	// declared length fail with [http.ErrContentLength]. Mismatches are
+	// reported to OnContentLengthMismatch, or logged with [slog.Default]
+	// if it is nil.
+	VerifyContentLength bool
+
+	// OnContentLengthMismatch, if non-nil, is called with the declared
+	// and the written lengths of the mismatches found with
+	// VerifyContentLength. Written includes the bytes refused beyond the
+	// declared length.
+	OnContentLengthMismatch func(r *http.Request, declared, written int64)
+
+	// Clock, if non-nil, replaces the system clock for the time-dependent
+	// features of the mux, such as schedules, time matchers, write rate
+	// limits, signed URLs and the in-memory webhook dedupe store, so that
+	// they can be tested deterministically. Timers still run on the system
+	// clock.
+	Clock Clock
+
+	// Rand, if non-nil, replaces the default source of randomness of the
+	// mux, used by BlueGreen and mirror sampling. It must be safe for
+	// concurrent use.
+	Rand rand.Source
+
+	// TimeoutHeaders lists the headers, such as "X-Request-Timeout" and
+	// "Grpc-Timeout", carrying the timeouts propagated by clients, which
+	// set the deadline of the context of matched requests. The first header
+	// present applies; see [ParseTimeout] for its format. The deadline of a
+	// route with a budget set with WithBudget is never later than the
+	// budget allows, and invalid timeouts are ignored.
+	TimeoutHeaders []string
+
+	// Sessions, if non-nil, loads the session of each matched request,
+	// available to handlers with [SessionOf], and saves it if modified.
+	// Routes opt out with [WithoutSession].
+	Sessions SessionManager
+
+	// ErrorPages, if non-nil, renders the error responses of the mux.
+	ErrorPages *ErrorPages
+
+	// SubtreeErrorPages maps subtrees, as paths ending in a slash with an
+	// optional host, as in HideAllowedMethods, to the error responses of
+	// the requests in them, overriding ErrorPages. The longest subtree
+	// containing a request applies, so that, for example, "/api/" can
+	// answer with problem details while "/" renders HTML pages. Of
+	// subtrees of the same length, one with a host applies.
+	SubtreeErrorPages map[string]*ErrorPages
+
+	// HideAllowedMethods lists subtrees, as paths ending in a slash with an
+	// optional host, such as "/admin/" or "example.com/internal/", where
+	// requests matching a pattern except for their method get 404 Not Found
+	// instead of 405 Method Not Allowed, so as not to reveal which paths
+	// exist.
+	HideAllowedMethods []string
+
+	// ServerOptions, if non-nil, serves "OPTIONS *" requests, which RFC
+	// 9110 allows for asking about the capabilities of the server, instead
+	// of the 400 Bad Request reply given to all requests for "*". See
+	// [ServeMux.ServerOptionsHandler].
+	ServerOptions http.Handler
+
+	// WildcardName, if non-nil, reports whether name is a valid wildcard
+	// name, replacing the rule that names be Go identifiers, for example
+	// to allow dashes in patterns loaded from configuration. Names are
+	// unescaped before being checked, so characters with a meaning in
+	// patterns can be escaped, as in "{a%3Ab}" for the name "a:b", which
+	// is given unescaped to [http.Request.PathValue]. It must be set
+	// before patterns are registered.
+	WildcardName func(name string) bool
+
+	// HTTPS, if non-nil, makes hosts HTTPS-only, and configures the
+	// HTTPS-only routes registered with WithHTTPSOnly.
+	HTTPS *HTTPSPolicy
+
+	// StrictRequests, if true, rejects with 400 Bad Request the requests
+	// showing signs of request smuggling or header injection before they
+	// are routed: conflicting Content-Length and Transfer-Encoding
+	// headers, and invalid Host headers. It also rejects requests with an
+	// absolute-form target, as sent to forward proxies, unless they match
+	// a route registered with WithAbsoluteForm. It is meant for servers
+	// facing untrusted clients directly.
+	StrictRequests bool
+
+	// StrictMethods, if true, replies with 501 Not Implemented, rather
+	// than 404 Not Found or 405 Method Not Allowed, to requests whose
+	// method is not a valid token, or neither a method defined by RFC 9110
+	// or RFC 5789 nor the method of a registered pattern. The reply lists
+	// the methods the mux serves in its Allow header, as
+	// [ServeMux.ServerOptionsHandler] does.
+	StrictMethods bool
+
+	// FoldHosts, if true, matches hosts case-insensitively and ignoring a
+	// trailing dot, so that a request for "Example.COM." matches the
+	// pattern "example.com/". It must be set before patterns are
+	// registered.
+	FoldHosts bool
+
+	// MatchPorts, if true, keeps the port of the host of requests when
+	// matching patterns, so that patterns such as "example.com:8443/" only
+	// match requests received on that port, taking precedence over patterns
+	// with the same host and no port, which match any port. It is meant
+	// for muxes serving several listeners.
+	MatchPorts bool
+
+	// MatchLimit, if positive, limits the number of nodes of the routing
+	// tree visited to match a request, as a safety valve against the
+	// backtracking caused by many overlapping wildcard patterns. Requests
+	// exceeding it are answered with 404 Not Found, after calling
+	// OnMatchLimit, if non-nil.
+	MatchLimit   int
+	OnMatchLimit func(*http.Request)
+
+	// MatchCacheSize, if positive, is the number of matches kept in a
+	// cache evicting the matches not used recently, by method, host and
+	// path, for workloads with few distinct URLs. Only matches without wildcard values, by
+	// routes with no matchers, are cached, and the cache is emptied when
+	// the routes change. See [ServeMux.MatchCacheStats].
+	MatchCacheSize int
+
+	// Observer, if non-nil, is notified of the outcome of matched
+	// requests, such as clients disconnecting before their response is
+	// complete.
+	Observer Observer
 }
 
 // NewServeMux allocates and returns a new [ServeMux].
@@ -170,11 +384,20 @@ func cleanPath(p string) string {
 }
 
 // stripHostPort returns h without any trailing ":<port>".
+// IPv6 literals keep their brackets, in canonical form, so that they
+// match the hosts of patterns such as "[::1]/".
 func stripHostPort(h string) string {
 	// If no port on host, return unchanged
 	if !strings.Contains(h, ":") {
 		return h
 	}
+	if h[0] == '[' {
+		ip, ok := canonicalIPv6(h)
+		if !ok {
+			return h // on error, return unchanged
+		}
+		return ip[:strings.IndexByte(ip, ']')+1]
+	}
 	host, _, err := net.SplitHostPort(h)
 	if err != nil {
 		return h // on error, return unchanged
@@ -198,16 +421,19 @@ func stripHostPort(h string) string {
 // If there is no registered handler that applies to the request,
 // Handler returns a “page not found” handler and an empty pattern.
 func (mux *ServeMux) Handler(r *http.Request) (h http.Handler, pattern string) {
-	h, p, _, _ := mux.findHandler(r)
+	h, p, _, _, _ := mux.findHandler(r)
 	return h, p
 }
 
 // findHandler finds a handler for a request.
-// If there is a matching handler, it returns it and the pattern that matched.
+// If there is a matching handler, it returns it, the pattern that matched,
+// and the leaf node holding them.
 // Otherwise it returns a Redirect or NotFound handler with the path that would match
 // after the redirect.
-func (mux *ServeMux) findHandler(r *http.Request) (h http.Handler, patStr string, _ *pattern, matches []string) {
+func (mux *ServeMux) findHandler(r *http.Request) (h http.Handler, patStr string, _ *routingNode, matches []string, _ *MatchState) {
 	var n *routingNode
+	var st *MatchState
+	sc := mux.newScope(r)
 	host := r.URL.Host
 	escapedPath := r.URL.EscapedPath()
 	path := escapedPath
@@ -216,25 +442,35 @@ func (mux *ServeMux) findHandler(r *http.Request) (h http.Handler, patStr string
 		// If r.URL.Path is /tree and its handler is not registered,
 		// the /tree -> /tree/ redirect applies to CONNECT requests
 		// but the path canonicalization does not.
-		_, _, u := mux.matchOrRedirect(host, r.Method, path, r.URL)
+		_, _, _, u := mux.matchOrRedirect(host, r.Method, path, r.URL, r, sc)
 		if u != nil {
-			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), u.Path, nil, nil
+			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), u.Path, nil, nil, nil
 		}
 		// Redo the match, this time with r.Host instead of r.URL.Host.
 		// Pass a nil URL to skip the trailing-slash redirect logic.
-		n, matches, _ = mux.matchOrRedirect(r.Host, r.Method, path, nil)
+		n, matches, st, _ = mux.matchOrRedirect(r.Host, r.Method, path, nil, r, sc)
 	} else {
-		// All other requests have any port stripped and path cleaned
-		// before passing to mux.handler.
-		host = stripHostPort(r.Host)
+		// All other requests have any port stripped, unless MatchPorts
+		// is set, and path cleaned before passing to mux.handler.
+		host = mux.requestHost(r)
 		path = cleanPath(path)
 
+		// Published ACME challenges take precedence over patterns.
+		if h := mux.acmeHandler(r); h != nil {
+			return h, acmeChallengePrefix + "{token}", nil, nil, nil
+		}
+		if path == escapedPath {
+			if h, key := mux.redirects.handler(portlessHost(host, r.Method), path); h != nil {
+				return h, key, nil, nil, nil
+			}
+		}
+
 		// If the given path is /tree and its handler is not registered,
 		// redirect for /tree/.
 		var u *url.URL
-		n, matches, u = mux.matchOrRedirect(host, r.Method, path, r.URL)
+		n, matches, st, u = mux.matchOrRedirect(host, r.Method, path, r.URL, r, sc)
 		if u != nil {
-			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), u.Path, nil, nil
+			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), u.Path, nil, nil, nil
 		}
 		if path != escapedPath {
 			// Redirect to cleaned path.
@@ -243,23 +479,32 @@ func (mux *ServeMux) findHandler(r *http.Request) (h http.Handler, patStr string
 				patStr = n.pattern.String()
 			}
 			u := &url.URL{Path: path, RawQuery: r.URL.RawQuery}
-			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), patStr, nil, nil
+			return http.RedirectHandler(u.String(), http.StatusMovedPermanently), patStr, nil, nil, nil
+		}
+		if h, patStr := mux.preflight(r, host, path, n); h != nil {
+			return h, patStr, nil, nil, nil
 		}
 	}
 	if n == nil {
 		// We didn't find a match with the request method. To distinguish between
 		// Not Found and Method Not Allowed, see if there is another pattern that
 		// matches except for the method.
-		allowedMethods := mux.matchingMethods(host, path)
-		if len(allowedMethods) > 0 {
+		if sc != nil && sc.exceeded {
+			if mux.OnMatchLimit != nil {
+				mux.OnMatchLimit(r)
+			}
+			return mux.errorHandler(http.StatusNotFound), "", nil, nil, nil
+		}
+		allowedMethods := mux.matchingMethods(host, path, r, sc)
+		if len(allowedMethods) > 0 && !mux.hidesMethods(portlessHost(host, r.Method), path) {
 			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.Header().Set("Allow", strings.Join(allowedMethods, ", "))
-				http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-			}), "", nil, nil
+				mux.Error(w, r, http.StatusMethodNotAllowed)
+			}), "", nil, nil, nil
 		}
-		return http.NotFoundHandler(), "", nil, nil
+		return mux.errorHandler(http.StatusNotFound), "", nil, nil, nil
 	}
-	return n.handler, n.pattern.String(), n.pattern, matches
+	return n.handler, n.pattern.String(), n, matches, st
 }
 
 // matchOrRedirect looks up a node in the tree that matches the host, method and path.
@@ -268,22 +513,44 @@ func (mux *ServeMux) findHandler(r *http.Request) (h http.Handler, patStr string
 // redirection: when a path doesn't match exactly, the match is tried again
 // after appending "/" to the path. If that second match succeeds, the last
 // return value is the URL to redirect to.
-func (mux *ServeMux) matchOrRedirect(host, method, path string, u *url.URL) (_ *routingNode, matches []string, redirectTo *url.URL) {
-	mux.mu.RLock()
-	defer mux.mu.RUnlock()
+//
+// If r is non-nil, it is the request evaluated by route matchers, in the
+// scope sc.
+func (mux *ServeMux) matchOrRedirect(host, method, path string, u *url.URL, r *http.Request, sc *matchScope) (_ *routingNode, matches []string, _ *MatchState, redirectTo *url.URL) {
+	// The routes of a frozen mux can be read without the lock.
+	if !mux.frozen.Load() {
+		mux.mu.RLock()
+		defer mux.mu.RUnlock()
+	}
 
-	n, matches := mux.tree.match(host, method, path)
+	if sc == nil || sc.skip == nil {
+		if n := mux.static.lookup(&mux.tree, method, host, path); n != nil {
+			return n, nil, nil, nil
+		}
+	}
+	// Matches done with a URL, which may be redirected, are cached.
+	cached := u != nil && sc != nil && sc.skip == nil && mux.MatchCacheSize > 0
+	key, version := staticKey{method, host, path}, mux.version.Load()
+	if cached {
+		if n := mux.cache.get(key, version, mux.MatchCacheSize); n != nil {
+			return n, nil, nil, nil
+		}
+	}
+	n, matches, st := mux.tree.match(host, method, path, mux.StrictHEAD, r, sc)
 	// If we have an exact match, or we were asked not to try trailing-slash redirection,
 	// or the URL already has a trailing slash, then we're done.
 	if !exactMatch(n, path) && u != nil && !strings.HasSuffix(path, "/") {
 		// If there is an exact match with a trailing slash, then redirect.
 		path += "/"
-		n2, _ := mux.tree.match(host, method, path)
+		n2, _, _ := mux.tree.match(host, method, path, mux.StrictHEAD, r, sc)
 		if exactMatch(n2, path) {
-			return nil, nil, &url.URL{Path: cleanPath(u.Path) + "/", RawQuery: u.RawQuery}
+			return nil, nil, nil, &url.URL{Path: cleanPath(u.Path) + "/", RawQuery: u.RawQuery}
 		}
 	}
-	return n, matches, nil
+	if cached && n != nil && len(matches) == 0 && st == nil && !sc.consulted && !sc.exceeded {
+		mux.cache.put(key, n, version, mux.MatchCacheSize)
+	}
+	return n, matches, st, nil
 }
 
 // exactMatch reports whether the node's pattern exactly matches the path.
@@ -323,6 +590,11 @@ func exactMatch(n *routingNode, path string) bool {
 	if !n.pattern.lastSegment().multi {
 		return true
 	}
+	// The multi ending a suffix matches after the segments of the one
+	// before it.
+	if n.pattern.suffix != nil {
+		return n.pattern.exactSuffix(path)
+	}
 
 	// If the path doesn't end in a trailing slash, then the multi match
 	// is non-empty.
@@ -338,107 +610,436 @@ func exactMatch(n *routingNode, path string) bool {
 }
 
 // matchingMethods return a sorted list of all methods that would match with the given host and path.
-func (mux *ServeMux) matchingMethods(host, path string) []string {
+func (mux *ServeMux) matchingMethods(host, path string, r *http.Request, sc *matchScope) []string {
 	// Hold the read lock for the entire method so that the two matches are done
 	// on the same set of registered patterns.
-	mux.mu.RLock()
-	defer mux.mu.RUnlock()
+	if !mux.frozen.Load() {
+		mux.mu.RLock()
+		defer mux.mu.RUnlock()
+	}
 	ms := map[string]bool{}
-	mux.tree.matchingMethods(host, path, ms)
+	mux.tree.matchingMethods(host, path, ms, mux.StrictHEAD, r, sc)
 	// matchOrRedirect will try appending a trailing slash if there is no match.
 	if !strings.HasSuffix(path, "/") {
-		mux.tree.matchingMethods(host, path+"/", ms)
+		mux.tree.matchingMethods(host, path+"/", ms, mux.StrictHEAD, r, sc)
 	}
 	return slices.Sorted(maps.Keys(ms))
 }
 
+// hidesMethods reports whether host and path are in one of the subtrees
+// of HideAllowedMethods.
+func (mux *ServeMux) hidesMethods(host, path string) bool {
+	for _, st := range mux.HideAllowedMethods {
+		if inSubtree(st, host, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// inSubtree reports whether host and path are in the subtree st, a path
+// ending in a slash with an optional host, such as "example.com/api/".
+// The path of the subtree without its slash is in it.
+func inSubtree(st, host, path string) bool {
+	i := strings.IndexByte(st, '/')
+	if i < 0 || (i > 0 && !strings.EqualFold(st[:i], host)) {
+		return false
+	}
+	prefix := st[i:]
+	return strings.HasPrefix(path, prefix) || path+"/" == prefix
+}
+
+// AllowedMethods returns the sorted list of the methods of requests for
+// host and path that match a registered pattern, as listed in the Allow
+// header of 405 Method Not Allowed responses, so that OPTIONS handlers and
+// documentation agree with the mux. As for requests, any port is stripped
+// from host and path, in its escaped form, is cleaned. Route matchers are
+// not evaluated. A matching pattern with no method contributes "",
+// meaning any method.
+func (mux *ServeMux) AllowedMethods(host, path string) []string {
+	return mux.matchingMethods(mux.matchHost(stripHostPort(host)), cleanPath(path), nil, nil)
+}
+
 // ServeHTTP dispatches the request to the handler whose
 // pattern most closely matches the request URL.
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if mux.OnComplete != nil {
+		iw := &instrumentedWriter{ResponseWriter: w}
+		start := time.Now()
+		defer func() { mux.complete(iw, r, start) }()
+		w = iw
+	}
 	if r.RequestURI == "*" {
+		if r.Method == http.MethodOptions && mux.ServerOptions != nil {
+			mux.ServerOptions.ServeHTTP(w, r)
+			return
+		}
 		if r.ProtoAtLeast(1, 1) {
 			w.Header().Set("Connection", "close")
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	h, pattern, pat, matches := mux.findHandler(r)
-	r.Pattern = pattern
-	if pat != nil {
-		for _, p := range pat.segments {
-			if p.wild {
-				// If the segment is a wildcard, set the path value in the request.
-				// The wildcard name is in p.s.
-				if p.s != "" {
-					r.SetPathValue(p.s, matches[0]) // matches[0] is the first match for this segment
-					matches = matches[1:]           // remove the first match since it was used
-				} else if p.multi {
-					// Multi wildcard, set the rest of matches as a single value
-					r.SetPathValue("...", strings.Join(matches, "/"))
-					matches = nil // all matches consumed
-				}
+	r = mux.withEnv(r)
+	if ep := mux.errorPages(r); ep != nil && ep.Recover {
+		iw := &instrumentedWriter{ResponseWriter: w}
+		defer func() {
+			if v := recover(); v != nil {
+				mux.recoverPanic(v, iw, r)
 			}
+		}()
+		w = iw
+	}
+	if mux.preempt(w, r) {
+		return
+	}
+	var start time.Time
+	if mux.timed.Load() {
+		start = time.Now()
+	}
+	h, pattern, n, matches, st := mux.findHandler(r)
+	if mux.rejectMatch(w, r, n) {
+		return
+	}
+	r.Pattern = pattern
+	if n != nil {
+		r = setMatch(r, n, matches, st)
+		if rt := n.route; rt != nil && rt.cfg.fallsThrough {
+			r = mux.withNext(r, n)
+		}
+		if mux.shed(w, r, n.route) {
+			return
+		}
+		if len(mux.TimeoutHeaders) > 0 {
+			var cancel func()
+			r, cancel = mux.clientDeadline(r, n.route)
+			defer cancel()
+		}
+		if rt := n.route; rt != nil && rt.cfg.serverTiming {
+			tw, tr := startTiming(w, r, start)
+			defer tw.finish()
+			w, r = tw, tr
+		}
+		if mux.Observer != nil {
+			iw := &instrumentedWriter{ResponseWriter: w}
+			defer mux.observe(iw, r, time.Now())
+			w = iw
+		}
+		if mux.VerifyContentLength {
+			iw := &instrumentedWriter{ResponseWriter: w, verify: true}
+			defer mux.verifyLength(iw, r)
+			w = iw
+		}
+		if rt := n.route; mux.Sessions != nil && rt != nil && rt.cfg.session != sessionOff {
+			mux.serveSession(w, r, h, rt.cfg.session)
+			return
 		}
 	}
 	h.ServeHTTP(w, r)
 }
 
-// The four functions below all call ServeMux.register so that callerLocation
-// always refers to user code.
+// preempt answers r by itself if it is rejected or redirected before being
+// routed, as set by StrictRequests, StrictMethods, CanonicalHost and
+// HTTPS, or if it is for a published ACME challenge, and reports whether
+// it did.
+func (mux *ServeMux) preempt(w http.ResponseWriter, r *http.Request) bool {
+	if mux.StrictRequests {
+		if err := checkRequest(r); err != nil {
+			w.Header().Set("Connection", "close")
+			mux.errorText(w, r, http.StatusBadRequest, "400 Bad Request: "+err.Error())
+			return true
+		}
+	}
+	if mux.StrictMethods && mux.rejectMethod(w, r) {
+		return true
+	}
+	// Published ACME challenges are answered on every host, over plain
+	// HTTP, so hosts are only redirected for other requests.
+	if h := mux.acmeHandler(r); h != nil {
+		r.Pattern = acmeChallengePrefix + "{token}"
+		h.ServeHTTP(w, r)
+		return true
+	}
+	if c := mux.canonical.Load(); c != nil && mux.redirectHost(w, r, c) {
+		return true
+	}
+	return mux.httpsHost(r) && mux.enforceHTTPS(w, r)
+}
+
+// rejectMatch answers r by itself if it is rejected or redirected after
+// being matched by the leaf n, or nil, as for absolute-form targets with
+// StrictRequests and HTTPS-only routes, and reports whether it did.
+func (mux *ServeMux) rejectMatch(w http.ResponseWriter, r *http.Request, n *routingNode) bool {
+	var rt *route
+	if n != nil {
+		rt = n.route
+	}
+	if mux.StrictRequests && absoluteForm(r) && (rt == nil || !rt.cfg.absoluteForm) {
+		w.Header().Set("Connection", "close")
+		mux.errorText(w, r, http.StatusBadRequest, "400 Bad Request: absolute-form request target")
+		return true
+	}
+	// Requests for HTTPS-only hosts were enforced by preempt.
+	return rt != nil && rt.cfg.httpsOnly && !mux.httpsHost(r) && mux.enforceHTTPS(w, r)
+}
+
+// httpsHost reports whether the host of r is HTTPS-only in mux.HTTPS.
+func (mux *ServeMux) httpsHost(r *http.Request) bool {
+	return mux.HTTPS != nil && mux.HTTPS.httpsHost(stripHostPort(r.Host))
+}
+
+// setMatch returns r with the path values and the values set by the
+// matchers of the leaf n, matched with the wildcard values matches and
+// matcher state st.
+func setMatch(r *http.Request, n *routingNode, matches []string, st *MatchState) *http.Request {
+	if st != nil {
+		r = st.apply(r)
+	}
+	// The path values are set before r is copied, so that they are also
+	// set for the caller of ServeHTTP, as with [http.ServeMux].
+	wildcards := matches
+	for _, p := range n.pattern.segments {
+		if p.wild {
+			// If the segment is a wildcard, set the path value in the request.
+			// The wildcard name is in p.s.
+			// For a repeated name, the last value wins.
+			if p.s != "" {
+				r.SetPathValue(p.s, matches[0]) // matches[0] is the first match for this segment
+				matches = matches[1:]           // remove the first match since it was used
+			} else if p.multi {
+				// Multi wildcard, set the rest of matches as a single value
+				r.SetPathValue("...", strings.Join(matches, "/"))
+				matches = nil // all matches consumed
+			}
+		}
+	}
+	return withMatched(r, n.pattern, wildcards)
+}
+
+// The registration methods all call ServeMux.register directly so that
+// callerLocation always refers to user code.
 
 // Handle registers the handler for the given pattern.
 // If the given pattern conflicts, with one that is already registered, Handle
 // panics.
-func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
-	mux.register(pattern, handler)
+// The options, if any, configure the route; see [RouteOption].
+func (mux *ServeMux) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	mux.register(pattern, handler, opts)
 }
 
 // HandleFunc registers the handler function for the given pattern.
 // If the given pattern conflicts, with one that is already registered, HandleFunc
 // panics.
-func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
-	mux.register(pattern, http.HandlerFunc(handler))
+// The options, if any, configure the route; see [RouteOption].
+func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+	mux.register(pattern, http.HandlerFunc(handler), opts)
 }
 
-func (mux *ServeMux) register(pattern string, handler http.Handler) {
-	if err := mux.registerErr(pattern, handler); err != nil {
+func (mux *ServeMux) register(pattern string, handler http.Handler, opts []RouteOption) {
+	if err := mux.registerErr(pattern, handler, opts...); err != nil {
 		panic(err)
 	}
 }
 
-func (mux *ServeMux) registerErr(patstr string, handler http.Handler) error {
+func (mux *ServeMux) registerErr(patstr string, handler http.Handler, opts ...RouteOption) error {
+	rt, err := mux.newRoute(patstr, handler, opts)
+	if err != nil {
+		return err
+	}
+
+	// Get the caller's location, for better error messages.
+	// Skip register and whatever calls it.
+	rt.pat.loc = callerLocation(3)
+
+	if err := mux.addRoute(rt); err != nil {
+		return err
+	}
+	mux.changed(false, rt)
+	return nil
+}
+
+// newRoute parses patstr and applies opts to a new route for handler.
+func (mux *ServeMux) newRoute(patstr string, handler http.Handler, opts []RouteOption) (*route, error) {
 	if patstr == "" {
-		return errors.New("http: invalid pattern")
+		return nil, errors.New("http: invalid pattern")
 	}
 	if handler == nil {
-		return errors.New("http: nil handler")
+		return nil, errors.New("http: nil handler")
 	}
 	if f, ok := handler.(http.HandlerFunc); ok && f == nil {
-		return errors.New("http: nil handler")
+		return nil, errors.New("http: nil handler")
 	}
 
-	pat, err := parsePattern(patstr)
+	pat, err := mux.parsePattern(patstr)
 	if err != nil {
-		return fmt.Errorf("parsing %q: %w", patstr, err)
+		return nil, fmt.Errorf("parsing %q: %w", patstr, err)
 	}
 
-	// Get the caller's location, for better error messages.
-	// Skip register and whatever calls it.
-	_, file, line, ok := runtime.Caller(3)
+	cfg := routeConfig{mux: mux, pat: pat}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	inner := handler
+	if cfg.serverTiming {
+		inner = markHandlerStart(inner)
+	}
+	rt := &route{pat: pat, handler: handler, wrapped: cfg.wrap(inner), cfg: &cfg}
+	if cfg.slashAlias {
+		if rt.alias, err = mux.newAlias(rt); err != nil {
+			return nil, err
+		}
+	}
+	return rt, nil
+}
+
+// parsePattern parses s, with the wildcard names allowed by the mux.
+func (mux *ServeMux) parsePattern(s string) (*pattern, error) {
+	p, err := parsePatternNames(s, mux.WildcardName)
+	if err == nil && mux.FoldHosts {
+		p.host = foldHost(p.host)
+	}
+	return p, err
+}
+
+// callerLocation returns the source location of the caller skip frames
+// above its own caller.
+func callerLocation(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
 	if !ok {
-		pat.loc = "unknown location"
-	} else {
-		pat.loc = fmt.Sprintf("%s:%d", file, line)
+		return "unknown location"
 	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
 
+// addRoute adds rt to the mux.
+func (mux *ServeMux) addRoute(rt *route) error {
 	mux.mu.Lock()
 	defer mux.mu.Unlock()
+	if mux.frozen.Load() {
+		return ErrFrozen
+	}
+	if err := mux.addRouteLocked(rt); err != nil {
+		return err
+	}
+	mux.version.Add(1)
+	return nil
+}
+
+func (mux *ServeMux) addRouteLocked(rt *route) error {
 	// No conflict checking - differently than http.ServeMux, allow overlapping patterns
 	// Allow overlapping patterns, but not exact duplicates
-	if mux.index.hasPattern(pat) {
-		return fmt.Errorf("exact pattern already registered")
+	// Patterns occupying the same leaf of the tree, such as "/{a}" and
+	// "/{b}", match the same requests, so they conflict too.
+	if q := mux.tree.occupant(rt.pat); q != nil {
+		return errors.New(describeConflict(q, rt.pat))
+	}
+	if !rt.cfg.reserved {
+		if rp, ok := mux.reservation(rt.pat); ok {
+			return fmt.Errorf("pattern %q is under the reserved prefix %q; register it through the Reservation", rt.pat, rp)
+		}
+	}
+	if q := mux.tree.suffixConflict(rt.pat); q != nil {
+		return errors.New(describeSuffixConflict(q, rt.pat))
+	}
+	if name := rt.cfg.name; name != "" {
+		for _, other := range mux.routes {
+			if other.cfg.name == name {
+				return fmt.Errorf("route name %q already used by pattern %q at %s", name, other.pat, other.pat.loc)
+			}
+		}
+	}
+	if a := rt.alias; a != nil {
+		a.pat.loc = rt.pat.loc
+		if q := mux.tree.occupant(a.pat); q != nil {
+			return fmt.Errorf("slash alias of %q: %s", rt.pat, describeConflict(q, a.pat))
+		}
+		if !rt.cfg.reserved {
+			if rp, ok := mux.reservation(a.pat); ok {
+				return fmt.Errorf("slash alias %q of %q is under the reserved prefix %q; register it through the Reservation", a.pat, rt.pat, rp)
+			}
+		}
+	}
+	if rt.cfg.serverTiming {
+		mux.timed.Store(true)
+	}
+	if rt.cfg.fallsThrough {
+		mux.passes.Store(true)
+	}
+	mux.insertLocked(rt)
+	mux.routes = append(mux.routes, rt)
+	if rt.alias != nil {
+		mux.routes = append(mux.routes, rt.alias)
+	}
+	return nil
+}
+
+// insertLocked adds rt, and its slash alias, to the routing tree, the
+// index and the static routes.
+func (mux *ServeMux) insertLocked(rt *route) {
+	for ; rt != nil; rt = rt.alias {
+		mux.countMethod(rt.pat, 1)
+		mux.tree.addPattern(rt.pat, rt.wrapped, rt)
+		mux.index.addPattern(rt.pat)
+		mux.static.add(&mux.tree, rt)
+	}
+}
+
+// deleteLocked undoes insertLocked.
+func (mux *ServeMux) deleteLocked(rt *route) {
+	for ; rt != nil; rt = rt.alias {
+		mux.countMethod(rt.pat, -1)
+		mux.tree.removePattern(rt.pat)
+		mux.index.removePattern(rt.pat)
+		mux.static.remove(rt)
 	}
-	mux.tree.addPattern(pat, handler)
-	mux.index.addPattern(pat)
+}
+
+// Remove unregisters the route registered with exactly the given pattern.
+// Requests being served by it are not affected.
+// It returns an error if no such route is registered.
+func (mux *ServeMux) Remove(pattern string) error {
+	rt, err := mux.removeRoute(pattern)
+	if err != nil {
+		return err
+	}
+	mux.changed(true, rt)
 	return nil
 }
+
+// removeRoute removes the route with the given pattern from the mux,
+// and returns it.
+func (mux *ServeMux) removeRoute(pattern string) (*route, error) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if mux.frozen.Load() {
+		return nil, ErrFrozen
+	}
+	rt, err := mux.removeRouteLocked(pattern)
+	if err != nil {
+		return nil, err
+	}
+	mux.version.Add(1)
+	return rt, nil
+}
+
+// Version returns the version of the routing table of mux, which starts at
+// zero and is incremented by every registration or removal of a route, and
+// by every transaction applied with [ServeMux.Apply]. Consumers of
+// [ServeMux.Routes] can use it to tell whether the routes changed.
+func (mux *ServeMux) Version() uint64 {
+	return mux.version.Load()
+}
+
+func (mux *ServeMux) removeRouteLocked(pattern string) (*route, error) {
+	i := slices.IndexFunc(mux.routes, func(rt *route) bool { return rt.pat.str == pattern })
+	if i < 0 {
+		return nil, fmt.Errorf("pattern %q not registered", pattern)
+	}
+	rt := mux.routes[i]
+	if rt.aliasOf != nil {
+		return nil, fmt.Errorf("pattern %q is the slash alias of %q; remove that instead", pattern, rt.aliasOf.pat)
+	}
+	mux.deleteLocked(rt)
+	mux.routes = slices.DeleteFunc(mux.routes, func(r *route) bool { return r == rt || r.aliasOf == rt })
+	return rt, nil
+}
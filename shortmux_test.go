@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -66,7 +67,7 @@ func TestFindHandler(t *testing.T) {
 		r.Method = test.method
 		r.Host = "example.com"
 		r.URL = &url.URL{Path: test.path}
-		gotH, _, _, _ := mux.findHandler(&r)
+		gotH, _, _, _, _ := mux.findHandler(&r)
 		got := fmt.Sprintf("%#v", gotH)
 		if got != test.wantHandler {
 			t.Errorf("%s %q: got %q, want %q", test.method, test.path, got, test.wantHandler)
@@ -107,6 +108,7 @@ func TestRegisterErr(t *testing.T) {
 		{"/a", h, `exact pattern already registered`},
 		{"/a/b", h, `exact pattern already registered`},
 		{"/c/{x}", h, `exact pattern already registered`},
+		{"/c/{y}", h, `pattern "/c/\{x\}" .* matches the same requests as "/c/\{y\}"`},
 	} {
 		t.Run(fmt.Sprintf("%s:%#v", test.pattern, test.handler), func(t *testing.T) {
 			err := mux.registerErr(test.pattern, test.handler)
@@ -242,7 +244,7 @@ func BenchmarkServerMatch(b *testing.B) {
 		if err != nil {
 			b.Fatal(err)
 		}
-		if h, p, _, _ := mux.findHandler(r); h != nil && p == "" {
+		if h, p, _, _, _ := mux.findHandler(r); h != nil && p == "" {
 			b.Error("impossible")
 		}
 	}
@@ -295,3 +297,266 @@ func TestIntegrationWithHTTPServer(t *testing.T) {
 		}
 	}
 }
+
+func TestImplicitHEAD(t *testing.T) {
+	newMux := func(strict bool) *ServeMux {
+		mux := NewServeMux()
+		mux.StrictHEAD = strict
+		mux.Handle("GET /a", &handler{1})
+		mux.Handle("GET /b", &handler{2}, WithImplicitHEAD(false))
+		mux.Handle("/b", &handler{3})
+		mux.Handle("GET /c", &handler{4}, WithImplicitHEAD(true))
+		mux.Handle("GET /d", &handler{5})
+		mux.Handle("HEAD /d", &handler{6})
+		mux.Handle("GET /e/x", &handler{7}, WithImplicitHEAD(false))
+		mux.Handle("GET /e/{y}", &handler{8})
+		return mux
+	}
+	for _, test := range []struct {
+		strict      bool
+		path        string
+		wantHandler string
+	}{
+		{false, "/a", "&shortmux.handler{i:1}"},
+		{false, "/b", "&shortmux.handler{i:3}"},
+		{false, "/c", "&shortmux.handler{i:4}"},
+		{false, "/d", "&shortmux.handler{i:6}"},
+		{false, "/e/x", "&shortmux.handler{i:8}"},
+		{true, "/a", "(http.HandlerFunc)"}, // 405
+		{true, "/b", "&shortmux.handler{i:3}"},
+		{true, "/c", "&shortmux.handler{i:4}"},
+		{true, "/d", "&shortmux.handler{i:6}"},
+		{true, "/e/x", "(http.HandlerFunc)"}, // 405
+	} {
+		mux := newMux(test.strict)
+		r := httptest.NewRequest("HEAD", test.path, nil)
+		h, _ := mux.Handler(r)
+		got := fmt.Sprintf("%#v", h)
+		if !regexp.MustCompile("^" + regexp.QuoteMeta(test.wantHandler)).MatchString(got) {
+			t.Errorf("strict=%t HEAD %s: got %s, want %s", test.strict, test.path, got, test.wantHandler)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	newMux(true).ServeHTTP(w, httptest.NewRequest("HEAD", "/a", nil))
+	if got := w.Header().Get("Allow"); w.Code != http.StatusMethodNotAllowed || got != "GET" {
+		t.Errorf("strict HEAD /a: got %d Allow %q, want 405 Allow %q", w.Code, got, "GET")
+	}
+	w = httptest.NewRecorder()
+	newMux(true).ServeHTTP(w, httptest.NewRequest("POST", "/c", nil))
+	if got := w.Header().Get("Allow"); got != "GET, HEAD" {
+		t.Errorf("strict POST /c: got Allow %q, want %q", got, "GET, HEAD")
+	}
+}
+
+func TestConstrainedWildcards(t *testing.T) {
+	mux := NewServeMux()
+	for _, p := range []string{
+		"/export/{id}/{format:(json|xml)}",
+		"/export/{id}/{kind:(csv|tsv)}",
+		"/export/{id}/{other}",
+		"/pdf/{$}",
+		"/{doc:(pdf%2Fa|txt)}/",
+	} {
+		mux.Handle(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s format=%s kind=%s other=%s id=%s", r.Pattern,
+				r.PathValue("format"), r.PathValue("kind"), r.PathValue("other"), r.PathValue("id"))
+		}))
+	}
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/export/1/json", "/export/{id}/{format:(json|xml)} format=json kind= other= id=1"},
+		{"/export/2/xml", "/export/{id}/{format:(json|xml)} format=xml kind= other= id=2"},
+		{"/export/3/tsv", "/export/{id}/{kind:(csv|tsv)} format= kind=tsv other= id=3"},
+		{"/export/4/yaml", "/export/{id}/{other} format= kind= other=yaml id=4"},
+		{"/pdf%2Fa/x", "/{doc:(pdf%2Fa|txt)}/ format= kind= other= id="},
+		{"/pdf/", "/pdf/{$} format= kind= other= id="},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		pattern, wantErr string
+	}{
+		{"/export/{id}/{f:(xml|yaml)}", `matches the same requests`},
+		{"/export/{x}/csv", `matches the same requests`},
+		{"/{x:json}", `bad wildcard constraint`},
+		{"/{x:(a||b)}", `empty literal`},
+		{"/{x:(a|a)}", `duplicate literal`},
+		{"/{x...:(a|b)}", `cannot be constrained`},
+		{"/{x:(a|b)...}", `bad wildcard constraint`},
+	} {
+		err := mux.registerErr(test.pattern, http.NotFoundHandler())
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: got error %v, want %q", test.pattern, err, test.wantErr)
+		}
+	}
+
+	// Removing a constrained pattern frees all its literals.
+	if err := mux.Remove("/export/{id}/{kind:(csv|tsv)}"); err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/export/{x}/csv", http.NotFoundHandler())
+	mux.Handle("/export/{x}/tsv", http.NotFoundHandler())
+}
+
+func TestExcludingWildcards(t *testing.T) {
+	mux := NewServeMux()
+	for _, p := range []string{
+		"/",
+		"/{page:!(admin|api)}",
+		"/{page:!admin}/",
+		"/{f:(a|b)}/{x:!c}",
+		"/api/status",
+	} {
+		mux.Handle(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s page=%s x=%s", r.Pattern, r.PathValue("page"), r.PathValue("x"))
+		}))
+	}
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/about", "/{page:!(admin|api)} page=about x="},
+		{"/admin", "/ page= x="},
+		{"/login", "/{page:!(admin|api)} page=login x="},
+		{"/api/status", "/api/status page= x="},
+		{"/api/other", "/{page:!admin}/ page=api x="},
+		{"/admin/other", "/ page= x="},
+		{"/a/d", "/{f:(a|b)}/{x:!c} page= x=d"},
+		{"/a/c", "/{page:!admin}/ page=a x="},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	if err := mux.registerErr("/{x:!}", http.NotFoundHandler()); err == nil || !strings.Contains(err.Error(), "empty literal") {
+		t.Errorf("got error %v, want empty literal", err)
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("GET /items/{id}", h)
+	mux.Handle("DELETE /items/{id}", h)
+	mux.Handle("PUT api.example.com/items/{id}", h)
+	mux.Handle("POST /items/", h)
+	mux.Handle("/any", h)
+
+	for _, test := range []struct {
+		host, path string
+		want       []string
+	}{
+		{"example.com", "/items/1", []string{"DELETE", "GET", "HEAD", "POST"}},
+		{"api.example.com:8080", "/items/1", []string{"DELETE", "GET", "HEAD", "POST", "PUT"}},
+		{"example.com", "/items", []string{"POST"}},
+		{"example.com", "/items/../items/1", []string{"DELETE", "GET", "HEAD", "POST"}},
+		{"example.com", "/any", []string{""}},
+		{"example.com", "/missing", nil},
+	} {
+		got := mux.AllowedMethods(test.host, test.path)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("AllowedMethods(%q, %q) = %q, want %q", test.host, test.path, got, test.want)
+		}
+	}
+}
+
+func TestHideAllowedMethods(t *testing.T) {
+	mux := NewServeMux()
+	mux.HideAllowedMethods = []string{"/admin/", "internal.example.com/secret/"}
+	h := http.NotFoundHandler()
+	mux.Handle("GET /admin/users", h)
+	mux.Handle("GET /admin", h)
+	mux.Handle("GET /public", h)
+	mux.Handle("GET /secret/{x}", h)
+
+	for _, test := range []struct {
+		host, path string
+		want       int
+	}{
+		{"example.com", "/admin/users", 404},
+		{"example.com", "/admin", 404},
+		{"example.com", "/public", 405},
+		{"internal.example.com", "/secret/a", 404},
+		{"example.com", "/secret/a", 405},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "http://"+test.host+test.path, nil))
+		if w.Code != test.want {
+			t.Errorf("POST %s%s: status %d, want %d", test.host, test.path, w.Code, test.want)
+		}
+		if test.want == 404 && w.Header().Get("Allow") != "" {
+			t.Errorf("POST %s%s: Allow header %q revealed", test.host, test.path, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestWildcardNameOption(t *testing.T) {
+	mux := NewServeMux()
+	if err := mux.registerErr("/users/{user-id}", http.NotFoundHandler()); err == nil {
+		t.Fatal("dashed wildcard name accepted by default")
+	}
+	mux.WildcardName = func(name string) bool {
+		return !strings.ContainsAny(name, " ")
+	}
+	mux.HandleFunc("/users/{user-id}/{a%3Ab}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.PathValue("user-id"), r.PathValue("a:b"))
+		for name, value := range PathCaptures(r) {
+			fmt.Fprintf(w, " %s=%s", name, value)
+		}
+	})
+	if err := mux.registerErr("/{a%20b}", http.NotFoundHandler()); err == nil {
+		t.Error("wildcard name rejected by WildcardName accepted")
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/users/7/x", nil))
+	if got, want := w.Body.String(), "7 x user-id=7 a:b=x"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMatchLimit(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	// Each literal leads matching down a path that fails at the end,
+	// before the wildcards are tried.
+	mux.Handle("GET /a/b/c/d", h)
+	mux.Handle("GET /{w}/{x}/{y}/{z}/{$}", h)
+	mux.Handle("GET /a/{x}/c/{z}/e", h)
+	mux.Handle("GET /{w...}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "catch-all")
+	}))
+
+	var exceeded []string
+	mux.OnMatchLimit = func(r *http.Request) { exceeded = append(exceeded, r.URL.Path) }
+	for _, test := range []struct {
+		limit    int
+		wantCode int
+	}{
+		{0, 200},
+		{100, 200},
+		{5, 404},
+	} {
+		exceeded = nil
+		mux.MatchLimit = test.limit
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/a/b/c/d/x", nil))
+		if w.Code != test.wantCode {
+			t.Errorf("limit %d: got %d, want %d", test.limit, w.Code, test.wantCode)
+		}
+		if got := len(exceeded) > 0; got != (test.wantCode == 404) {
+			t.Errorf("limit %d: OnMatchLimit called for %q", test.limit, exceeded)
+		}
+	}
+}
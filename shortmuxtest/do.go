@@ -0,0 +1,98 @@
+package shortmuxtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+// A Result is the outcome of dispatching a request with [Do].
+type Result struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+
+	// Pattern is the pattern of the route the request was dispatched
+	// to, or empty if the mux responded by itself.
+	Pattern string
+	Params  map[string]string // wildcard values; the last one for repeated names
+}
+
+// Do dispatches req to mux with an [httptest.ResponseRecorder], and
+// returns the response along with the route that served it.
+func Do(mux *shortmux.ServeMux, req *http.Request) *Result {
+	e := mux.Explain(req)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	res := &Result{
+		StatusCode: w.Code,
+		Header:     w.Header(),
+		Body:       w.Body.String(),
+		Pattern:    e.Pattern,
+	}
+	if len(e.Params) > 0 {
+		res.Params = map[string]string{}
+		for _, p := range e.Params {
+			res.Params[p.Name] = p.Value
+		}
+	}
+	return res
+}
+
+// A Case is a request with the expected outcome of dispatching it, for
+// table-driven tests run with [Run]. Expectations left as zero values are
+// not checked.
+type Case struct {
+	Request string // as understood by NewRequest
+	Body    string // request body
+	Header  http.Header
+
+	WantStatus  int
+	WantPattern string
+	WantParams  map[string]string
+	WantBody    string // a substring of the response body
+	WantHeader  http.Header
+}
+
+// Run dispatches the request of each case to mux with [Do] in a subtest
+// named after the request, and checks its expectations.
+func Run(t *testing.T, mux *shortmux.ServeMux, cases []Case) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.Request, func(t *testing.T) {
+			t.Helper()
+			c.check(t, mux)
+		})
+	}
+}
+
+func (c *Case) check(t testing.TB, mux *shortmux.ServeMux) {
+	t.Helper()
+	req := newRequest(c.Request, strings.NewReader(c.Body))
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	res := Do(mux, req)
+	if c.WantStatus != 0 && res.StatusCode != c.WantStatus {
+		t.Errorf("got status %d, want %d", res.StatusCode, c.WantStatus)
+	}
+	if c.WantPattern != "" && res.Pattern != c.WantPattern {
+		t.Errorf("got pattern %q, want %q", res.Pattern, c.WantPattern)
+	}
+	for k, want := range c.WantParams {
+		if got, ok := res.Params[k]; !ok || got != want {
+			t.Errorf("got param %s = %q, want %q", k, got, want)
+		}
+	}
+	if c.WantBody != "" && !strings.Contains(res.Body, c.WantBody) {
+		t.Errorf("body %q does not contain %q", res.Body, c.WantBody)
+	}
+	for k, want := range c.WantHeader {
+		if got := res.Header.Values(k); strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Errorf("got header %s = %q, want %q", k, got, want)
+		}
+	}
+}
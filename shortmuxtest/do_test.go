@@ -0,0 +1,47 @@
+package shortmuxtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+func TestDo(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	mux.HandleFunc("POST /users/{id}/notes", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-User", r.PathValue("id"))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "note %q", b)
+	})
+
+	res := Do(mux, newRequest("POST /users/7/notes", nil))
+	if res.StatusCode != http.StatusCreated || res.Pattern != "POST /users/{id}/notes" || res.Params["id"] != "7" || res.Header.Get("X-User") != "7" {
+		t.Errorf("got %+v", res)
+	}
+
+	Run(t, mux, []Case{
+		{
+			Request:     "POST /users/3/notes",
+			Body:        "hello",
+			WantStatus:  http.StatusCreated,
+			WantPattern: "POST /users/{id}/notes",
+			WantParams:  map[string]string{"id": "3"},
+			WantBody:    `note "hello"`,
+			WantHeader:  http.Header{"X-User": {"3"}},
+		},
+		{
+			Request:    "GET /users/3/notes",
+			WantStatus: http.StatusMethodNotAllowed,
+			WantHeader: http.Header{"Allow": {"POST"}},
+		},
+	})
+
+	c := Case{Request: "POST /users/3/notes", WantStatus: 200, WantParams: map[string]string{"id": "4"}}
+	if errs := failures(func(tb testing.TB) { c.check(tb, mux) }); len(errs) != 2 {
+		t.Errorf("got failures %q, want 2", errs)
+	}
+}
@@ -0,0 +1,128 @@
+// Package shortmuxtest provides utilities for testing the routing of a
+// [shortmux.ServeMux], so that changes to routing behavior are caught in CI:
+//
+//	func TestRouting(t *testing.T) {
+//		mux := newMux()
+//		shortmuxtest.AssertMatch(t, mux, "GET /users/3", "GET /users/{id}", map[string]string{"id": "3"})
+//		shortmuxtest.AssertRoutes(t, mux, "testdata/routes.golden")
+//	}
+//
+// [Do] and [Run] dispatch requests end to end, checking the response along
+// with the route that served it.
+//
+// Golden files are rewritten with the -shortmuxtest.update flag:
+//
+//	go test -run TestRouting -shortmuxtest.update
+package shortmuxtest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+var update = flag.Bool("shortmuxtest.update", false, "rewrite the golden files of shortmuxtest.AssertRoutes")
+
+// NewRequest returns a request for req, which has the form
+//
+//	[METHOD ][HOST]/[PATH]
+//
+// like a pattern, e.g. "GET example.com/users/3?tab=posts". The method
+// defaults to GET and the host to example.com.
+func NewRequest(req string) *http.Request {
+	return newRequest(req, nil)
+}
+
+func newRequest(req string, body io.Reader) *http.Request {
+	method, target, found := strings.Cut(req, " ")
+	if !found {
+		method, target = "GET", req
+	}
+	target = strings.TrimLeft(target, " \t")
+	host := "example.com"
+	if i := strings.IndexByte(target, '/'); i > 0 {
+		host, target = target[:i], target[i:]
+	}
+	r := httptest.NewRequest(method, target, body)
+	r.Host = host
+	return r
+}
+
+// AssertMatch checks that mux routes req, as understood by [NewRequest], to
+// the route registered with wantPattern, with the wildcard values in
+// wantParams. An empty wantPattern asserts that no route matches.
+// No handler is called.
+func AssertMatch(t testing.TB, mux *shortmux.ServeMux, req, wantPattern string, wantParams map[string]string) {
+	t.Helper()
+	e := mux.Explain(NewRequest(req))
+	if e.Pattern != wantPattern {
+		if e.Pattern == "" {
+			t.Errorf("%s: no match (status %d), want pattern %q", req, e.Status, wantPattern)
+		} else {
+			t.Errorf("%s: matched pattern %q, want %q", req, e.Pattern, wantPattern)
+		}
+		return
+	}
+	params := map[string]string{}
+	for _, p := range e.Params {
+		params[p.Name] = p.Value
+	}
+	if len(wantParams) == 0 && len(params) == 0 {
+		return
+	}
+	if !maps.Equal(params, wantParams) {
+		t.Errorf("%s: got params %v, want %v", req, params, wantParams)
+	}
+}
+
+// AssertRoutes checks the table of routes registered on mux against the
+// golden file at path, or writes the table to the file if the
+// -shortmuxtest.update flag is set.
+func AssertRoutes(t testing.TB, mux *shortmux.ServeMux, path string) {
+	t.Helper()
+	got := RouteTable(mux)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -shortmuxtest.update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("routes differ from %s (run with -shortmuxtest.update to accept them):\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// RouteTable returns the text of the golden files of [AssertRoutes]: a
+// line for each route registered on mux, with its pattern and its budget
+// and class, if any.
+func RouteTable(mux *shortmux.ServeMux) []byte {
+	var b bytes.Buffer
+	for _, r := range mux.Routes() {
+		b.WriteString(r.Pattern)
+		if r.Budget != 0 {
+			fmt.Fprintf(&b, " budget=%s", r.Budget)
+		}
+		if r.Class != "" {
+			fmt.Fprintf(&b, " class=%s", r.Class)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
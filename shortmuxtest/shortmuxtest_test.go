@@ -0,0 +1,90 @@
+package shortmuxtest
+
+import (
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+// recorder records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, format)
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.errors = append(r.errors, format)
+	runtime.Goexit()
+}
+
+// failures runs f with a recorder, and returns the recorded failures.
+func failures(f func(testing.TB)) []string {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.errors
+}
+
+func newTestMux() *shortmux.ServeMux {
+	mux := shortmux.NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("GET /users/{id}", h, shortmux.WithBudget(time.Second))
+	mux.Handle("api.example.com/v1/", h, shortmux.WithClass("api"))
+	mux.Handle("/static/", h)
+	return mux
+}
+
+func TestAssertMatch(t *testing.T) {
+	mux := newTestMux()
+	for _, test := range []struct {
+		req, pattern string
+		params       map[string]string
+		wantFail     bool
+	}{
+		{"GET /users/3", "GET /users/{id}", map[string]string{"id": "3"}, false},
+		{"/users/3", "GET /users/{id}", map[string]string{"id": "3"}, false},
+		{"GET /users/3", "GET /users/{id}", map[string]string{"id": "4"}, true},
+		{"POST api.example.com/v1/x", "api.example.com/v1/", nil, false},
+		{"GET /static/a.css", "/static/", nil, false},
+		{"GET /nope", "", nil, false},
+		{"GET /nope", "/static/", nil, true},
+		{"POST /users/3", "GET /users/{id}", nil, true},
+	} {
+		errs := failures(func(tb testing.TB) { AssertMatch(tb, mux, test.req, test.pattern, test.params) })
+		if failed := len(errs) > 0; failed != test.wantFail {
+			t.Errorf("AssertMatch(%q, %q, %v): failed = %t, want %t", test.req, test.pattern, test.params, failed, test.wantFail)
+		}
+	}
+}
+
+func TestAssertRoutes(t *testing.T) {
+	mux := newTestMux()
+	AssertRoutes(t, mux, "testdata/routes.golden")
+
+	mux.Handle("/new", http.NotFoundHandler())
+	errs := failures(func(tb testing.TB) { AssertRoutes(tb, mux, "testdata/routes.golden") })
+	if len(errs) != 1 {
+		t.Errorf("after adding a route: got %d failures, want 1", len(errs))
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.golden")
+	errs = failures(func(tb testing.TB) { AssertRoutes(tb, mux, missing) })
+	if len(errs) != 1 || !strings.Contains(errs[0], "shortmuxtest.update") {
+		t.Errorf("missing golden file: got failures %q", errs)
+	}
+}
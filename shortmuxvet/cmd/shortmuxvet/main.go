@@ -0,0 +1,13 @@
+// The shortmuxvet command checks the patterns registered on
+// shortmux.ServeMux values; see package [shortmuxvet].
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/henvic/shortmux/shortmuxvet"
+)
+
+func main() {
+	singlechecker.Main(shortmuxvet.Analyzer)
+}
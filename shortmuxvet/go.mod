@@ -0,0 +1,16 @@
+module github.com/henvic/shortmux/shortmuxvet
+
+go 1.24.5
+
+require (
+	github.com/henvic/shortmux v0.0.0
+	golang.org/x/tools v0.42.0
+)
+
+require (
+	golang.org/x/mod v0.33.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+)
+
+// The analyzer is developed along with the mux.
+replace github.com/henvic/shortmux => ../
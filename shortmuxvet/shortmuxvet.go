@@ -0,0 +1,278 @@
+// Package shortmuxvet defines an [analysis.Analyzer] checking the patterns
+// registered on a [shortmux.ServeMux] with constant strings.
+//
+// It reports patterns that don't parse, patterns repeating a wildcard
+// name, and patterns conflicting with another registered on the same mux
+// in the same file, all of which would otherwise only be found when the
+// registration panics at run time.
+//
+// The analyzer is a module of its own, so that the mux doesn't depend on
+// golang.org/x/tools, developed along with the mux in the same repository.
+// It can be run on its own with the shortmuxvet command, installed from a
+// checkout of the repository:
+//
+//	cd shortmuxvet && go install ./cmd/shortmuxvet
+//	shortmuxvet ./...
+//
+// Patterns are checked as the mux configuration set in the same file
+// allows: FoldHosts set to a constant, and WildcardName, whose names are
+// all accepted. The variants of HandleLocalized patterns are checked for
+// each constant locale.
+package shortmuxvet
+
+import (
+	"errors"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/henvic/shortmux"
+)
+
+// Analyzer checks the patterns registered on a shortmux.ServeMux.
+var Analyzer = &analysis.Analyzer{
+	Name:     "shortmuxvet",
+	Doc:      "check patterns registered on shortmux.ServeMux for parse errors, repeated wildcard names and conflicts",
+	URL:      "https://pkg.go.dev/github.com/henvic/shortmux/shortmuxvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+const pkgPath = "github.com/henvic/shortmux"
+
+// registrations are the ServeMux methods taking a pattern as their first
+// argument.
+var registrations = map[string]bool{
+	"Handle":          true,
+	"HandleFunc":      true,
+	"HandleLazy":      true,
+	"HandleLocalized": true,
+}
+
+// muxKey identifies a mux within a file: the variable or field holding it,
+// or the expression it is obtained with.
+type muxKey struct {
+	file *token.File
+	obj  types.Object
+	expr string
+}
+
+// keyOf returns the key of the mux x, used at pos.
+func keyOf(pass *analysis.Pass, x ast.Expr, pos token.Pos) muxKey {
+	key := muxKey{file: pass.Fset.File(pos), expr: types.ExprString(x)}
+	if id, ok := ast.Unparen(x).(*ast.Ident); ok {
+		key.obj, key.expr = pass.TypesInfo.ObjectOf(id), ""
+	} else if s, ok := ast.Unparen(x).(*ast.SelectorExpr); ok && pass.TypesInfo.Uses[s.Sel] != nil {
+		if x, ok := s.X.(*ast.Ident); ok && pass.TypesInfo.Uses[x] != nil {
+			// Distinguish the same field of different variables.
+			key.expr = x.Name
+		}
+		key.obj = pass.TypesInfo.Uses[s.Sel]
+	}
+	return key
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	muxes := map[muxKey]*shortmux.ServeMux{}
+	muxOf := func(key muxKey) *shortmux.ServeMux {
+		mux := muxes[key]
+		if mux == nil {
+			mux = shortmux.NewServeMux()
+			muxes[key] = mux
+		}
+		return mux
+	}
+	insp.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			configure(pass, n, muxOf)
+		case *ast.CallExpr:
+			check(pass, n, muxOf)
+		}
+	})
+	return nil, nil
+}
+
+// configure applies to the muxes assigned by as the configuration fields
+// affecting the parsing and conflicts of patterns, as in
+//
+//	mux.FoldHosts = true
+//	mux := &shortmux.ServeMux{FoldHosts: true}
+func configure(pass *analysis.Pass, as *ast.AssignStmt, muxOf func(muxKey) *shortmux.ServeMux) {
+	if len(as.Lhs) != len(as.Rhs) {
+		return
+	}
+	for i, lhs := range as.Lhs {
+		rhs := ast.Unparen(as.Rhs[i])
+		if sel, ok := ast.Unparen(lhs).(*ast.SelectorExpr); ok && isMuxField(pass, sel) {
+			setField(pass, muxOf(keyOf(pass, sel.X, sel.Pos())), sel.Sel.Name, rhs)
+			continue
+		}
+		if u, ok := rhs.(*ast.UnaryExpr); ok && u.Op == token.AND {
+			rhs = ast.Unparen(u.X)
+		}
+		lit, ok := rhs.(*ast.CompositeLit)
+		if !ok || !isServeMux(pass.TypesInfo.TypeOf(lit)) {
+			continue
+		}
+		mux := muxOf(keyOf(pass, lhs, lhs.Pos()))
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if id, ok := kv.Key.(*ast.Ident); ok {
+					setField(pass, mux, id.Name, ast.Unparen(kv.Value))
+				}
+			}
+		}
+	}
+}
+
+// setField sets the configuration field name of mux to the value of v, if
+// it is known. A WildcardName function, which can't be evaluated, accepts
+// every name, so as not to report valid patterns.
+func setField(pass *analysis.Pass, mux *shortmux.ServeMux, name string, v ast.Expr) {
+	switch name {
+	case "FoldHosts":
+		if tv := pass.TypesInfo.Types[v]; tv.Value != nil && tv.Value.Kind() == constant.Bool {
+			mux.FoldHosts = constant.BoolVal(tv.Value)
+		}
+	case "WildcardName":
+		if pass.TypesInfo.Types[v].IsNil() {
+			mux.WildcardName = nil
+		} else {
+			mux.WildcardName = func(string) bool { return true }
+		}
+	}
+}
+
+// isMuxField reports whether sel selects a field of a shortmux.ServeMux.
+func isMuxField(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	s := pass.TypesInfo.Selections[sel]
+	return s != nil && s.Kind() == types.FieldVal && isServeMux(s.Recv())
+}
+
+// check reports the errors of the pattern registered by call, if it is a
+// registration with a constant pattern.
+func check(pass *analysis.Pass, call *ast.CallExpr, muxOf func(muxKey) *shortmux.ServeMux) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) == 0 {
+		return
+	}
+	fn := typeutil.Callee(pass.TypesInfo, call)
+	if !isRegistration(fn) {
+		return
+	}
+	arg := call.Args[0]
+	pattern, ok := constString(pass, arg)
+	if !ok {
+		return
+	}
+	mux := muxOf(keyOf(pass, sel.X, call.Pos()))
+	register := func(pattern string) error {
+		return firstError(mux.Apply([]shortmux.RouteChange{{
+			Pattern:  pattern,
+			Handler:  http.NotFoundHandler(),
+			Location: pass.Fset.Position(arg.Pos()).String(),
+		}}))
+	}
+
+	if fn.Name() == "HandleLocalized" && len(call.Args) > 2 && !call.Ellipsis.IsValid() {
+		// The variants for each locale are registered before the
+		// pattern, as HandleLocalized does.
+		if i := strings.IndexByte(pattern, '/'); i >= 0 {
+			for _, a := range call.Args[2:] {
+				l, ok := constString(pass, a)
+				if !ok {
+					continue
+				}
+				if err := register(pattern[:i] + "/" + url.PathEscape(l) + pattern[i:]); err != nil {
+					pass.Reportf(a.Pos(), "invalid pattern for locale %q: %v", l, err)
+				}
+			}
+		}
+	}
+	if err := register(pattern); err != nil {
+		pass.Reportf(arg.Pos(), "invalid pattern: %v", err)
+		return
+	}
+	if name := repeatedName(pattern); name != "" {
+		pass.Reportf(arg.Pos(), "pattern %q repeats wildcard name %q; Request.PathValue returns only its last value (use shortmux.PathValues for all of them)", pattern, name)
+	}
+}
+
+// constString returns the value of e, if it is a constant string.
+func constString(pass *analysis.Pass, e ast.Expr) (string, bool) {
+	tv, ok := pass.TypesInfo.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// firstError returns the error of the single change applied by
+// ServeMux.Apply, without the change and its location, which the
+// diagnostic gives.
+func firstError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		err = j.Unwrap()[0]
+	}
+	if u := errors.Unwrap(err); u != nil {
+		err = u
+	}
+	return err
+}
+
+// isRegistration reports whether obj is a pattern registration method of
+// shortmux.ServeMux.
+func isRegistration(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != pkgPath || !registrations[fn.Name()] {
+		return false
+	}
+	recv := fn.Signature().Recv()
+	return recv != nil && isServeMux(recv.Type())
+}
+
+// isServeMux reports whether t is shortmux.ServeMux or a pointer to it.
+func isServeMux(t types.Type) bool {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == "ServeMux" && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkgPath
+}
+
+// repeatedName returns the first wildcard name appearing more than once in
+// pattern, which must be valid, or "".
+func repeatedName(pattern string) string {
+	seen := map[string]bool{}
+	for _, seg := range strings.Split(pattern, "/") {
+		name, ok := strings.CutPrefix(seg, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(name, "}")
+		name, _, _ = strings.Cut(name, ":")
+		name = strings.TrimSuffix(name, "...")
+		if name == "$" {
+			continue
+		}
+		if seen[name] {
+			return name
+		}
+		seen[name] = true
+	}
+	return ""
+}
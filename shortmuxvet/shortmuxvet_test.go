@@ -0,0 +1,13 @@
+package shortmuxvet_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/henvic/shortmux/shortmuxvet"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), shortmuxvet.Analyzer, "a")
+}
@@ -0,0 +1,57 @@
+package a
+
+import (
+	"net/http"
+
+	"github.com/henvic/shortmux"
+)
+
+const usersPattern = "GET /users/{id}"
+
+type server struct {
+	mux *shortmux.ServeMux
+}
+
+func register(h http.Handler, f func(http.ResponseWriter, *http.Request)) {
+	mux := shortmux.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle(usersPattern, h)
+	mux.Handle("/{x", h)               // want `invalid pattern: parsing "/\{x": at offset 1: bad wildcard segment`
+	mux.Handle("GET /users/{name}", h) // want `matches the same requests as "GET /users/\{name\}"`
+	mux.HandleFunc("/", f)             // want `exact pattern already registered at .*a.go:17`
+	mux.Handle("/{a}/x/{a}", h)        // want `repeats wildcard name "a"`
+	mux.Handle("/e/{f:(json|xml)}", h) // ok
+	mux.Handle("/e/{g:(csv|tsv)}", h)  // ok: disjoint
+	mux.Handle("/e/{k:(xml)}", h)      // want `remove "xml" from the literals of \{k\}`
+
+	other := shortmux.NewServeMux()
+	other.Handle("/", h) // ok: different mux
+
+	s1, s2 := server{mux: other}, server{mux: other}
+	s1.mux.Handle("/s", h)
+	s2.mux.Handle("/s", h) // ok: can't tell
+	s1.mux.Handle("/s", h) // want `exact pattern already registered`
+
+	folded := shortmux.NewServeMux()
+	folded.FoldHosts = true
+	folded.Handle("Example.com/", h)
+	folded.Handle("example.com/", h) // want `already registered|matches the same requests`
+	other.Handle("Example.com/", h)
+	other.Handle("example.com/", h) // ok: hosts aren't folded
+
+	lit := &shortmux.ServeMux{FoldHosts: true}
+	lit.Handle("Example.com/", h)
+	lit.Handle("example.com/", h) // want `already registered|matches the same requests`
+
+	named := shortmux.NewServeMux()
+	named.WildcardName = func(string) bool { return true }
+	named.Handle("/{a%3Ab}", h) // ok: custom wildcard names
+	other.Handle("/{a%3Ab}", h) // want `invalid pattern`
+
+	mux.HandleLocalized("GET /about", h, "en", "pt")
+	mux.Handle("GET /pt/about", h)          // want `exact pattern already registered`
+	mux.HandleLocalized("GET /{x", h, "en") // want `invalid pattern for locale "en"` `invalid pattern`
+
+	pattern := "/{" // not constant
+	mux.Handle(pattern, h)
+}
@@ -0,0 +1,20 @@
+// Package shortmux is a stub of the real package, for type checking.
+package shortmux
+
+import "net/http"
+
+type ServeMux struct {
+	WildcardName func(name string) bool
+	FoldHosts    bool
+}
+
+type RouteOption func()
+
+func NewServeMux() *ServeMux { return nil }
+
+func (*ServeMux) Handle(pattern string, handler http.Handler, opts ...RouteOption) {}
+
+func (*ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request), opts ...RouteOption) {
+}
+
+func (*ServeMux) HandleLocalized(pattern string, handler http.Handler, locales ...string) {}
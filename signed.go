@@ -0,0 +1,84 @@
+package shortmux
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The query parameters of signed URLs.
+const (
+	expiresParam   = "expires"
+	signatureParam = "signature"
+)
+
+// WithSignedURL restricts the route to requests for URLs signed with key
+// by [ServeMux.SignedURL] that haven't expired, such as time-limited
+// download links. Other requests are answered with 403 Forbidden.
+//
+// The signature covers the path and the query of the URL, so neither can
+// be changed, but not the host.
+func WithSignedURL(key []byte) RouteOption {
+	if len(key) == 0 {
+		panic("shortmux: empty signing key")
+	}
+	return func(c *routeConfig) {
+		c.signKey = key
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !verifySigned(key, r.URL, nowFor(r.Context())) {
+					mux.Error(w, r, http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
+
+// SignedURL returns the URL of the route named name, as [ServeMux.URL]
+// does, signed with the key of the route set with [WithSignedURL] and
+// valid for ttl.
+func (mux *ServeMux) SignedURL(name string, ttl time.Duration, params ...string) (*url.URL, error) {
+	rt := mux.named(name)
+	if rt == nil {
+		return nil, fmt.Errorf("shortmux: no route named %q", name)
+	}
+	if rt.cfg.signKey == nil {
+		return nil, fmt.Errorf("shortmux: route %q has no signing key", name)
+	}
+	u, err := rt.pat.build(params)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{expiresParam: {strconv.FormatInt(mux.now().Add(ttl).Unix(), 10)}}
+	q.Set(signatureParam, signature(rt.cfg.signKey, u.EscapedPath(), q))
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// verifySigned reports whether u has a valid signature made with key that
+// hasn't expired at now.
+func verifySigned(key []byte, u *url.URL, now time.Time) bool {
+	q := u.Query()
+	sig := q.Get(signatureParam)
+	exp, err := strconv.ParseInt(q.Get(expiresParam), 10, 64)
+	if sig == "" || err != nil || now.Unix() > exp {
+		return false
+	}
+	q.Del(signatureParam)
+	return hmac.Equal([]byte(sig), []byte(signature(key, u.EscapedPath(), q)))
+}
+
+// signature returns the signature of the path and query q with key.
+func signature(key []byte, path string, q url.Values) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(path + "?" + q.Encode()))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,50 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	key := []byte("secret")
+	mux := NewServeMux()
+	mux.HandleFunc("GET /downloads/{file...}", func(w http.ResponseWriter, r *http.Request) {},
+		WithName("download"), WithSignedURL(key))
+	mux.HandleFunc("GET /public", func(w http.ResponseWriter, r *http.Request) {}, WithName("public"))
+
+	get := func(target string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w.Code
+	}
+	u, err := mux.SignedURL("download", time.Hour, "file", "reports/q1.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(u.String()); code != 200 {
+		t.Errorf("%v: status %d", u, code)
+	}
+	if code := get("/downloads/reports/q1.pdf"); code != 403 {
+		t.Errorf("unsigned: status %d", code)
+	}
+	tampered := *u
+	tampered.Path, tampered.RawPath = "/downloads/reports/q2.pdf", ""
+	if code := get(tampered.String()); code != 403 {
+		t.Errorf("tampered path: status %d", code)
+	}
+	extra := *u
+	extra.RawQuery += "&x=1"
+	if code := get(extra.String()); code != 403 {
+		t.Errorf("extra query: status %d", code)
+	}
+
+	expired, _ := mux.SignedURL("download", -time.Minute, "file", "reports/q1.pdf")
+	if code := get(expired.String()); code != 403 {
+		t.Errorf("expired: status %d", code)
+	}
+	if _, err := mux.SignedURL("public", time.Hour); err == nil {
+		t.Error("signed URL for a route without a signing key")
+	}
+}
@@ -0,0 +1,222 @@
+// Package sitemap generates robots.txt and sitemap.xml documents from the
+// routes registered on a [shortmux.ServeMux].
+//
+// Routes opt in with the [Public] and [Private] route options:
+//
+//	mux.HandleFunc("GET /{$}", home, sitemap.Public(sitemap.Daily, 1.0))
+//	mux.HandleFunc("GET /about", about, sitemap.Public(sitemap.Monthly, 0.5))
+//	mux.Handle("/admin/", admin, sitemap.Private())
+//	mux.Handle("GET /sitemap.xml", sitemap.Handler(mux, "https://example.com"))
+//	mux.Handle("GET /robots.txt", sitemap.RobotsHandler(mux, "https://example.com/sitemap.xml"))
+//
+// Both documents are generated from [shortmux.ServeMux.Routes] on every
+// request, so they always reflect the current routing table.
+package sitemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/henvic/shortmux"
+)
+
+// A ChangeFreq is how frequently a page is likely to change.
+type ChangeFreq string
+
+// The change frequencies defined by the sitemaps protocol.
+const (
+	Always  ChangeFreq = "always"
+	Hourly  ChangeFreq = "hourly"
+	Daily   ChangeFreq = "daily"
+	Weekly  ChangeFreq = "weekly"
+	Monthly ChangeFreq = "monthly"
+	Yearly  ChangeFreq = "yearly"
+	Never   ChangeFreq = "never"
+)
+
+// metaKey is the route metadata key for an entry.
+type metaKey struct{}
+
+// entry is the sitemap metadata of a route.
+type entry struct {
+	private    bool
+	changeFreq ChangeFreq
+	priority   float64
+}
+
+// Public lists the route in the sitemap with the given change frequency and
+// priority, between 0 and 1. A negative priority omits it.
+//
+// Only routes that serve GET requests and whose path has no wildcards,
+// other than a final {$}, can be listed.
+func Public(changeFreq ChangeFreq, priority float64) shortmux.RouteOption {
+	if priority > 1 {
+		panic(fmt.Sprintf("sitemap: priority %v out of range", priority))
+	}
+	return shortmux.WithMetadata(metaKey{}, entry{changeFreq: changeFreq, priority: priority})
+}
+
+// Private disallows crawling the route in robots.txt.
+func Private() shortmux.RouteOption {
+	return shortmux.WithMetadata(metaKey{}, entry{private: true})
+}
+
+type urlset struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []urlEntry
+}
+
+type urlEntry struct {
+	XMLName    xml.Name   `xml:"url"`
+	Loc        string     `xml:"loc"`
+	ChangeFreq ChangeFreq `xml:"changefreq,omitempty"`
+	Priority   string     `xml:"priority,omitempty"`
+}
+
+// Write writes the sitemap of the public routes of mux to w.
+// The locations are resolved against baseURL, e.g. "https://example.com".
+// Routes bound to a host other than the host of baseURL are skipped.
+func Write(w io.Writer, mux *shortmux.ServeMux, baseURL string) error {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	var set urlset
+	for _, rt := range mux.Routes() {
+		e, ok := rt.Value(metaKey{}).(entry)
+		if !ok || e.private || (rt.Method != "" && rt.Method != "GET") ||
+			(rt.Host != "" && rt.Host != base.Hostname()) {
+			continue
+		}
+		p, ok := literalPath(rt.Path)
+		if !ok {
+			continue
+		}
+		loc, ok := locURL(base, p)
+		if !ok {
+			continue
+		}
+		u := urlEntry{Loc: loc, ChangeFreq: e.changeFreq}
+		if e.priority >= 0 {
+			u.Priority = fmt.Sprintf("%.1f", e.priority)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// WriteRobots writes a robots.txt for all user agents to w, disallowing
+// the private routes of mux, and pointing to sitemapURL, if not empty.
+func WriteRobots(w io.Writer, mux *shortmux.ServeMux, sitemapURL string) error {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	seen := map[string]bool{}
+	for _, rt := range mux.Routes() {
+		if e, ok := rt.Value(metaKey{}).(entry); !ok || !e.private {
+			continue
+		}
+		rule := robotsRule(rt.Path)
+		if !seen[rule] {
+			seen[rule] = true
+			fmt.Fprintf(&b, "Disallow: %s\n", rule)
+		}
+	}
+	if len(seen) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	if sitemapURL != "" {
+		fmt.Fprintf(&b, "\nSitemap: %s\n", sitemapURL)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Handler returns a handler serving the sitemap written by [Write].
+func Handler(mux *shortmux.ServeMux, baseURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		if err := Write(&b, mux, baseURL); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		io.WriteString(w, b.String())
+	})
+}
+
+// RobotsHandler returns a handler serving the robots.txt written by
+// [WriteRobots].
+func RobotsHandler(mux *shortmux.ServeMux, sitemapURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		WriteRobots(w, mux, sitemapURL)
+	})
+}
+
+// literalPath returns the path matched by the pattern path p, if p has no
+// wildcards. For subtrees, it is the root of the subtree.
+func literalPath(p string) (string, bool) {
+	p = strings.TrimSuffix(p, "{$}")
+	return p, !strings.Contains(p, "{")
+}
+
+// robotsEscaper escapes the characters with special meanings in robots.txt
+// rules.
+var robotsEscaper = strings.NewReplacer("*", "%2A", "$", "%24")
+
+// locURL returns the URL of the path p, in its escaped form as in
+// patterns, on base. It reports false if p is not validly escaped.
+func locURL(base *url.URL, p string) (string, bool) {
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return "", false
+	}
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + unescaped
+	// Keep the escaping of p, e.g. of "%2F".
+	u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + p
+	return u.String(), true
+}
+
+// robotsRule returns the robots.txt path rule (RFC 9309) matching the same
+// paths as the pattern path p: wildcards become "*", and paths that don't
+// end in a multi wildcard or a trailing slash end in "$". A multi wildcard
+// followed by more segments, as in "/files/{p...}/secret", also becomes
+// "*", which matches across slashes. Literal "*" and "$" are escaped, as
+// rules give them special meanings.
+func robotsRule(p string) string {
+	p, exact := strings.CutSuffix(p, "{$}")
+	segs := strings.Split(p, "/")
+	multi := strings.HasSuffix(p, "/") && !exact
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			switch {
+			case strings.HasSuffix(seg, "...}") && i == len(segs)-1:
+				multi = true
+				segs[i] = ""
+			default:
+				segs[i] = "*"
+			}
+			continue
+		}
+		segs[i] = robotsEscaper.Replace(seg)
+	}
+	rule := strings.Join(segs, "/")
+	if !multi {
+		rule += "$"
+	}
+	return rule
+}
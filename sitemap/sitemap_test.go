@@ -0,0 +1,98 @@
+package sitemap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+func TestSitemap(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux := shortmux.NewServeMux()
+	mux.Handle("GET /{$}", h, Public(Daily, 1))
+	mux.Handle("/about", h, Public(Monthly, -1))
+	mux.Handle("GET /docs/", h, Public(Weekly, 0.5))
+	mux.Handle("GET /users/{id}", h, Public(Daily, 0.5)) // not listable
+	mux.Handle("POST /form", h, Public(Daily, 0.5))      // not GET
+	mux.Handle("other.com/x", h, Public(Daily, 0.5))     // other host
+	mux.Handle("/admin/", h, Private())
+	mux.Handle("/users/{id}/edit", h, Private())
+	mux.Handle("/secret/{$}", h, Private())
+	mux.Handle("/files/{p...}/secret", h, Private())
+	mux.Handle("/plain", h)
+	mux.Handle("GET /a%20b", h, Public(Daily, -1))
+	mux.Handle("GET /c%2Fd", h, Public(Daily, -1))
+	mux.Handle("GET /sitemap.xml", Handler(mux, "https://example.com"))
+	mux.Handle("GET /robots.txt", RobotsHandler(mux, "https://example.com/sitemap.xml"))
+
+	for _, test := range []struct {
+		path string
+		want string
+	}{
+		{"/sitemap.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <changefreq>daily</changefreq>
+    <priority>1.0</priority>
+  </url>
+  <url>
+    <loc>https://example.com/a%20b</loc>
+    <changefreq>daily</changefreq>
+  </url>
+  <url>
+    <loc>https://example.com/about</loc>
+    <changefreq>monthly</changefreq>
+  </url>
+  <url>
+    <loc>https://example.com/c%2Fd</loc>
+    <changefreq>daily</changefreq>
+  </url>
+  <url>
+    <loc>https://example.com/docs/</loc>
+    <changefreq>weekly</changefreq>
+    <priority>0.5</priority>
+  </url>
+</urlset>
+`},
+		{"/robots.txt", `User-agent: *
+Disallow: /admin/
+Disallow: /files/*/secret$
+Disallow: /secret/$
+Disallow: /users/*/edit$
+
+Sitemap: https://example.com/sitemap.xml
+`},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "https://example.com"+test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.path, got, test.want)
+		}
+	}
+}
+
+func TestRobotsRule(t *testing.T) {
+	for _, test := range []struct {
+		pattern, want string
+	}{
+		{"/a", "/a$"},
+		{"/a/", "/a/"},
+		{"/a/{$}", "/a/$"},
+		{"/a/{b}/{$}", "/a/*/$"},
+		{"/a/{b...}", "/a/"},
+		{"/a/{b:(x|y)}", "/a/*$"},
+		{"/files/{p...}/secret", "/files/*/secret$"},
+		{"/files/{p...}/{id}", "/files/*/*$"},
+		{"/files/{p...}/raw/", "/files/*/raw/"},
+		{"/files/{p...}/raw/{q...}", "/files/*/raw/"},
+		{"/files/{p...}/raw/{$}", "/files/*/raw/$"},
+		{"/a*b/{c}/$x", "/a%2Ab/*/%24x$"},
+	} {
+		if got := robotsRule(test.pattern); got != test.want {
+			t.Errorf("robotsRule(%q) = %q, want %q", test.pattern, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package shortmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithSlashAlias also registers the route under its slash alias: the
+// pattern without its trailing "/", "/{$}" or "/{name...}" segment, or,
+// if it has none, with a trailing "/" added. For example, "GET /docs"
+// also registers "GET /docs/", and "/files/{path...}" also registers
+// "/files". Both patterns are registered, and removed, together: if
+// either conflicts with a registered pattern, neither is registered.
+//
+// The alias serves requests as the route does, but is listed separately
+// by [ServeMux.Routes], and can't be removed on its own. A route name set
+// with [WithName] refers to the route, not the alias. Patterns whose path
+// is "/" have no alias.
+func WithSlashAlias() RouteOption {
+	return func(c *routeConfig) {
+		c.slashAlias = true
+	}
+}
+
+// slashAlias returns the slash alias of the pattern s, as documented by
+// WithSlashAlias.
+func slashAlias(s string) (string, error) {
+	i := strings.IndexByte(s, '/')
+	prefix, path := s[:i], s[i:]
+	if path == "/" {
+		return "", fmt.Errorf("pattern %q has no slash alias", s)
+	}
+	j := strings.LastIndexByte(path, '/')
+	switch last := path[j+1:]; {
+	case last == "", last == "{$}", strings.HasPrefix(last, "{") && strings.HasSuffix(last, "...}"):
+		if j == 0 {
+			return "", fmt.Errorf("pattern %q has no slash alias", s)
+		}
+		return prefix + path[:j], nil
+	}
+	return s + "/", nil
+}
+
+// newAlias returns the slash alias route of rt, sharing its handlers.
+func (mux *ServeMux) newAlias(rt *route) (*route, error) {
+	s, err := slashAlias(rt.pat.str)
+	if err != nil {
+		return nil, err
+	}
+	pat, err := mux.parsePattern(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing slash alias %q: %w", s, err)
+	}
+	cfg := *rt.cfg
+	cfg.pat, cfg.name, cfg.slashAlias = pat, "", false
+	return &route{pat: pat, handler: rt.handler, wrapped: rt.wrapped, cfg: &cfg, aliasOf: rt}, nil
+}
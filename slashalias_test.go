@@ -0,0 +1,83 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestSlashAlias(t *testing.T) {
+	for _, test := range []struct {
+		pat, want string
+	}{
+		{"/a", "/a/"},
+		{"GET example.com/a/b", "GET example.com/a/b/"},
+		{"/a/", "/a"},
+		{"/a/{$}", "/a"},
+		{"/a/{x...}", "/a"},
+		{"/a/{x}", "/a/{x}/"},
+		{"/", ""},
+		{"/{x...}", ""},
+	} {
+		got, err := slashAlias(test.pat)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%q: got %q, want error", test.pat, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%q: got %q, %v, want %q", test.pat, got, err, test.want)
+		}
+	}
+}
+
+func TestWithSlashAlias(t *testing.T) {
+	mux := NewServeMux()
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "docs")
+	})
+	mux.Handle("GET /docs", h, WithSlashAlias(), WithName("docs"))
+	for _, target := range []string{"/docs", "/docs/", "/docs/x"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != 200 || w.Body.String() != "docs" {
+			t.Errorf("%s: got %d %q", target, w.Code, w.Body)
+		}
+	}
+	var pats []string
+	for _, rt := range mux.Routes() {
+		pats = append(pats, rt.Pattern)
+	}
+	slices.Sort(pats)
+	if want := []string{"GET /docs", "GET /docs/"}; !slices.Equal(pats, want) {
+		t.Errorf("got routes %q, want %q", pats, want)
+	}
+
+	// A conflicting alias registers neither pattern.
+	mux.Handle("/b/", h)
+	if err := mux.registerErr("/b", h, WithSlashAlias()); err == nil {
+		t.Error("got no error for a conflicting alias")
+	}
+	if n := len(mux.Routes()); n != 3 {
+		t.Errorf("got %d routes, want 3", n)
+	}
+
+	// The alias is removed with the route, and not on its own.
+	if err := mux.Remove("GET /docs/"); err == nil {
+		t.Error("removing the alias: got no error")
+	}
+	if err := mux.Remove("GET /docs"); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+	if w.Code != 404 {
+		t.Errorf("after removal, got %d, want 404", w.Code)
+	}
+	if n := len(mux.Routes()); n != 1 {
+		t.Errorf("after removal, got %d routes, want 1", n)
+	}
+}
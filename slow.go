@@ -0,0 +1,61 @@
+package shortmux
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// A SlowRequest describes a request whose handler has been running for
+// longer than the threshold set on its route with [WithSlowThreshold].
+type SlowRequest struct {
+	Pattern   string            // matched pattern
+	Method    string            // request method
+	Path      string            // request path
+	Params    map[string]string // wildcard values, by name
+	Threshold time.Duration     // threshold of the route
+	Start     time.Time         // when the handler started
+}
+
+// WithSlowThreshold reports requests whose handler runs for longer than d.
+// The report is made as soon as d elapses, while the handler is still
+// running, and the request is not interrupted.
+//
+// Reports go to the mux OnSlowRequest hook, or are logged with
+// [slog.Default] if it is nil.
+func WithSlowThreshold(d time.Duration) RouteOption {
+	if d <= 0 {
+		panic(fmt.Sprintf("shortmux: invalid slow threshold %v", d))
+	}
+	return func(c *routeConfig) {
+		mux, names := c.mux, c.pat.wildcardNames()
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Take a snapshot, as the handler may modify r concurrently
+				// with the report.
+				e := &SlowRequest{
+					Pattern:   r.Pattern,
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Params:    make(map[string]string, len(names)),
+					Threshold: d,
+					Start:     time.Now(),
+				}
+				for _, name := range names {
+					e.Params[name] = r.PathValue(name)
+				}
+				t := time.AfterFunc(d, func() {
+					if mux.OnSlowRequest != nil {
+						mux.OnSlowRequest(e)
+						return
+					}
+					slog.Warn("shortmux: slow request", "pattern", e.Pattern, "method", e.Method,
+						"path", e.Path, "params", e.Params, "threshold", e.Threshold)
+				})
+				defer t.Stop()
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithSlowThreshold(t *testing.T) {
+	slow := make(chan *SlowRequest, 1)
+	mux := NewServeMux()
+	mux.OnSlowRequest = func(e *SlowRequest) { slow <- e }
+	mux.HandleFunc("/reports/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("slow") {
+			<-slow // wait for the report, and put it back
+			slow <- &SlowRequest{Pattern: "seen"}
+		}
+	}, WithSlowThreshold(20*time.Millisecond))
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/1", nil))
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case e := <-slow:
+		t.Fatalf("unexpected report %+v", e)
+	default:
+	}
+
+	mux.OnSlowRequest = func(e *SlowRequest) {
+		if e.Pattern != "/reports/{id}" || e.Params["id"] != "2" || e.Threshold != 20*time.Millisecond {
+			t.Errorf("got %+v", e)
+		}
+		slow <- e
+	}
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports/2?slow", nil))
+	if e := <-slow; e.Pattern != "seen" {
+		t.Errorf("handler did not observe the report")
+	}
+}
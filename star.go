@@ -0,0 +1,41 @@
+package shortmux
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ServerOptionsHandler returns a handler for "OPTIONS *" requests, asking
+// about the capabilities of the server rather than of a resource, to be
+// set as [ServeMux.ServerOptions]. It replies with 200 OK and an Allow
+// header listing the methods of all the registered patterns, including
+// HEAD for GET patterns that match it, and OPTIONS. Patterns with no
+// method, which match any method, don't contribute to the list.
+func (mux *ServeMux) ServerOptionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(mux.allMethods(), ", "))
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// allMethods returns the sorted methods of all the registered patterns, as
+// described in ServerOptionsHandler.
+func (mux *ServeMux) allMethods() []string {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	ms := []string{http.MethodOptions}
+	for _, rt := range mux.routes {
+		m := rt.pat.method
+		if m == "" {
+			continue
+		}
+		ms = append(ms, m)
+		if m == http.MethodGet && (rt.cfg.head == headAllow || rt.cfg.head == headDefault && !mux.StrictHEAD) {
+			ms = append(ms, http.MethodHead)
+		}
+	}
+	slices.Sort(ms)
+	return slices.Compact(ms)
+}
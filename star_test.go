@@ -0,0 +1,42 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerOptions(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	mux.Handle("GET /a", h)
+	mux.Handle("POST /a", h)
+	mux.Handle("GET /b", h, WithImplicitHEAD(false))
+	mux.Handle("DELETE example.com/c/{x}", h)
+	mux.Handle("/d", h)
+
+	serve := func(method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		r.RequestURI = "*"
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	if w := serve("OPTIONS"); w.Code != http.StatusBadRequest {
+		t.Errorf("without ServerOptions: got %d, want 400", w.Code)
+	}
+
+	mux.ServerOptions = mux.ServerOptionsHandler()
+	w := serve("OPTIONS")
+	if want := "DELETE, GET, HEAD, OPTIONS, POST"; w.Code != 200 || w.Header().Get("Allow") != want {
+		t.Errorf("got %d, Allow %q, want 200, %q", w.Code, w.Header().Get("Allow"), want)
+	}
+	if w := serve("GET"); w.Code != http.StatusBadRequest {
+		t.Errorf("GET *: got %d, want 400", w.Code)
+	}
+
+	mux.StrictHEAD = true
+	if w, want := serve("OPTIONS"), "DELETE, GET, OPTIONS, POST"; w.Header().Get("Allow") != want {
+		t.Errorf("with StrictHEAD: got Allow %q, want %q", w.Header().Get("Allow"), want)
+	}
+}
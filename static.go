@@ -0,0 +1,96 @@
+package shortmux
+
+import "strings"
+
+// staticRoutes indexes the leaves of the patterns with a method and a
+// literal path, such as "GET /healthz" or "POST example.com/login", by
+// method, host and path, so that requests for them are matched without
+// walking the routing tree.
+type staticRoutes map[staticKey]*routingNode
+
+type staticKey struct {
+	method, host, path string
+}
+
+// staticPath returns the path matched by p, and whether p is eligible:
+// it has a method, a literal path and no matchers.
+func staticPath(p *pattern, rt *route) (string, bool) {
+	if p.method == "" || rt != nil && len(rt.cfg.matchers) > 0 {
+		return "", false
+	}
+	return literalPath(p)
+}
+
+// literalPath returns the only path matched by p, escaped and cleaned,
+// and whether p has a literal path matched by a single escaped path.
+func literalPath(p *pattern) (string, bool) {
+	var b strings.Builder
+	for i, seg := range p.segments {
+		switch {
+		case seg.wild || seg.enum != nil:
+			return "", false
+		case seg.s == "/" && i == len(p.segments)-1:
+			b.WriteByte('/') // "{$}"
+		case seg.s == "" || strings.ContainsAny(seg.s, "/%"):
+			// Not matched by a path with no escapes.
+			return "", false
+		default:
+			b.WriteByte('/')
+			b.WriteString(seg.s)
+		}
+	}
+	if b.Len() == 0 {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// add indexes the leaf of rt in the tree at root, if eligible.
+func (s *staticRoutes) add(root *routingNode, rt *route) {
+	p := rt.pat
+	path, ok := staticPath(p, rt)
+	if !ok {
+		return
+	}
+	n := root.findChild(p.host)
+	if n != nil {
+		n = n.findChild(p.method)
+	}
+	for _, seg := range p.segments {
+		if n == nil {
+			return
+		}
+		n = n.findChild(seg.s)
+	}
+	if n == nil || n.route != rt {
+		return
+	}
+	if *s == nil {
+		*s = staticRoutes{}
+	}
+	(*s)[staticKey{p.method, p.host, path}] = n
+}
+
+// remove removes rt from the index.
+func (s staticRoutes) remove(rt *route) {
+	if path, ok := staticPath(rt.pat, rt); ok {
+		delete(s, staticKey{rt.pat.method, rt.pat.host, path})
+	}
+}
+
+// lookup returns the leaf matching method, host and path, if it is
+// indexed and the routing tree would also return it: patterns with the
+// host take precedence over those without, so patterns with no host are
+// only returned if root has no patterns with the host.
+func (s staticRoutes) lookup(root *routingNode, method, host, path string) *routingNode {
+	if len(s) == 0 || strings.IndexByte(path, '%') >= 0 {
+		return nil
+	}
+	if n := s[staticKey{method, host, path}]; n != nil || host == "" {
+		return n
+	}
+	if root.findChild(host) != nil || portlessHost(host, method) != host {
+		return nil
+	}
+	return s[staticKey{method, "", path}]
+}
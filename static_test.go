@@ -0,0 +1,82 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStaticRoutes checks that the static fast path matches the same
+// leaves as the routing tree.
+func TestStaticRoutes(t *testing.T) {
+	mux := NewServeMux()
+	h := http.NotFoundHandler()
+	for _, pat := range []string{
+		"GET /a",
+		"GET /a/b",
+		"GET /a/{$}",
+		"GET /{x}",
+		"POST /a",
+		"/c",
+		"GET /c/{$}",
+		"HEAD /h",
+		"GET /%61%2Fb",
+		"GET example.com/a",
+		"GET example.com/d/",
+		"GET other.com/{x}",
+		"GET /q",
+		"GET /m",
+		"GET /k/{k:(v|w)}",
+	} {
+		mux.Handle(pat, h)
+	}
+	mux.Handle("GET /m/{$}", h, WithMatcher(MatchQuery("x", "")))
+
+	// Indexed patterns have a method, a literal path and no matchers.
+	var indexed []string
+	for k, n := range mux.static {
+		indexed = append(indexed, k.method+" "+k.host+k.path+" -> "+n.pattern.String())
+	}
+	if len(indexed) != 9 {
+		t.Errorf("indexed %d patterns, want 9: %q", len(indexed), indexed)
+	}
+
+	for _, host := range []string{"", "example.com", "other.com", "example.com:8080"} {
+		for _, method := range []string{"GET", "HEAD", "POST", "CONNECT"} {
+			for _, path := range []string{"/", "/a", "/a/", "/a/b", "/a%2Fb", "/b", "/c", "/c/", "/d/", "/h", "/m", "/m/", "/q", "/k/v"} {
+				want, _, _ := mux.tree.match(host, method, path, false, nil, nil)
+				got := mux.static.lookup(&mux.tree, method, host, path)
+				if got != nil && got != want {
+					t.Errorf("%s %s%s: fast path matched %s, tree matched %v", method, host, path, got.pattern, want.pattern)
+				}
+			}
+		}
+	}
+
+	if err := mux.Remove("GET /a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := mux.static.lookup(&mux.tree, "GET", "", "/a"); n != nil {
+		t.Errorf("after Remove, fast path matched %s", n.pattern)
+	}
+}
+
+func BenchmarkStaticRoutes(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		mux := NewServeMux()
+		for i := range size {
+			mux.HandleFunc(fmt.Sprintf("GET /api/v1/resource%d/list", i), func(http.ResponseWriter, *http.Request) {})
+		}
+		mux.HandleFunc("GET /api/v1/{kind}/{id}", func(http.ResponseWriter, *http.Request) {})
+		for _, path := range []string{"/api/v1/resource7/list", "/api/v1/other/7"} {
+			b.Run(fmt.Sprintf("routes=%d%s", size, path), func(b *testing.B) {
+				r := httptest.NewRequest("GET", path, nil)
+				b.ReportAllocs()
+				for b.Loop() {
+					mux.findHandler(r)
+				}
+			})
+		}
+	}
+}
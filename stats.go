@@ -0,0 +1,91 @@
+package shortmux
+
+import "unsafe"
+
+// MuxStats describes the size of the routing table of a [ServeMux], for
+// budgeting the memory of muxes, for example one per tenant.
+type MuxStats struct {
+	Routes int // registered routes
+	Hosts  int // distinct hosts of patterns, including no host
+	Nodes  int // nodes of the routing tree, including leaves
+	Leaves int // leaves of the routing tree; a constrained pattern has several
+	Multis int // patterns ending in a multi wildcard, including a trailing slash
+	Static int // literal patterns matched with a hash lookup
+
+	// IndexBuckets counts the buckets of the index used to detect
+	// conflicting patterns, by number of patterns in them. Patterns are
+	// indexed under each of their segments, so large buckets, such as one
+	// for a segment shared by most patterns, slow down registrations.
+	IndexBuckets map[int]int
+
+	// Bytes is an estimate of the memory used by the routing table, not
+	// counting handlers and the values set by route options.
+	Bytes int64
+}
+
+// Stats reports the size of the routing table of mux.
+func (mux *ServeMux) Stats() MuxStats {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	s := MuxStats{
+		Routes:       len(mux.routes),
+		Multis:       len(mux.index.multis),
+		Static:       len(mux.static),
+		IndexBuckets: map[int]int{},
+	}
+	ptr := int64(unsafe.Sizeof(uintptr(0)))
+	mux.tree.stats(&s, 0)
+	for _, rt := range mux.routes {
+		p := rt.pat
+		s.Bytes += int64(unsafe.Sizeof(route{})+unsafe.Sizeof(routeConfig{})+unsafe.Sizeof(*p)) +
+			int64(len(p.str)+len(p.loc)) + int64(cap(p.segments))*int64(unsafe.Sizeof(segment{})) +
+			int64(len(rt.cfg.middleware)+len(rt.cfg.inner)+len(rt.cfg.matchers))*2*ptr
+		for _, seg := range p.segments {
+			s.Bytes += int64(len(seg.s))
+		}
+	}
+	for k, pats := range mux.index.segments {
+		s.IndexBuckets[len(pats)]++
+		s.Bytes += int64(unsafe.Sizeof(k)) + int64(len(k.s)) + int64(cap(pats))*ptr + mapEntryOverhead
+	}
+	s.Bytes += int64(cap(mux.index.multis)+cap(mux.routes)) * ptr
+	s.Bytes += int64(len(mux.static)) * (int64(unsafe.Sizeof(staticKey{})) + ptr + mapEntryOverhead)
+	return s
+}
+
+// mapEntryOverhead is an estimate of the memory used by a map for each
+// entry, besides its key and value.
+const mapEntryOverhead = 16
+
+// stats adds n and its descendants, at the given depth, to s.
+// The root is at depth 0, and hosts at depth 1.
+func (n *routingNode) stats(s *MuxStats, depth int) {
+	s.Nodes++
+	s.Bytes += int64(unsafe.Sizeof(*n))
+	if n.pattern != nil {
+		s.Leaves++
+	}
+	if depth == 1 {
+		s.Hosts++
+	}
+	ptr := int64(unsafe.Sizeof(uintptr(0)))
+	if n.children.m != nil {
+		s.Bytes += int64(len(n.children.m)) * (int64(unsafe.Sizeof("")) + ptr + mapEntryOverhead)
+	} else {
+		s.Bytes += int64(cap(n.children.s)) * int64(unsafe.Sizeof(entry[string, *routingNode]{}))
+	}
+	n.children.eachPair(func(k string, c *routingNode) bool {
+		s.Bytes += int64(len(k))
+		c.stats(s, depth+1)
+		return true
+	})
+	if n.multiChild != nil {
+		n.multiChild.stats(s, depth+1)
+	}
+	for _, c := range n.suffixed {
+		c.stats(s, depth+1)
+	}
+	if n.emptyChild != nil {
+		n.emptyChild.stats(s, depth+1)
+	}
+}
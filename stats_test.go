@@ -0,0 +1,32 @@
+package shortmux
+
+import (
+	"maps"
+	"net/http"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	mux := NewServeMux()
+	empty := mux.Stats()
+	if empty.Routes != 0 || empty.Nodes != 1 || empty.Bytes <= 0 {
+		t.Errorf("empty mux: got %+v", empty)
+	}
+
+	h := http.NotFoundHandler()
+	for _, pat := range []string{"/a", "GET /a/b", "GET /k/{k:(v|w)}", "example.com/c/"} {
+		mux.Handle(pat, h)
+	}
+	s := mux.Stats()
+	want := MuxStats{Routes: 4, Hosts: 2, Nodes: 14, Leaves: 5, Multis: 1, Static: 1}
+	if s.Routes != want.Routes || s.Hosts != want.Hosts || s.Nodes != want.Nodes ||
+		s.Leaves != want.Leaves || s.Multis != want.Multis || s.Static != want.Static {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+	if want := map[int]int{1: 3, 2: 1}; !maps.Equal(s.IndexBuckets, want) {
+		t.Errorf("IndexBuckets: got %v, want %v", s.IndexBuckets, want)
+	}
+	if s.Bytes <= empty.Bytes {
+		t.Errorf("Bytes: got %d, want more than %d", s.Bytes, empty.Bytes)
+	}
+}
@@ -0,0 +1,77 @@
+package shortmux
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// WithAbsoluteForm allows requests with an absolute-form target, such as
+// "GET http://example.com/ HTTP/1.1", which clients send to forward
+// proxies, on a mux with StrictRequests set.
+func WithAbsoluteForm() RouteOption {
+	return func(c *routeConfig) {
+		c.absoluteForm = true
+	}
+}
+
+// checkRequest returns the reason r must be rejected by a mux with
+// StrictRequests set before it is routed, or nil.
+//
+// The server removes the framing ambiguities it can resolve, so any left
+// are the sign of a smuggling attempt through a lenient front end.
+func checkRequest(r *http.Request) error {
+	if cl := r.Header.Values("Content-Length"); len(cl) > 1 {
+		return errors.New("multiple Content-Length headers")
+	} else if len(cl) == 1 && len(r.TransferEncoding) > 0 {
+		return errors.New("both Content-Length and Transfer-Encoding")
+	}
+	if _, ok := r.Header["Transfer-Encoding"]; ok {
+		return errors.New("unexpected Transfer-Encoding header")
+	}
+	if !validHost(r.Host) {
+		return errors.New("invalid Host header")
+	}
+	return nil
+}
+
+// absoluteForm reports whether r has an absolute-form target.
+func absoluteForm(r *http.Request) bool {
+	return r.Method != "CONNECT" && r.RequestURI != "" && r.RequestURI != "*" && !strings.HasPrefix(r.RequestURI, "/")
+}
+
+// validHost reports whether h is a valid Host header value: a registered
+// name or an IP address, with an optional port (RFC 9110, section 7.2).
+func validHost(h string) bool {
+	if h == "" {
+		return false
+	}
+	if strings.HasPrefix(h, "[") {
+		// IP literal.
+		end := strings.IndexByte(h, ']')
+		if end < 0 || strings.Trim(h[1:end], "0123456789abcdefABCDEF:.") != "" {
+			return false
+		}
+		h = h[end+1:]
+		if h == "" {
+			return true
+		}
+		if h[0] != ':' {
+			return false
+		}
+		return strings.Trim(h[1:], "0123456789") == ""
+	}
+	host, port, hasPort := strings.Cut(h, ":")
+	if hasPort && strings.Trim(port, "0123456789") != "" {
+		return false
+	}
+	for _, c := range []byte(host) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-._~%!$&'()*+,;=", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return host != ""
+}
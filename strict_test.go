@@ -0,0 +1,48 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStrictRequests(t *testing.T) {
+	mux := NewServeMux()
+	mux.StrictRequests = true
+	h := http.NotFoundHandler()
+	mux.Handle("/", h)
+	mux.Handle("proxy.example.com/", h, WithAbsoluteForm())
+
+	for _, test := range []struct {
+		name   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{"valid", func(r *http.Request) {}, 404},
+		{"valid with port", func(r *http.Request) { r.Host = "example.com:8080" }, 404},
+		{"valid IPv6", func(r *http.Request) { r.Host = "[::1]:8080" }, 404},
+		{"two Content-Length", func(r *http.Request) { r.Header["Content-Length"] = []string{"1", "2"} }, 400},
+		{"Content-Length and chunked", func(r *http.Request) {
+			r.Header.Set("Content-Length", "5")
+			r.TransferEncoding = []string{"chunked"}
+		}, 400},
+		{"Transfer-Encoding header", func(r *http.Request) { r.Header.Set("Transfer-Encoding", "chunked") }, 400},
+		{"Host with path", func(r *http.Request) { r.Host = "example.com/evil" }, 400},
+		{"Host with space", func(r *http.Request) { r.Host = "example.com evil" }, 400},
+		{"Host with userinfo", func(r *http.Request) { r.Host = "user@example.com" }, 400},
+		{"Host with bad port", func(r *http.Request) { r.Host = "example.com:x" }, 400},
+		{"absolute form", func(r *http.Request) { r.RequestURI = "http://example.com/" }, 400},
+		{"absolute form to proxy route", func(r *http.Request) {
+			r.RequestURI = "http://proxy.example.com/"
+			r.Host = "proxy.example.com"
+		}, 404},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		test.modify(r)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, want %d", test.name, w.Code, test.status)
+		}
+	}
+}
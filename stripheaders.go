@@ -0,0 +1,52 @@
+package shortmux
+
+import (
+	"net/http"
+)
+
+// WithStripHeaders removes the named headers from the requests of the
+// route before the handler sees them, such as Cookie and Authorization for
+// a route proxying to a backend that must not get the credentials of the
+// client. Registering a group of routes with a [ServeMux.Group] with the
+// option applies it to all of them.
+func WithStripHeaders(names ...string) RouteOption {
+	strip := make(map[string]bool, len(names))
+	for _, name := range names {
+		strip[http.CanonicalHeaderKey(name)] = true
+	}
+	return withHeaderFilter(func(name string) bool { return !strip[name] })
+}
+
+// WithAllowHeaders removes every header but the named ones from the
+// requests of the route before the handler sees them, as an allowlist
+// counterpart of [WithStripHeaders].
+func WithAllowHeaders(names ...string) RouteOption {
+	allow := make(map[string]bool, len(names))
+	for _, name := range names {
+		allow[http.CanonicalHeaderKey(name)] = true
+	}
+	return withHeaderFilter(func(name string) bool { return allow[name] })
+}
+
+// withHeaderFilter keeps only the request headers for which keep, given a
+// canonical header name, returns true.
+func withHeaderFilter(keep func(string) bool) RouteOption {
+	return func(c *routeConfig) {
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h := make(http.Header, len(r.Header))
+				for name, vs := range r.Header {
+					// Headers set by handlers or tests may not be in
+					// canonical form.
+					if keep(http.CanonicalHeaderKey(name)) {
+						h[name] = vs
+					}
+				}
+				r2 := new(http.Request)
+				*r2 = *r
+				r2.Header = h
+				next.ServeHTTP(w, r2)
+			})
+		})
+	}
+}
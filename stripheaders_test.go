@@ -0,0 +1,49 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestWithStripHeaders(t *testing.T) {
+	var got []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		got = got[:0]
+		for name := range r.Header {
+			got = append(got, name)
+		}
+		slices.Sort(got)
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/strip", record, WithStripHeaders("cookie", "Authorization"))
+	mux.HandleFunc("/allow", record, WithAllowHeaders("Accept", "x-request-id"))
+	proxy := mux.Group(WithStripHeaders("Cookie", "Authorization"))
+	proxy.HandleFunc("/proxy/a", record)
+	proxy.HandleFunc("/proxy/b", record, WithStripHeaders("User-Agent"))
+
+	for _, test := range []struct {
+		path string
+		want []string
+	}{
+		{"/strip", []string{"Accept", "User-Agent", "X-Request-Id"}},
+		{"/allow", []string{"Accept", "X-Request-Id"}},
+		{"/proxy/a", []string{"Accept", "User-Agent", "X-Request-Id"}},
+		{"/proxy/b", []string{"Accept", "X-Request-Id"}},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Accept", "text/html")
+		r.Header.Set("User-Agent", "test")
+		r.Header.Set("X-Request-Id", "1")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: handler got headers %q, want %q", test.path, got, test.want)
+		}
+		if r.Header.Get("Cookie") == "" {
+			t.Errorf("%s: header of the original request modified", test.path)
+		}
+	}
+}
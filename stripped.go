@@ -0,0 +1,78 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HandleStripped registers handler for pattern, which must end in a slash
+// or a "..." wildcard, such as "/admin/" or "/tenants/{tenant}/admin/",
+// with the part of the request path matched by the pattern before the
+// final wildcard removed, as handlers written for the root of a server
+// expect. For example, with the second pattern above, the handler sees a
+// request for "/tenants/acme/admin/users" as a request for "/users".
+//
+// Unlike [http.StripPrefix], which removes a fixed string, it removes the
+// segments matched by the pattern, including wildcards, and it keeps the
+// escaping of the remaining path in RawPath. Path values remain available
+// with [http.Request.PathValue].
+func (mux *ServeMux) HandleStripped(pattern string, handler http.Handler, opts ...RouteOption) {
+	if handler == nil {
+		panic("http: nil handler")
+	}
+	p, err := mux.parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	if !p.lastSegment().multi {
+		panic("shortmux: stripped pattern " + pattern + " must end in a slash or a \"...\" wildcard")
+	}
+	mux.register(pattern, &strippedHandler{mux: mux, segments: len(p.segments) - 1, next: handler}, opts)
+}
+
+// strippedHandler serves requests with the first segments of their path
+// removed.
+type strippedHandler struct {
+	mux      *ServeMux // for error responses
+	segments int
+	next     http.Handler
+}
+
+func (h *strippedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := stripSegments(r.URL.EscapedPath(), h.segments)
+	path, err := url.PathUnescape(rest)
+	if err != nil {
+		h.mux.Error(w, r, http.StatusBadRequest)
+		return
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = rest
+	h.next.ServeHTTP(w, r2)
+}
+
+// Unwrap returns the handler serving the stripped requests.
+func (h *strippedHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+// stripSegments returns the escaped path with its first n segments
+// removed. The result starts with a slash.
+func stripSegments(escaped string, n int) string {
+	rest := escaped
+	for range n {
+		i := strings.IndexByte(rest[min(1, len(rest)):], '/')
+		if i < 0 {
+			return "/"
+		}
+		rest = rest[i+1:]
+	}
+	if rest == "" {
+		return "/"
+	}
+	return rest
+}
@@ -0,0 +1,61 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleStripped(t *testing.T) {
+	show := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.URL.EscapedPath(), r.PathValue("tenant"))
+	})
+	mux := NewServeMux()
+	mux.HandleStripped("/admin/", show)
+	mux.HandleStripped("/tenants/{tenant}/admin/", show)
+	mux.HandleStripped("GET /files/{rest...}", show)
+
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/admin/", "/ / "},
+		{"/admin/users", "/users /users "},
+		{"/admin/a%2Fb/c", "/a/b/c /a%2Fb/c "},
+		{"/tenants/acme/admin/users/7", "/users/7 /users/7 acme"},
+		{"/tenants/a%2Fb/admin/x%20y", "/x y /x%20y a/b"},
+		{"/files/docs/readme", "/docs/readme /docs/readme "},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("HandleStripped without a final wildcard did not panic")
+		}
+	}()
+	mux.HandleStripped("/exact", show)
+}
+
+func TestStripSegments(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		n    int
+		want string
+	}{
+		{"/a/b/c", 0, "/a/b/c"},
+		{"/a/b/c", 1, "/b/c"},
+		{"/a/b/c", 2, "/c"},
+		{"/a/b/c", 3, "/"},
+		{"/a/b/", 2, "/"},
+		{"/a", 1, "/"},
+	} {
+		if got := stripSegments(test.path, test.n); got != test.want {
+			t.Errorf("stripSegments(%q, %d) = %q, want %q", test.path, test.n, got, test.want)
+		}
+	}
+}
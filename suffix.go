@@ -0,0 +1,306 @@
+package shortmux
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// This file implements patterns whose "{name...}" wildcard is followed by
+// a suffix of other segments, such as "/repo/{path...}/blob/{file}".
+//
+// The suffix is made of literals and single wildcards, and may end with
+// "{$}", or be open-ended, ending with a "{name...}" wildcard or a
+// trailing slash. The first "{name...}" wildcard matches the shortest
+// sequence of one or more segments for which the suffix matches the rest
+// of the path. For a suffix that isn't open-ended, that is the only
+// sequence leaving as many segments as the suffix has; otherwise, the
+// splits are tried in order, so matching a path costs at most a number of
+// segment comparisons proportional to the number of its segments times
+// the length of the suffix.
+//
+// The patterns with a suffix after the same prefix are kept in their
+// order of precedence (see suffixPrecedes), before the pattern without a
+// suffix, if any. Patterns that could both match a path without either
+// taking precedence can't be registered together (see suffixConflict).
+
+// splitSuffix matches path, starting with the slash before the segments
+// of the "{name...}" wildcard of p that is followed by a suffix, against
+// that wildcard and the suffix, trying the shortest match of the wildcard
+// first. It returns the values of the wildcards, starting with that of the
+// "{name...}" wildcard, and including those of constrained wildcards if
+// enums is set, and whether a "{name...}" wildcard or trailing slash
+// ending the suffix matched nothing.
+func (p *pattern) splitSuffix(path string, enums bool) (vals []string, exact, ok bool) {
+	fixed, last, open := p.suffix, segment{}, false
+	if l := fixed[len(fixed)-1]; l.multi {
+		fixed, last, open = fixed[:len(fixed)-1], l, true
+	}
+	if !open {
+		i := suffixStart(path, len(fixed))
+		if i < 0 {
+			return nil, false, false
+		}
+		vals, rest, ok := matchFixed(path[i:], fixed, []string{pathUnescape(path[1:i])}, enums)
+		return vals, true, ok && rest == ""
+	}
+	for i := 1; i < len(path); i++ {
+		if path[i] != '/' {
+			continue
+		}
+		vals, rest, ok := matchFixed(path[i:], fixed, []string{pathUnescape(path[1:i])}, enums)
+		if !ok || rest == "" {
+			continue
+		}
+		if last.s != "" {
+			vals = append(vals, pathUnescape(rest[1:]))
+		}
+		return vals, rest == "/", true
+	}
+	return nil, false, false
+}
+
+// matchFixed matches the first segments of path against segs, appending
+// the values of their wildcards to vals, as splitSuffix does, and returns
+// them with the rest of the path.
+func matchFixed(path string, segs []segment, vals []string, enums bool) ([]string, string, bool) {
+	for _, seg := range segs {
+		if path == "" {
+			return nil, "", false
+		}
+		var s string
+		s, path = firstSegment(path)
+		switch {
+		case !seg.wild:
+			if s != seg.s {
+				return nil, "", false
+			}
+		case s == "/":
+			// Single wildcards don't match trailing slashes.
+			return nil, "", false
+		case seg.enum != nil:
+			if !slices.Contains(seg.enum, s) {
+				return nil, "", false
+			}
+			if enums {
+				vals = append(vals, s)
+			}
+		default:
+			if slices.Contains(seg.exclude, s) {
+				return nil, "", false
+			}
+			vals = append(vals, s)
+		}
+	}
+	return vals, path, true
+}
+
+// suffixStart returns the index in path of the slash starting its last n
+// segments, as split by firstSegment, or -1 if path doesn't have at least
+// one more segment, to be matched by a multi wildcard.
+func suffixStart(path string, n int) int {
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] != '/' {
+			continue
+		}
+		if n--; n == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// exactSuffix reports whether p, which has a suffix, matches path without
+// a "{name...}" wildcard or trailing slash ending the suffix matching
+// anything, as described by exactMatch.
+func (p *pattern) exactSuffix(path string) bool {
+	if !p.lastSegment().multi {
+		return true
+	}
+	for range len(p.segments) - len(p.suffix) - 1 {
+		_, path = firstSegment(path)
+		if path == "" {
+			return false
+		}
+	}
+	_, exact, ok := p.splitSuffix(path, false)
+	return ok && exact
+}
+
+// openSuffix splits the suffix s into the segments matching a fixed number
+// of path segments, and whether a multi wildcard follows them.
+func openSuffix(s []segment) ([]segment, bool) {
+	if s[len(s)-1].multi {
+		return s[:len(s)-1], true
+	}
+	return s, false
+}
+
+// suffixPrecedes reports whether the suffix a of a multi wildcard takes
+// precedence over the suffix b at the same position: suffixes that aren't
+// open-ended come first, then longer suffixes, which match fewer paths,
+// then more specific ones.
+func suffixPrecedes(a, b []segment) bool {
+	a, aopen := openSuffix(a)
+	b, bopen := openSuffix(b)
+	if aopen != bopen {
+		return bopen
+	}
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return compareSuffixes(a, b) < 0
+}
+
+// compareSuffixes returns -1 if every segment of a is at least as
+// specific as the segment of b at the same position, and one of them is
+// more specific; 1 if the reverse is true; and 0 otherwise. The suffixes
+// have the same length.
+func compareSuffixes(a, b []segment) int {
+	c := 0
+	for i := range a {
+		switch d := suffixRank(a[i]) - suffixRank(b[i]); {
+		case d < 0 && c <= 0:
+			c = -1
+		case d > 0 && c >= 0:
+			c = 1
+		case d != 0:
+			return 0
+		}
+	}
+	return c
+}
+
+// suffixRank orders the kinds of segments of suffixes from the most
+// specific to the least. Unlike in the tree, a wildcard excluding literals
+// takes precedence over one that doesn't.
+func suffixRank(s segment) int {
+	switch {
+	case !s.wild:
+		return 0
+	case s.enum != nil:
+		return 1
+	case s.exclude != nil:
+		return 2
+	}
+	return 3
+}
+
+// sameSegments reports whether a and b match the same paths.
+func sameSegments(a, b []segment) bool {
+	return slices.EqualFunc(a, b, func(s, t segment) bool {
+		return s.wild == t.wild && s.multi == t.multi && (s.wild || s.s == t.s) &&
+			slices.Equal(s.enum, t.enum) && slices.Equal(s.exclude, t.exclude)
+	})
+}
+
+// suffixConflict returns a registered pattern with a suffix at the same
+// position as that of p, such that some paths are matched by both and
+// neither takes precedence, or nil if there is none.
+func (root *routingNode) suffixConflict(p *pattern) *pattern {
+	if p.suffix == nil {
+		return nil
+	}
+	hn := root.findChild(p.host)
+	if hn == nil {
+		return nil
+	}
+	prefix := p.segments[:len(p.segments)-len(p.suffix)-1]
+	var conflict *pattern
+	hn.findChild(p.method).eachPrefixNode(prefix, func(n *routingNode) {
+		for _, c := range n.suffixed {
+			if conflict == nil && suffixesConflict(c.pattern.suffix, p.suffix) {
+				conflict = c.pattern
+			}
+		}
+	})
+	return conflict
+}
+
+// eachPrefixNode calls f with the nodes under n reached by segs, which
+// has no multi wildcard.
+func (n *routingNode) eachPrefixNode(segs []segment, f func(*routingNode)) {
+	if n == nil {
+		return
+	}
+	if len(segs) == 0 {
+		f(n)
+		return
+	}
+	seg := segs[0]
+	switch {
+	case seg.enum != nil:
+		for _, lit := range seg.enum {
+			n.findChild(lit).eachPrefixNode(segs[1:], f)
+		}
+	case seg.wild:
+		n.emptyChild.eachPrefixNode(segs[1:], f)
+	default:
+		n.findChild(seg.s).eachPrefixNode(segs[1:], f)
+	}
+}
+
+// suffixesConflict reports whether the suffixes a and b at the same
+// position both match some paths, without either taking precedence.
+func suffixesConflict(a, b []segment) bool {
+	if sameSegments(a, b) || suffixPrecedes(a, b) || suffixPrecedes(b, a) {
+		return false
+	}
+	a, _ = openSuffix(a)
+	b, _ = openSuffix(b)
+	return suffixExample(a, b) != ""
+}
+
+// suffixExample returns a path matched by both of the suffixes a and b,
+// of the same length and not open-ended, or "" if there is none.
+func suffixExample(a, b []segment) string {
+	var path strings.Builder
+	for i := range a {
+		s, t := a[i], b[i]
+		if t.enum != nil || !t.wild {
+			s, t = t, s
+		}
+		var lits []string // literals matched by s, if limited
+		switch {
+		case !s.wild:
+			lits = []string{s.s}
+		case s.enum != nil:
+			lits = s.enum
+		}
+		if lits == nil {
+			// Both are single wildcards, which may exclude literals.
+			lit := "x"
+			for !segmentMatches(s, lit) || !segmentMatches(t, lit) {
+				lit += "x"
+			}
+			path.WriteString("/" + lit)
+			continue
+		}
+		i := slices.IndexFunc(lits, func(lit string) bool { return segmentMatches(t, lit) })
+		if i < 0 {
+			return ""
+		}
+		path.WriteString("/" + lits[i])
+	}
+	return path.String()
+}
+
+// segmentMatches reports whether seg matches the path segment lit.
+func segmentMatches(seg segment, lit string) bool {
+	switch {
+	case !seg.wild:
+		return seg.s == lit
+	case seg.enum != nil:
+		return slices.Contains(seg.enum, lit)
+	}
+	return lit != "/" && !slices.Contains(seg.exclude, lit)
+}
+
+// describeSuffixConflict returns a message explaining why p2 can't be
+// registered alongside p1, as reported by suffixConflict.
+func describeSuffixConflict(p1, p2 *pattern) string {
+	a, _ := openSuffix(p1.suffix)
+	b, _ := openSuffix(p2.suffix)
+	return fmt.Sprintf("pattern %q (registered at %s) and %q both match paths ending in %q after their {...} wildcard, and neither is more specific",
+		p1, p1.loc, p2, suffixExample(a, b))
+}
@@ -0,0 +1,179 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSuffixPatternErrors(t *testing.T) {
+	for _, test := range []struct {
+		pat, want string
+	}{
+		{"/a/{p...}/", "{...} wildcard followed by a trailing slash"},
+		{"/a/{p...}/{q...}", "{...} wildcard followed by {...} wildcard"},
+		{"/a/{p...}/b/{q...}/c", "{...} wildcard not at end"},
+	} {
+		_, err := parsePattern(test.pat)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got %v, want %q", test.pat, err, test.want)
+		}
+	}
+	for _, pat := range []string{
+		"/a/{p...}/b", "/a/{p...}/b/c", "/a/{p...}/b/{$}", "/{p...}/{$}",
+		"/a/{p...}/{x}", "/a/{p...}/b/", "/a/{p...}/b/{q...}", "/a/{p...}/{x:(c|d)}/{y:!e}",
+	} {
+		if _, err := parsePattern(pat); err != nil {
+			t.Errorf("%q: %v", pat, err)
+		}
+	}
+}
+
+func TestSuffixPatterns(t *testing.T) {
+	mux := NewServeMux()
+	for _, pat := range []string{
+		"/files/{path...}/meta",
+		"/files/{path...}/meta/raw",
+		"/dirs/{path...}/{$}",
+		"/files/{path...}",
+		"GET /{kind:(repo|org)}/{path...}/blob",
+		"/x/{p:!skip}/{rest...}/end",
+	} {
+		mux.HandleFunc(pat, func(w http.ResponseWriter, r *http.Request) {
+			var vals []string
+			for _, name := range []string{"path", "kind", "p", "rest"} {
+				if v := r.PathValue(name); v != "" {
+					vals = append(vals, name+"="+v)
+				}
+			}
+			w.Write([]byte(pat + " " + strings.Join(vals, " ")))
+		})
+	}
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/files/a/b/meta", "/files/{path...}/meta path=a/b"},
+		{"/files/a/meta", "/files/{path...}/meta path=a"},
+		{"/files/a/meta/raw", "/files/{path...}/meta/raw path=a"},
+		{"/files/meta/meta/raw", "/files/{path...}/meta/raw path=meta"},
+		{"/dirs/a/b/", "/dirs/{path...}/{$} path=a/b"},
+		{"/files/meta", "/files/{path...} path=meta"},
+		{"/files/a/metadata", "/files/{path...} path=a/metadata"},
+		{"/files/a%2Fb/meta", "/files/{path...}/meta path=a/b"},
+		{"/repo/a/b/blob", "GET /{kind:(repo|org)}/{path...}/blob path=a/b kind=repo"},
+		{"/x/y/a/b/end", "/x/{p:!skip}/{rest...}/end p=y rest=a/b"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/x/skip/a/end", nil))
+	if w.Code != 404 {
+		t.Errorf("excluded: got %d, want 404", w.Code)
+	}
+
+	// Patterns with the same suffix occupy the same leaf.
+	if err := mux.registerErr("/files/{p...}/meta", http.NotFoundHandler()); err == nil {
+		t.Error("duplicate suffix pattern: got no error")
+	}
+	if err := mux.Remove("/files/{path...}/meta"); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/files/a/meta", nil))
+	if got, want := w.Body.String(), "/files/{path...} path=a/meta"; got != want {
+		t.Errorf("after removal: got %q, want %q", got, want)
+	}
+}
+
+func TestSuffixWildcards(t *testing.T) {
+	mux := NewServeMux()
+	for _, pat := range []string{
+		"/repo/{path...}/blob/{file}",
+		"/repo/{path...}/tree/{rest...}",
+		"/repo/{path...}/raw/",
+		"/repo/{path...}/{file:(README|LICENSE)}",
+		"/repo/{path...}",
+		"/src/{path...}/{kind}/{file}/{$}",
+		"/src/{path...}/tree/{rest...}",
+		"/src/{path...}/{file:!skip}",
+		"/src/{path...}",
+	} {
+		mux.HandleFunc(pat, func(w http.ResponseWriter, r *http.Request) {
+			var vals []string
+			for _, name := range []string{"path", "kind", "file", "rest"} {
+				if v := r.PathValue(name); v != "" {
+					vals = append(vals, name+"="+v)
+				}
+			}
+			w.Write([]byte(pat + " " + strings.Join(vals, " ")))
+		})
+	}
+	for _, test := range []struct {
+		path, want string
+	}{
+		{"/repo/a/b/blob/main.go", "/repo/{path...}/blob/{file} path=a/b file=main.go"},
+		{"/repo/a/blob/blob/x", "/repo/{path...}/blob/{file} path=a/blob file=x"},
+		// The shortest match of path is taken.
+		{"/repo/a/tree/b/tree/c", "/repo/{path...}/tree/{rest...} path=a rest=b/tree/c"},
+		{"/repo/a/tree/", "/repo/{path...}/tree/{rest...} path=a"},
+		{"/repo/a/b/raw/x/y", "/repo/{path...}/raw/ path=a/b"},
+		{"/repo/a/README", "/repo/{path...}/{file:(README|LICENSE)} path=a file=README"},
+		{"/repo/a/LICENSE.md", "/repo/{path...} path=a/LICENSE.md"},
+		{"/src/a/b/log/main/", "/src/{path...}/{kind}/{file}/{$} path=a/b kind=log file=main"},
+		// Suffixes matching a fixed number of segments take precedence.
+		{"/src/a/tree/b/", "/src/{path...}/{kind}/{file}/{$} path=a kind=tree file=b"},
+		{"/src/a/tree/b", "/src/{path...}/{file:!skip} path=a/tree file=b"},
+		{"/src/a/tree/b/c/", "/src/{path...}/{kind}/{file}/{$} path=a/tree kind=b file=c"},
+		{"/src/a/tree/", "/src/{path...}/tree/{rest...} path=a"},
+		{"/src/a/skip", "/src/{path...} path=a/skip"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+
+	// A path missing the trailing slash of an open-ended suffix is
+	// redirected.
+	mux = NewServeMux()
+	mux.Handle("/repo/{path...}/raw/", http.NotFoundHandler())
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/repo/a/raw", nil))
+	if loc := w.Header().Get("Location"); w.Code != 301 || loc != "/repo/a/raw/" {
+		t.Errorf("/repo/a/raw: got %d to %q, want a redirect to /repo/a/raw/", w.Code, loc)
+	}
+}
+
+func TestSuffixConflicts(t *testing.T) {
+	for _, test := range []struct {
+		p1, p2 string
+		want   string // example path, or "" if there is no conflict
+	}{
+		{"/{p...}/a/{x}", "/{q...}/{y}/b", "/a/b"},
+		{"/{p...}/a/{x}/", "/{q...}/{y}/b/", "/a/b"},
+		{"/{p...}/{x:(a|b)}", "/{q...}/{y:(b|c)}", "/b"},
+		{"/{p...}/{x:!a}/c", "/{q...}/{y:!b}/c", "/x/c"},
+		{"/{p...}/a/{x}", "/{q...}/b/{y}", ""},
+		{"/{p...}/a/{x}", "/{q...}/{y}/{z}", ""},
+		{"/{p...}/{x:(a|b)}", "/{q...}/{y:(c|d)}", ""},
+		{"/{p...}/a/{x}", "/{q...}/{y}/b/", ""},
+		{"/{p...}/a", "/{q...}/{y}/a", ""},
+	} {
+		mux := NewServeMux()
+		mux.Handle(test.p1, http.NotFoundHandler())
+		err := mux.registerErr(test.p2, http.NotFoundHandler())
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%q and %q: %v", test.p1, test.p2, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), fmt.Sprintf("%q", test.want))):
+			t.Errorf("%q and %q: got %v, want a conflict on %q", test.p1, test.p2, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package shortmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// A RouteChange adds or removes a route. It is the unit of the updates
+// streamed by a [RouteSource], and of the notifications of
+// [ServeMux.Changes], which don't carry Options.
+type RouteChange struct {
+	Remove  bool          // remove the route registered with Pattern, instead of adding it
+	Pattern string        // pattern of the route
+	Handler http.Handler  // handler of an added route
+	Options []RouteOption // options of an added route
+
+	// Location, if set, is reported as the source location of an added
+	// route, e.g. "routes.json:12". It defaults to the caller of Apply or
+	// Sync.
+	Location string
+}
+
+// A RouteSource streams changes to the routing table of a mux, for example
+// from a configuration store shared by a fleet of gateways.
+// Adapters for particular stores live in subpackages.
+type RouteSource interface {
+	// Watch calls apply with each batch of changes, in order, until ctx is
+	// done or the source fails. If apply returns an error, the batch was
+	// not applied, and the source may retry it, skip it or give up
+	// returning the error.
+	Watch(ctx context.Context, apply func([]RouteChange) error) error
+}
+
+// Sync applies the changes streamed by src to mux with [ServeMux.Apply],
+// until ctx is done or src.Watch returns. It returns the error returned by
+// src.Watch.
+func (mux *ServeMux) Sync(ctx context.Context, src RouteSource) error {
+	loc := callerLocation(1)
+	return src.Watch(ctx, func(changes []RouteChange) error {
+		return mux.apply(changes, loc)
+	})
+}
+
+// Apply adds and removes routes, in order, as a single transaction: either
+// all changes take effect at once, or, if any of them fails, none does and
+// the error is returned. Requests are never matched against a partially
+// applied batch. The error reports every change that failed, with its
+// pattern and location, as joined by [errors.Join]; changes are checked
+// as if those before them that failed weren't in the batch.
+// [ServeMux.OnRegister] and [ServeMux.OnRemove] are called, and
+// [ServeMux.Changes] notified, for each change after the batch is applied.
+func (mux *ServeMux) Apply(changes []RouteChange) error {
+	return mux.apply(changes, callerLocation(1))
+}
+
+func (mux *ServeMux) apply(changes []RouteChange, loc string) error {
+	// Build the routes to add before taking the lock, as options may use
+	// the mux.
+	added := make([]*route, len(changes))
+	var errs []error
+	for i, c := range changes {
+		if c.Remove {
+			continue
+		}
+		rt, err := mux.newRoute(c.Pattern, c.Handler, c.Options)
+		if err != nil {
+			errs = append(errs, changeError(i, c, loc, err))
+			continue
+		}
+		rt.pat.loc = loc
+		if c.Location != "" {
+			rt.pat.loc = c.Location
+		}
+		added[i] = rt
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	applied, err := mux.applyLocked(changes, added, loc)
+	if err != nil {
+		return err
+	}
+	for i, rt := range applied {
+		mux.changed(changes[i].Remove, rt)
+	}
+	return nil
+}
+
+// applyLocked applies changes, using the routes in added for additions,
+// and returns the route added or removed by each change. If any change
+// fails, it goes on checking the others, and then undoes the changes that
+// succeeded.
+func (mux *ServeMux) applyLocked(changes []RouteChange, added []*route, loc string) ([]*route, error) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if mux.frozen.Load() {
+		return nil, ErrFrozen
+	}
+	routes := slices.Clone(mux.routes)
+	applied := make([]*route, len(changes))
+	var errs []error
+	for i, c := range changes {
+		rt := added[i]
+		var err error
+		if c.Remove {
+			rt, err = mux.removeRouteLocked(c.Pattern)
+		} else {
+			err = mux.addRouteLocked(rt)
+		}
+		if err != nil {
+			errs = append(errs, changeError(i, c, loc, err))
+			continue
+		}
+		applied[i] = rt
+	}
+	if len(errs) > 0 {
+		mux.rollback(changes, applied)
+		mux.routes = routes
+		return nil, errors.Join(errs...)
+	}
+	mux.version.Add(1)
+	return applied, nil
+}
+
+// changeError returns err, the failure of the change i applied from loc,
+// with the change and its location.
+func changeError(i int, c RouteChange, loc string, err error) error {
+	if c.Location != "" {
+		loc = c.Location
+	}
+	return fmt.Errorf("applying change %d (%q) at %s: %w", i, c.Pattern, loc, err)
+}
+
+// rollback undoes the applied changes, in reverse order, skipping the
+// changes that failed, whose route is nil. The caller restores mux.routes.
+func (mux *ServeMux) rollback(changes []RouteChange, applied []*route) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		rt := applied[i]
+		if rt == nil {
+			continue
+		}
+		if changes[i].Remove {
+			mux.insertLocked(rt)
+		} else {
+			mux.deleteLocked(rt)
+		}
+	}
+}
+
+// changesBuffer is the capacity of the channels returned by Changes.
+const changesBuffer = 128
+
+// Changes returns a channel receiving a [RouteChange] for every route
+// registered on or removed from mux from now on, until ctx is done, so that
+// systems derived from the routing table, such as documentation or caches
+// keyed by pattern, can be invalidated precisely. Each call returns a new
+// channel, which is closed when ctx is done.
+//
+// Changes are sent without blocking the mux. A receiver that falls more
+// than 128 changes behind misses changes, so it should keep up, and may
+// resynchronize with [ServeMux.Routes] when [ServeMux.Version] moves
+// unexpectedly.
+func (mux *ServeMux) Changes(ctx context.Context) <-chan RouteChange {
+	c := make(chan RouteChange, changesBuffer)
+	mux.subsMu.Lock()
+	mux.subs = append(mux.subs, c)
+	mux.subsMu.Unlock()
+	context.AfterFunc(ctx, func() {
+		mux.subsMu.Lock()
+		defer mux.subsMu.Unlock()
+		mux.subs = slices.DeleteFunc(mux.subs, func(sub chan RouteChange) bool { return sub == c })
+		close(c)
+	})
+	return c
+}
+
+// changed reports the registration or removal of rt to the OnRegister or
+// OnRemove hook and to the channels returned by Changes.
+func (mux *ServeMux) changed(removed bool, rt *route) {
+	if removed && mux.OnRemove != nil {
+		mux.OnRemove(rt.export())
+	}
+	if !removed && mux.OnRegister != nil {
+		mux.OnRegister(rt.export())
+	}
+
+	mux.subsMu.Lock()
+	defer mux.subsMu.Unlock()
+	if len(mux.subs) == 0 {
+		return
+	}
+	c := RouteChange{Remove: removed, Pattern: rt.pat.str, Location: rt.pat.loc}
+	if !removed {
+		c.Handler = rt.handler
+	}
+	for _, sub := range mux.subs {
+		select {
+		case sub <- c:
+		default:
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package shortmux
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, name) })
+	}
+	var registered, removed []string
+	mux := NewServeMux()
+	mux.OnRegister = func(r Route) { registered = append(registered, r.Pattern) }
+	mux.OnRemove = func(r Route) { removed = append(removed, r.Pattern) }
+	mux.Handle("/a", named("a1"))
+	mux.Handle("/b", named("b"))
+	registered = nil
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+	patterns := func() []string {
+		var s []string
+		for _, r := range mux.Routes() {
+			s = append(s, r.Pattern)
+		}
+		return s
+	}
+
+	// A failing batch leaves the mux untouched.
+	err := mux.Apply([]RouteChange{
+		{Remove: true, Pattern: "/a"},
+		{Pattern: "/a", Handler: named("a2")},
+		{Pattern: "/c", Handler: named("c")},
+		{Pattern: "/b", Handler: named("b2")}, // duplicate
+	})
+	if err == nil {
+		t.Fatal("got nil error applying duplicate pattern")
+	}
+	if got, want := patterns(), []string{"/a", "/b"}; !slices.Equal(got, want) {
+		t.Errorf("after failed batch, routes: got %q, want %q", got, want)
+	}
+	if got := get("/a") + get("/c"); got != "a1404 page not found\n" {
+		t.Errorf("after failed batch, got %q", got)
+	}
+	if registered != nil || removed != nil {
+		t.Errorf("after failed batch, hooks called: %q, %q", registered, removed)
+	}
+
+	if err := mux.Apply([]RouteChange{
+		{Remove: true, Pattern: "/a"},
+		{Pattern: "/a", Handler: named("a2")},
+		{Pattern: "/c", Handler: named("c")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/a") + get("/b") + get("/c"); got != "a2bc" {
+		t.Errorf("got %q, want %q", got, "a2bc")
+	}
+	if want := []string{"/a", "/c"}; !slices.Equal(registered, want) {
+		t.Errorf("OnRegister: got %q, want %q", registered, want)
+	}
+	if want := []string{"/a"}; !slices.Equal(removed, want) {
+		t.Errorf("OnRemove: got %q, want %q", removed, want)
+	}
+
+	if err := mux.Apply([]RouteChange{{Remove: true, Pattern: "/nope"}}); err == nil {
+		t.Error("got nil error removing unregistered pattern")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/before", http.NotFound)
+	ctx, cancel := context.WithCancel(context.Background())
+	c1, c2 := mux.Changes(ctx), mux.Changes(context.Background())
+	mux.HandleFunc("/a", http.NotFound)
+	mux.Apply([]RouteChange{{Remove: true, Pattern: "/a"}, {Pattern: "/b", Handler: http.NotFoundHandler()}})
+
+	for _, c := range []<-chan RouteChange{c1, c2} {
+		var got []string
+		for len(c) > 0 {
+			ch := <-c
+			if ch.Remove {
+				got = append(got, "-"+ch.Pattern)
+			} else {
+				got = append(got, "+"+ch.Pattern)
+				if ch.Handler == nil || ch.Location == "" {
+					t.Errorf("%s: got handler %v, location %q", ch.Pattern, ch.Handler, ch.Location)
+				}
+			}
+		}
+		if want := []string{"+/a", "-/a", "+/b"}; !slices.Equal(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+
+	// A receiver falling behind doesn't block the mux.
+	for i := range changesBuffer + 1 {
+		mux.HandleFunc(fmt.Sprintf("/%d", i), http.NotFound)
+	}
+	if len(c1) != changesBuffer {
+		t.Errorf("got %d buffered changes, want %d", len(c1), changesBuffer)
+	}
+
+	// Canceling the context closes and unregisters the channel.
+	cancel()
+	n := 0
+	for range c1 {
+		n++
+	}
+	if n != changesBuffer {
+		t.Errorf("got %d changes before the channel was closed, want %d", n, changesBuffer)
+	}
+	mux.subsMu.Lock()
+	subs := len(mux.subs)
+	mux.subsMu.Unlock()
+	if subs != 1 {
+		t.Errorf("got %d subscribers after canceling, want 1", subs)
+	}
+	mux.HandleFunc("/after", http.NotFound)
+}
+
+func TestApplyReportsEveryError(t *testing.T) {
+	h := http.NotFoundHandler()
+	mux := NewServeMux()
+	mux.Handle("/taken", h)
+
+	// Parse errors are reported before conflicts are checked.
+	err := mux.Apply([]RouteChange{
+		{Pattern: "/ok", Handler: h},
+		{Pattern: "/{", Handler: h, Location: "routes.json:2"},
+		{Pattern: "/a/{x", Handler: h, Location: "routes.json:3"},
+	})
+	want := `applying change 1 ("/{") at routes.json:2: parsing "/{": at offset 1: bad wildcard segment (must end with '}')
+applying change 2 ("/a/{x") at routes.json:3: parsing "/a/{x": at offset 3: bad wildcard segment (must end with '}')`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error\n%v\nwant\n%s", err, want)
+	}
+
+	err = mux.Apply([]RouteChange{
+		{Pattern: "/ok", Handler: h},
+		{Pattern: "/taken", Handler: h, Location: "routes.json:2"},
+		{Remove: true, Pattern: "/missing", Location: "routes.json:3"},
+		{Pattern: "/also-ok", Handler: h},
+	})
+	if err == nil {
+		t.Fatal("got nil error")
+	}
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2:\n%v", len(errs), err)
+	}
+	for i, prefix := range []string{
+		`applying change 1 ("/taken") at routes.json:2: `,
+		`applying change 2 ("/missing") at routes.json:3: `,
+	} {
+		if got := errs[i].Error(); !strings.HasPrefix(got, prefix) {
+			t.Errorf("error %d: got %q, want prefix %q", i, got, prefix)
+		}
+	}
+	var patterns []string
+	for _, r := range mux.Routes() {
+		patterns = append(patterns, r.Pattern)
+	}
+	if want := []string{"/taken"}; !slices.Equal(patterns, want) {
+		t.Errorf("routes: got %q, want %q", patterns, want)
+	}
+}
@@ -0,0 +1,119 @@
+package shortmux
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithWriteRateLimit limits the rate at which the responses of the route
+// are written to bytesPerSec, shared by all the requests the route serves
+// at a time, so that large downloads don't starve the other routes of
+// bandwidth.
+//
+// Writes block until the limit allows them, or until the request context
+// is done, in which case they fail with the context error. Flushing is not
+// throttled.
+func WithWriteRateLimit(bytesPerSec int64) RouteOption {
+	if bytesPerSec <= 0 {
+		panic(fmt.Sprintf("shortmux: invalid write rate limit %d", bytesPerSec))
+	}
+	return func(c *routeConfig) {
+		b := newTokenBucket(bytesPerSec)
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(&throttledWriter{ResponseWriter: w, ctx: r.Context(), bucket: b}, r)
+			})
+		})
+	}
+}
+
+// tokenBucket is a token bucket of bytes, refilled at rate bytes per
+// second up to rate bytes.
+type tokenBucket struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time // when tokens was last refilled, or zero if never
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate)}
+}
+
+// wait takes n tokens from the bucket, waiting until they are available or
+// ctx is done. Tokens are reserved in order, so waiters are served first
+// come, first served.
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	now := nowFor(ctx)
+	if b.last.IsZero() {
+		b.last = now
+	}
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	debt := b.tokens
+	b.mu.Unlock()
+	if debt >= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(-debt / b.rate * float64(time.Second)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledWriter is a [http.ResponseWriter] writing at the rate allowed by
+// a token bucket.
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	// Write in chunks no larger than a second's worth of bytes, so that
+	// concurrent responses share the bandwidth.
+	chunk := min(32<<10, int(w.bucket.rate))
+	var written int
+	for len(p) > 0 {
+		n := min(len(p), chunk)
+		if err := w.bucket.wait(w.ctx, n); err != nil {
+			return written, err
+		}
+		n, err := w.ResponseWriter.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (w *throttledWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *throttledWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *throttledWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *throttledWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package shortmux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithWriteRateLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3000)
+	mux := NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}, WithWriteRateLimit(2000))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/download", nil))
+	// The bucket starts full with 2000 bytes, so the remaining 1000 bytes
+	// take half a second.
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("response written in %v, want at least 500ms", d)
+	}
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("got %d bytes, want %d", w.Body.Len(), len(body))
+	}
+
+	w = httptest.NewRecorder()
+	if err := http.NewResponseController(&throttledWriter{ResponseWriter: w}).Flush(); err != nil || !w.Flushed {
+		t.Errorf("Flush did not reach the underlying writer: %v", err)
+	}
+}
+
+func TestWriteRateLimitCanceled(t *testing.T) {
+	var err error
+	mux := NewServeMux()
+	mux.HandleFunc("/download", func(w http.ResponseWriter, r *http.Request) {
+		_, err = w.Write(make([]byte, 10000))
+	}, WithWriteRateLimit(100))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, "GET", "/download", nil))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Write error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
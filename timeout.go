@@ -0,0 +1,83 @@
+package shortmux
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientDeadline returns r with the deadline given by the first of the
+// TimeoutHeaders of mux present in r, if valid, clamped to the budget of
+// rt, and the function releasing it.
+func (mux *ServeMux) clientDeadline(r *http.Request, rt *route) (*http.Request, func()) {
+	for _, name := range mux.TimeoutHeaders {
+		v := r.Header.Get(name)
+		if v == "" {
+			continue
+		}
+		d, ok := ParseTimeout(name, v)
+		if !ok {
+			break
+		}
+		if rt != nil && rt.cfg.budget > 0 {
+			d = min(d, rt.cfg.budget)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		return r.WithContext(ctx), cancel
+	}
+	return r, func() {}
+}
+
+// ParseTimeout parses the value v of the timeout header name, as listed in
+// [ServeMux.TimeoutHeaders], and reports whether it is a valid, positive
+// timeout. The value of the Grpc-Timeout header is an integer of at most 8
+// digits followed by a unit: H, M, S, m, u or n for hours, minutes,
+// seconds, milliseconds, microseconds or nanoseconds, as in "250m". The
+// value of other headers is a number of seconds, such as "1.5", or a
+// duration as accepted by [time.ParseDuration], such as "1500ms".
+func ParseTimeout(name, v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if strings.EqualFold(name, "Grpc-Timeout") {
+		return parseGRPCTimeout(v)
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		// Reject NaN and values overflowing a Duration.
+		if !(secs > 0 && secs < float64(1<<63-1)/float64(time.Second)) {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// grpcUnits maps the units of gRPC timeouts to their durations.
+var grpcUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// parseGRPCTimeout parses the value of a Grpc-Timeout header.
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	unit, ok := grpcUnits[v[len(v)-1]]
+	if !ok || !isDigits(v[:len(v)-1]) {
+		return 0, false
+	}
+	n, _ := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if n == 0 || n > int64(1<<63-1)/int64(unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,77 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	for _, test := range []struct {
+		name, v string
+		want    time.Duration
+		ok      bool
+	}{
+		{"X-Request-Timeout", "1.5", 1500 * time.Millisecond, true},
+		{"X-Request-Timeout", " 2 ", 2 * time.Second, true},
+		{"X-Request-Timeout", "250ms", 250 * time.Millisecond, true},
+		{"X-Request-Timeout", "0", 0, false},
+		{"X-Request-Timeout", "-1s", 0, false},
+		{"X-Request-Timeout", "NaN", 0, false},
+		{"X-Request-Timeout", "1e300", 0, false},
+		{"X-Request-Timeout", "soon", 0, false},
+		{"Grpc-Timeout", "250m", 250 * time.Millisecond, true},
+		{"grpc-timeout", "3S", 3 * time.Second, true},
+		{"Grpc-Timeout", "2H", 2 * time.Hour, true},
+		{"Grpc-Timeout", "100u", 100 * time.Microsecond, true},
+		{"Grpc-Timeout", "123456789n", 0, false},
+		{"Grpc-Timeout", "99999999H", 0, false},
+		{"Grpc-Timeout", "1s", 0, false},
+		{"Grpc-Timeout", "0S", 0, false},
+		{"Grpc-Timeout", "S", 0, false},
+	} {
+		got, ok := ParseTimeout(test.name, test.v)
+		if got != test.want || ok != test.ok {
+			t.Errorf("ParseTimeout(%q, %q) = %v, %t; want %v, %t", test.name, test.v, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestTimeoutHeaders(t *testing.T) {
+	var left time.Duration
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		left = -1
+		if deadline, ok := r.Context().Deadline(); ok {
+			left = time.Until(deadline)
+		}
+	})
+	mux := NewServeMux()
+	mux.TimeoutHeaders = []string{"Grpc-Timeout", "X-Request-Timeout"}
+	mux.Handle("/fast", h, WithBudget(time.Second))
+	mux.Handle("/any", h)
+
+	for _, test := range []struct {
+		path   string
+		header map[string]string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"/any", nil, -1, -1},
+		{"/any", map[string]string{"X-Request-Timeout": "30"}, 29 * time.Second, 30 * time.Second},
+		{"/any", map[string]string{"X-Request-Timeout": "30", "Grpc-Timeout": "5S"}, 4 * time.Second, 5 * time.Second},
+		{"/any", map[string]string{"X-Request-Timeout": "never"}, -1, -1},
+		{"/fast", map[string]string{"X-Request-Timeout": "30"}, 900 * time.Millisecond, time.Second},
+		{"/fast", map[string]string{"Grpc-Timeout": "100m"}, 0, 100 * time.Millisecond},
+		{"/fast", nil, 900 * time.Millisecond, time.Second},
+	} {
+		r := httptest.NewRequest("GET", test.path, nil)
+		for k, v := range test.header {
+			r.Header.Set(k, v)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+		if left < test.min || left > test.max {
+			t.Errorf("%s %v: got %v left, want between %v and %v", test.path, test.header, left, test.min, test.max)
+		}
+	}
+}
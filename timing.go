@@ -0,0 +1,133 @@
+package shortmux
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithServerTiming adds a Server-Timing header to the responses of the
+// route, for the developer tools of browsers, with the durations of:
+//
+//   - match: finding the route;
+//   - mw: the route options, until the handler is called;
+//   - handler: the handler, until it writes the response header, or
+//     returns if it writes nothing.
+func WithServerTiming() RouteOption {
+	return func(c *routeConfig) {
+		c.serverTiming = true
+	}
+}
+
+// serverTiming records the timing of a request for WithServerTiming.
+type serverTiming struct {
+	start   time.Time // of routing
+	matched time.Time // when the route was found
+	handler time.Time // when the handler was called, or zero
+}
+
+type serverTimingKey struct{}
+
+// startTiming returns w and r instrumented for the Server-Timing header of
+// a request whose routing started at start.
+func startTiming(w http.ResponseWriter, r *http.Request, start time.Time) (*timingWriter, *http.Request) {
+	st := &serverTiming{start: start, matched: time.Now()}
+	r = r.WithContext(context.WithValue(r.Context(), serverTimingKey{}, st))
+	return &timingWriter{ResponseWriter: w, st: st}, r
+}
+
+// markHandlerStart returns h recording when it's called in the
+// serverTiming of the request.
+func markHandlerStart(h http.Handler) http.Handler {
+	return &timingHandler{h}
+}
+
+type timingHandler struct {
+	next http.Handler
+}
+
+func (h *timingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if st, ok := r.Context().Value(serverTimingKey{}).(*serverTiming); ok {
+		st.handler = time.Now()
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// Unwrap returns the handler being timed.
+func (h *timingHandler) Unwrap() http.Handler {
+	return h.next
+}
+
+// header returns the value of the Server-Timing header at now.
+func (st *serverTiming) header(now time.Time) string {
+	b := make([]byte, 0, 64)
+	b = appendTiming(b, "match", st.matched.Sub(st.start))
+	if st.handler.IsZero() {
+		// An option answered by itself.
+		b = append(b, ", "...)
+		return string(appendTiming(b, "mw", now.Sub(st.matched)))
+	}
+	b = append(b, ", "...)
+	b = appendTiming(b, "mw", st.handler.Sub(st.matched))
+	b = append(b, ", "...)
+	return string(appendTiming(b, "handler", now.Sub(st.handler)))
+}
+
+// appendTiming appends a Server-Timing metric with duration d, in
+// milliseconds.
+func appendTiming(b []byte, name string, d time.Duration) []byte {
+	b = append(b, name...)
+	b = append(b, ";dur="...)
+	return strconv.AppendFloat(b, float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// timingWriter is a [http.ResponseWriter] adding the Server-Timing header
+// right before the response header is written.
+type timingWriter struct {
+	http.ResponseWriter
+	st      *serverTiming
+	written bool
+}
+
+// finish adds the header if it wasn't written yet.
+func (w *timingWriter) finish() {
+	if !w.written {
+		w.written = true
+		w.Header().Add("Server-Timing", w.st.header(time.Now()))
+	}
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if code < 100 || code > 199 || code == http.StatusSwitchingProtocols {
+		w.finish()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	w.finish()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timingWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *timingWriter) FlushError() error {
+	w.finish()
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	// The response header is never written on a hijacked connection.
+	w.written = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,78 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestServerTiming(t *testing.T) {
+	mux := NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+	mux.HandleFunc("/timed", ok, WithServerTiming())
+	mux.HandleFunc("/denied", ok, WithServerTiming(), WithAuth(func(*http.Request) bool { return false }))
+	mux.HandleFunc("/empty", func(http.ResponseWriter, *http.Request) {}, WithServerTiming())
+	mux.HandleFunc("/plain", ok)
+
+	full := regexp.MustCompile(`^match;dur=\d+\.\d{3}, mw;dur=\d+\.\d{3}, handler;dur=\d+\.\d{3}$`)
+	short := regexp.MustCompile(`^match;dur=\d+\.\d{3}, mw;dur=\d+\.\d{3}$`)
+	for _, test := range []struct {
+		path string
+		want *regexp.Regexp
+	}{
+		{"/timed", full},
+		{"/empty", full},
+		{"/denied", short},
+		{"/plain", nil},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		got := w.Header().Values("Server-Timing")
+		if test.want == nil {
+			if len(got) != 0 {
+				t.Errorf("%s: Server-Timing = %q, want none", test.path, got)
+			}
+			continue
+		}
+		if len(got) != 1 || !test.want.MatchString(got[0]) {
+			t.Errorf("%s: Server-Timing = %q, want match for %s", test.path, got, test.want)
+		}
+	}
+}
+
+func TestWritersHijack(t *testing.T) {
+	hijack := func(w http.ResponseWriter, r *http.Request) {
+		// As websocket libraries do.
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "not a Hijacker", http.StatusInternalServerError)
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/timing", hijack, WithServerTiming())
+	mux.HandleFunc("/throttle", hijack, WithWriteRateLimit(1<<20))
+	mux.HandleFunc("/fallback", hijack, WithFallback(http.NotFoundHandler()))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, path := range []string{"/timing", "/throttle", "/fallback"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("%s: got status %d, want %d", path, resp.StatusCode, http.StatusSwitchingProtocols)
+		}
+	}
+}
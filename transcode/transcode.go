@@ -0,0 +1,228 @@
+// Package transcode registers JSON/HTTP routes on a [shortmux.ServeMux] that
+// are transcoded to unary gRPC-style calls, in the spirit of grpc-gateway and
+// the google.api.http annotations.
+//
+// The package has no dependency on gRPC. A [Backend] receives the request
+// message as JSON and returns the response message as JSON; adapting it to a
+// grpc.ClientConn is a matter of calling protojson on both sides.
+//
+// Path wildcards become message fields:
+//
+//	transcode.Handle(mux, backend, transcode.Rule{
+//		Pattern: "GET /v1/{name...}",
+//		Method:  "/library.Library/GetBook",
+//	})
+//
+// transcodes GET /v1/shelves/1/books/2 into the message
+// {"name": "shelves/1/books/2"}.
+package transcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/henvic/shortmux"
+)
+
+// A Backend invokes unary RPCs.
+type Backend interface {
+	// Invoke calls the fully-qualified method (e.g. "/pkg.Service/Method")
+	// with a JSON-encoded request message and returns the JSON-encoded
+	// response message.
+	// Errors of type *Error are mapped to the matching HTTP status code;
+	// any other error results in 500 Internal Server Error.
+	Invoke(r *http.Request, method string, req json.RawMessage) (json.RawMessage, error)
+}
+
+// BackendFunc is an adapter to use ordinary functions as a [Backend].
+type BackendFunc func(r *http.Request, method string, req json.RawMessage) (json.RawMessage, error)
+
+// Invoke calls f(r, method, req).
+func (f BackendFunc) Invoke(r *http.Request, method string, req json.RawMessage) (json.RawMessage, error) {
+	return f(r, method, req)
+}
+
+// A Rule maps an HTTP route to an RPC method.
+type Rule struct {
+	// Pattern is the shortmux pattern, e.g. "POST /v1/shelves/{shelf}/books".
+	Pattern string
+
+	// Method is the fully-qualified RPC method, e.g. "/library.Library/CreateBook".
+	Method string
+
+	// Body selects where the request body goes:
+	//   - "" means the request has no body, and query parameters are
+	//     mapped to message fields;
+	//   - "*" means the body is the JSON message itself;
+	//   - any other value is the name of the field that receives the body,
+	//     and query parameters are mapped to the remaining fields.
+	Body string
+
+	// Fields maps wildcard names to message field paths, for fields whose
+	// name differs from the wildcard name or which are nested.
+	// Nested fields are separated with dots, e.g. {"book": "book.name"}.
+	// Wildcards not listed here map to the field with the same name.
+	Fields map[string]string
+
+	// MaxBodyBytes limits the size of the request body.
+	// Zero means DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// DefaultMaxBodyBytes is the request body limit used when Rule.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 4 << 20
+
+// Handle registers rule on mux. Like [shortmux.ServeMux.Handle], it panics
+// if the pattern is invalid or already registered.
+func Handle(mux *shortmux.ServeMux, b Backend, rule Rule, opts ...shortmux.RouteOption) {
+	names, err := mux.WildcardNames(rule.Pattern)
+	if err != nil {
+		panic(fmt.Errorf("transcode: %w", err))
+	}
+	h, err := newHandler(b, rule, names)
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle(rule.Pattern, h, opts...)
+}
+
+// NewHandler returns the handler that transcodes requests matched by
+// rule.Pattern.
+func NewHandler(b Backend, rule Rule) (http.Handler, error) {
+	names, err := shortmux.WildcardNames(rule.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("transcode: %w", err)
+	}
+	return newHandler(b, rule, names)
+}
+
+// newHandler returns the handler for rule, whose pattern has the wildcards
+// names.
+func newHandler(b Backend, rule Rule, names []string) (http.Handler, error) {
+	if b == nil {
+		return nil, errors.New("transcode: nil backend")
+	}
+	if rule.Method == "" {
+		return nil, fmt.Errorf("transcode: %q: missing method", rule.Pattern)
+	}
+	fields := map[string][]string{}
+	for _, name := range names {
+		f := name
+		if v, ok := rule.Fields[name]; ok {
+			f = v
+		}
+		fields[name] = strings.Split(f, ".")
+	}
+	for name := range rule.Fields {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("transcode: %q: no wildcard named %q", rule.Pattern, name)
+		}
+	}
+	if rule.MaxBodyBytes == 0 {
+		rule.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &handler{backend: b, rule: rule, fields: fields}, nil
+}
+
+type handler struct {
+	backend Backend
+	rule    Rule
+	fields  map[string][]string // wildcard name -> field path
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	msg, err := h.message(w, r)
+	if err != nil {
+		writeError(w, &Error{Code: InvalidArgument, Message: err.Error()})
+		return
+	}
+	req, err := json.Marshal(msg)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp, err := h.backend.Invoke(r, h.rule.Method, req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// message builds the request message from the body, the query string, and
+// the path values of r.
+func (h *handler) message(w http.ResponseWriter, r *http.Request) (map[string]any, error) {
+	msg := map[string]any{}
+	if h.rule.Body != "" {
+		var body any
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.rule.MaxBodyBytes))
+		if err := dec.Decode(&body); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		if h.rule.Body == "*" {
+			if body != nil {
+				obj, ok := body.(map[string]any)
+				if !ok {
+					return nil, errors.New("request body must be a JSON object")
+				}
+				msg = obj
+			}
+		} else if body != nil {
+			if err := setField(msg, strings.Split(h.rule.Body, "."), body); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if h.rule.Body != "*" {
+		for _, k := range sortedKeys(r.URL.Query()) {
+			vs := r.URL.Query()[k]
+			var v any = vs[0]
+			if len(vs) > 1 {
+				v = vs
+			}
+			if err := setField(msg, strings.Split(k, "."), v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Path values take precedence over the body and the query string.
+	for _, name := range sortedKeys(h.fields) {
+		if err := setField(msg, h.fields[name], r.PathValue(name)); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// setField sets the field at path in msg to v, creating intermediate
+// messages as needed.
+func setField(msg map[string]any, path []string, v any) error {
+	for _, p := range path[:len(path)-1] {
+		next, ok := msg[p]
+		if !ok {
+			m := map[string]any{}
+			msg[p] = m
+			msg = m
+			continue
+		}
+		m, ok := next.(map[string]any)
+		if !ok {
+			return fmt.Errorf("field %q is not a message", p)
+		}
+		msg = m
+	}
+	msg[path[len(path)-1]] = v
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	// Deterministic order makes the generated message stable.
+	return slices.Sorted(maps.Keys(m))
+}
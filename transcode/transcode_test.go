@@ -0,0 +1,68 @@
+package transcode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/henvic/shortmux"
+)
+
+func TestHandle(t *testing.T) {
+	var gotMethod, gotReq string
+	backend := BackendFunc(func(r *http.Request, method string, req json.RawMessage) (json.RawMessage, error) {
+		gotMethod, gotReq = method, string(req)
+		if strings.Contains(gotReq, "missing") {
+			return nil, &Error{Code: NotFound, Message: "no such book"}
+		}
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+	mux := shortmux.NewServeMux()
+	Handle(mux, backend, Rule{Pattern: "GET /v1/{name...}", Method: "/library.Library/GetBook"})
+	Handle(mux, backend, Rule{Pattern: "POST /v1/shelves/{shelf}/books", Method: "/library.Library/CreateBook", Body: "book"})
+	Handle(mux, backend, Rule{Pattern: "GET /v1/export/{format:(json|xml)}", Method: "/library.Library/Export"})
+	Handle(mux, backend, Rule{Pattern: "DELETE /v1/users/{id:!(me)}", Method: "/library.Library/DeleteUser"})
+	Handle(mux, backend, Rule{Pattern: "PATCH /v1/books/{id}", Method: "/library.Library/UpdateBook", Body: "*", Fields: map[string]string{"id": "book.id"}})
+
+	for _, test := range []struct {
+		method, target, body string
+		wantMethod, wantReq  string
+		wantCode             int
+	}{
+		{"GET", "/v1/shelves/1/books/2", "", "/library.Library/GetBook", `{"name":"shelves/1/books/2"}`, 200},
+		{"GET", "/v1/shelves/1?view=full", "", "/library.Library/GetBook", `{"name":"shelves/1","view":"full"}`, 200},
+		{"GET", "/v1/missing", "", "/library.Library/GetBook", `{"name":"missing"}`, 404},
+		{"POST", "/v1/shelves/7/books?lang=en", `{"title":"Go"}`, "/library.Library/CreateBook", `{"book":{"title":"Go"},"lang":"en","shelf":"7"}`, 200},
+		{"PATCH", "/v1/books/9", `{"book":{"title":"Go"}}`, "/library.Library/UpdateBook", `{"book":{"id":"9","title":"Go"}}`, 200},
+		{"PATCH", "/v1/books/9", `[1]`, "", "", 400},
+		{"GET", "/v1/export/xml", "", "/library.Library/Export", `{"format":"xml"}`, 200},
+		{"DELETE", "/v1/users/7", "", "/library.Library/DeleteUser", `{"id":"7"}`, 200},
+	} {
+		gotMethod, gotReq = "", ""
+		r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.wantCode {
+			t.Errorf("%s %s: got status %d, want %d", test.method, test.target, w.Code, test.wantCode)
+		}
+		if gotMethod != test.wantMethod || gotReq != test.wantReq {
+			t.Errorf("%s %s: got %s %s, want %s %s", test.method, test.target, gotMethod, gotReq, test.wantMethod, test.wantReq)
+		}
+	}
+}
+
+func TestNewHandlerErr(t *testing.T) {
+	b := BackendFunc(func(*http.Request, string, json.RawMessage) (json.RawMessage, error) { return nil, nil })
+	for _, rule := range []Rule{
+		{Pattern: "/a"},
+		{Pattern: "/a/{}", Method: "/s/M"},
+		{Pattern: "/a/{x:(b|c)}", Method: "/s/M", Fields: map[string]string{"x:(b|c)": "x"}},
+		{Pattern: "/a/{x}", Method: "/s/M", Fields: map[string]string{"y": "y"}},
+	} {
+		if _, err := NewHandler(b, rule); err == nil {
+			t.Errorf("%+v: got nil error", rule)
+		}
+	}
+}
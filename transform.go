@@ -0,0 +1,167 @@
+package shortmux
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// A BufferedResponse is a complete response produced by a handler, before
+// it is sent to the client.
+type BufferedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// A ResponseTransform rewrites a buffered response in place, for example to
+// redact JSON fields or to inject a banner in an HTML page.
+// If it returns an error, the client receives 500 Internal Server Error
+// instead.
+type ResponseTransform func(r *http.Request, res *BufferedResponse) error
+
+// WithResponseTransform buffers the responses of the route and passes them
+// to t before they are sent.
+// The Content-Length header is recomputed after t runs, except for HEAD
+// responses with no body.
+//
+// Streaming responses opt out: once the handler flushes the response writer
+// (directly or through [http.ResponseController]), the buffered data and
+// everything written afterwards is sent untransformed.
+// Hijacked connections are never transformed.
+func WithResponseTransform(t ResponseTransform) RouteOption {
+	if t == nil {
+		panic("shortmux: nil response transform")
+	}
+	return func(c *routeConfig) {
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tw := &transformWriter{ResponseWriter: w, header: w.Header().Clone()}
+				next.ServeHTTP(tw, r)
+				if tw.streaming {
+					return
+				}
+				res := &BufferedResponse{StatusCode: tw.status, Header: tw.header, Body: tw.buf.Bytes()}
+				if res.StatusCode == 0 {
+					res.StatusCode = http.StatusOK
+				}
+				if err := t(r, res); err != nil {
+					clear(w.Header())
+					mux.Error(w, r, http.StatusInternalServerError)
+					return
+				}
+				h := w.Header()
+				clear(h)
+				for k, v := range res.Header {
+					h[k] = v
+				}
+				// A HEAD response has no body, but may have the
+				// Content-Length of the GET response, as set by the
+				// handler.
+				if bodyAllowedForStatus(res.StatusCode) && (r.Method != "HEAD" || len(res.Body) > 0) {
+					h.Set("Content-Length", strconv.Itoa(len(res.Body)))
+				}
+				w.WriteHeader(res.StatusCode)
+				_, _ = w.Write(res.Body)
+			})
+		})
+	}
+}
+
+// transformWriter buffers a response until the handler returns, or until
+// the handler flushes and the response becomes a stream.
+type transformWriter struct {
+	http.ResponseWriter
+	header    http.Header
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (w *transformWriter) Header() http.Header {
+	if w.streaming {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *transformWriter) WriteHeader(code int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// Informational responses are sent right away, as usual.
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *transformWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+// Flush switches w to streaming mode, sending what was buffered so far.
+func (w *transformWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError is like Flush, but reports errors; see [http.ResponseController].
+func (w *transformWriter) FlushError() error {
+	if !w.streaming {
+		w.streaming = true
+		h := w.ResponseWriter.Header()
+		clear(h)
+		for k, v := range w.header {
+			h[k] = v
+		}
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		if _, err := w.ResponseWriter.Write(w.buf.Bytes()); err != nil {
+			return err
+		}
+		w.buf = bytes.Buffer{}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the handler take over the connection; nothing is transformed.
+func (w *transformWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.streaming = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *transformWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bodyAllowedForStatus reports whether a given response status code
+// permits a body. See RFC 7230, section 3.3.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == 204:
+		return false
+	case status == 304:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package shortmux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithResponseTransform(t *testing.T) {
+	redact := func(r *http.Request, res *BufferedResponse) error {
+		if res.StatusCode == http.StatusTeapot {
+			return errors.New("no tea")
+		}
+		res.Body = bytes.ReplaceAll(res.Body, []byte("secret"), []byte("******"))
+		res.Header.Set("X-Redacted", "1")
+		return nil
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/buffered", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "13")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "secret: 1234\n")
+	}, WithResponseTransform(redact))
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secret ")
+		http.NewResponseController(w).Flush()
+		io.WriteString(w, "secret")
+	}, WithResponseTransform(redact))
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}, WithResponseTransform(redact))
+
+	for _, test := range []struct {
+		path         string
+		wantCode     int
+		wantBody     string
+		wantRedacted string
+	}{
+		{"/buffered", http.StatusCreated, "******: 1234\n", "1"},
+		{"/stream", http.StatusOK, "secret secret", ""},
+		{"/fail", http.StatusInternalServerError, "Internal Server Error\n", ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.wantCode || w.Body.String() != test.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", test.path, w.Code, w.Body.String(), test.wantCode, test.wantBody)
+		}
+		if got := w.Header().Get("X-Redacted"); got != test.wantRedacted {
+			t.Errorf("%s: got X-Redacted %q, want %q", test.path, got, test.wantRedacted)
+		}
+	}
+}
+
+func TestWithResponseTransformHEAD(t *testing.T) {
+	nop := func(*http.Request, *BufferedResponse) error { return nil }
+	mux := NewServeMux()
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.txt", time.Time{}, strings.NewReader("hello"))
+	}, WithResponseTransform(nop))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("HEAD", "/file", nil))
+	if got := w.Header().Get("Content-Length"); got != "5" {
+		t.Errorf("HEAD: got Content-Length %q, want 5", got)
+	}
+}
+
+func TestWithResponseTransformFailedHijack(t *testing.T) {
+	upper := func(r *http.Request, res *BufferedResponse) error {
+		res.Body = bytes.ToUpper(res.Body)
+		return nil
+	}
+	mux := NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := http.NewResponseController(w).Hijack(); err == nil {
+			t.Error("hijacked a recorder")
+		}
+		io.WriteString(w, "buffered")
+	}, WithResponseTransform(upper))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Body.String(); got != "BUFFERED" {
+		t.Errorf("got body %q, want %q", got, "BUFFERED")
+	}
+}
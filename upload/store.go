@@ -0,0 +1,101 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a [Store] keeping uploads in memory, for tests and small
+// uploads. Expired uploads are discarded as new ones are created.
+type MemoryStore struct {
+	mu      sync.Mutex
+	uploads map[string]*memoryUpload
+}
+
+type memoryUpload struct {
+	info Info
+	data bytes.Buffer
+	busy bool // data is being appended
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{uploads: map[string]*memoryUpload{}}
+}
+
+// Create implements [Store].
+func (s *MemoryStore) Create(_ context.Context, info Info) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, u := range s.uploads {
+		if !u.info.Complete() && now.After(u.info.Expires) && !u.busy {
+			delete(s.uploads, id)
+		}
+	}
+	s.uploads[info.ID] = &memoryUpload{info: info}
+	return nil
+}
+
+// Info implements [Store].
+func (s *MemoryStore) Info(_ context.Context, id string) (Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	return u.info, nil
+}
+
+// Append implements [Store].
+func (s *MemoryStore) Append(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	s.mu.Lock()
+	u, ok := s.uploads[id]
+	switch {
+	case !ok:
+		s.mu.Unlock()
+		return 0, ErrNotFound
+	case u.busy || u.info.Offset != offset:
+		s.mu.Unlock()
+		return 0, ErrOffsetMismatch
+	}
+	u.busy = true
+	s.mu.Unlock()
+
+	// Read outside of the lock, as r is usually a request body.
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.busy = false
+	u.data.Write(buf.Bytes())
+	u.info.Offset += n
+	return n, err
+}
+
+// Delete implements [Store].
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.uploads, id)
+	return nil
+}
+
+// Data returns the data received for the upload with the given ID.
+func (s *MemoryStore) Data(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(u.data.Bytes()), nil
+}
@@ -0,0 +1,251 @@
+// Package upload registers routes for resumable uploads on a
+// [shortmux.ServeMux], following the core protocol of tus
+// (https://tus.io/protocols/resumable-upload):
+//
+//	POST   /uploads/      creates an upload of Upload-Length bytes
+//	HEAD   /uploads/{id}  reports the Upload-Offset of the upload
+//	PATCH  /uploads/{id}  appends the body at Upload-Offset
+//	DELETE /uploads/{id}  terminates the upload
+//
+// Clients resume an interrupted upload by asking for its offset and
+// sending the rest of the data from there:
+//
+//	h := &upload.Handler{
+//		Store: upload.NewMemoryStore(),
+//		OnComplete: func(ctx context.Context, info upload.Info) error {
+//			// Move the data out of the store.
+//		},
+//	}
+//	upload.Register(mux, "/uploads/", h, shortmux.WithAuth(loggedIn))
+//
+// The data is kept by a pluggable [Store].
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+// The headers of the protocol.
+const (
+	LengthHeader  = "Upload-Length"
+	OffsetHeader  = "Upload-Offset"
+	ExpiresHeader = "Upload-Expires"
+)
+
+// ContentType is the content type of the body of PATCH requests.
+const ContentType = "application/offset+octet-stream"
+
+// DefaultExpiration is the time an upload is kept without being completed
+// if [Handler.Expiration] is zero.
+const DefaultExpiration = 24 * time.Hour
+
+var (
+	// ErrNotFound is returned by a Store for unknown uploads.
+	ErrNotFound = errors.New("upload: not found")
+
+	// ErrOffsetMismatch is returned by a Store when data is appended at an
+	// offset other than the current offset of the upload.
+	ErrOffsetMismatch = errors.New("upload: offset mismatch")
+)
+
+// Info describes an upload.
+type Info struct {
+	ID      string
+	Size    int64     // total size, in bytes
+	Offset  int64     // number of bytes received
+	Expires time.Time // when the upload is discarded if not completed
+}
+
+// Complete reports whether all the data of the upload was received.
+func (i Info) Complete() bool {
+	return i.Offset == i.Size
+}
+
+// A Store keeps the data of uploads. Its methods may be called
+// concurrently.
+type Store interface {
+	// Create creates an empty upload.
+	Create(ctx context.Context, info Info) error
+
+	// Info returns the upload with the given ID, or ErrNotFound.
+	Info(ctx context.Context, id string) (Info, error)
+
+	// Append writes the data read from r at offset, which must be the
+	// current offset of the upload, or it returns ErrOffsetMismatch.
+	// It returns the number of bytes stored, which are kept even if
+	// reading r fails, so that the client can resume from there.
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+
+	// Delete discards the upload, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+}
+
+// Handler serves resumable uploads. Its fields must be set before it is
+// registered.
+type Handler struct {
+	Store Store
+
+	// MaxSize is the maximum size of an upload, or zero for no limit.
+	MaxSize int64
+
+	// Expiration is the time an upload is kept without being completed.
+	// It defaults to DefaultExpiration.
+	Expiration time.Duration
+
+	// OnComplete, if set, is called when all the data of an upload was
+	// received, before the response to the last PATCH request. If it
+	// returns an error, the response is 500 Internal Server Error and the
+	// client may retry by sending an empty PATCH request.
+	OnComplete func(ctx context.Context, info Info) error
+}
+
+// Register registers the routes of h under prefix, which must end in a
+// slash and must not have a method, e.g. "/uploads/" or
+// "files.example.com/". The options apply to every route.
+func Register(mux *shortmux.ServeMux, prefix string, h *Handler, opts ...shortmux.RouteOption) {
+	if !strings.HasSuffix(prefix, "/") || strings.ContainsAny(prefix, " \t") {
+		panic(fmt.Sprintf("upload: invalid prefix %q", prefix))
+	}
+	if h.Store == nil {
+		panic("upload: nil store")
+	}
+	mux.HandleFunc("POST "+prefix+"{$}", h.create, opts...)
+	mux.HandleFunc("HEAD "+prefix+"{id}", h.head, opts...)
+	mux.HandleFunc("PATCH "+prefix+"{id}", h.patch, opts...)
+	mux.HandleFunc("DELETE "+prefix+"{id}", h.delete, opts...)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get(LengthHeader), 10, 64)
+	if err != nil || size < 0 {
+		http.Error(w, "invalid "+LengthHeader, http.StatusBadRequest)
+		return
+	}
+	if h.MaxSize > 0 && size > h.MaxSize {
+		http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	exp := h.Expiration
+	if exp == 0 {
+		exp = DefaultExpiration
+	}
+	info := Info{ID: newID(), Size: size, Expires: time.Now().Add(exp)}
+	if err := h.Store.Create(r.Context(), info); err != nil {
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", r.URL.Path+info.ID)
+	setInfo(w, info)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request) {
+	info, ok := h.info(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	setInfo(w, info)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != ContentType {
+		http.Error(w, "Content-Type must be "+ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(OffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid "+OffsetHeader, http.StatusBadRequest)
+		return
+	}
+	info, ok := h.info(w, r)
+	if !ok {
+		return
+	}
+	if offset != info.Offset {
+		http.Error(w, OffsetHeader+" does not match the upload", http.StatusConflict)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, info.Size-offset)
+	n, err := h.Store.Append(r.Context(), info.ID, offset, body)
+	info.Offset += n
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrOffsetMismatch):
+		// A concurrent request appended data first.
+		http.Error(w, OffsetHeader+" does not match the upload", http.StatusConflict)
+		return
+	case errors.As(err, &tooLarge):
+		w.Header().Set(OffsetHeader, strconv.FormatInt(info.Offset, 10))
+		http.Error(w, "data exceeds "+LengthHeader, http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		// The client resumes from the offset it gets with HEAD.
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if info.Complete() && h.OnComplete != nil {
+		if err := h.OnComplete(r.Context(), info); err != nil {
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+	setInfo(w, info)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	err := h.Store.Delete(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// info returns the upload of the request. If it doesn't exist or expired,
+// it responds and returns false.
+func (h *Handler) info(w http.ResponseWriter, r *http.Request) (Info, bool) {
+	info, err := h.Store.Info(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return Info{}, false
+	case err != nil:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return Info{}, false
+	case !info.Complete() && time.Now().After(info.Expires):
+		h.Store.Delete(r.Context(), info.ID)
+		http.Error(w, "upload expired", http.StatusGone)
+		return Info{}, false
+	}
+	return info, true
+}
+
+func setInfo(w http.ResponseWriter, info Info) {
+	w.Header().Set(LengthHeader, strconv.FormatInt(info.Size, 10))
+	w.Header().Set(OffsetHeader, strconv.FormatInt(info.Offset, 10))
+	if !info.Complete() {
+		w.Header().Set(ExpiresHeader, info.Expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,108 @@
+package upload
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/henvic/shortmux"
+)
+
+func do(mux http.Handler, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	for i := 0; i < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestUpload(t *testing.T) {
+	store := NewMemoryStore()
+	var completed []Info
+	mux := shortmux.NewServeMux()
+	Register(mux, "/uploads/", &Handler{
+		Store:   store,
+		MaxSize: 100,
+		OnComplete: func(_ context.Context, info Info) error {
+			completed = append(completed, info)
+			return nil
+		},
+	})
+
+	if w := do(mux, "POST", "/uploads/", "", LengthHeader, "1000"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large: status %d", w.Code)
+	}
+	w := do(mux, "POST", "/uploads/", "", LengthHeader, "10")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d", w.Code)
+	}
+	loc := w.Header().Get("Location")
+	if !strings.HasPrefix(loc, "/uploads/") || w.Header().Get(ExpiresHeader) == "" {
+		t.Fatalf("create: Location %q, headers %v", loc, w.Header())
+	}
+
+	patch := func(offset, body string) *httptest.ResponseRecorder {
+		return do(mux, "PATCH", loc, body, "Content-Type", ContentType, OffsetHeader, offset)
+	}
+	if w := patch("0", "01234"); w.Code != http.StatusNoContent || w.Header().Get(OffsetHeader) != "5" {
+		t.Errorf("first patch: status %d, offset %q", w.Code, w.Header().Get(OffsetHeader))
+	}
+	if w := patch("0", "01234"); w.Code != http.StatusConflict {
+		t.Errorf("patch at a stale offset: status %d", w.Code)
+	}
+	if w := do(mux, "HEAD", loc, ""); w.Code != http.StatusOK || w.Header().Get(OffsetHeader) != "5" {
+		t.Errorf("head: status %d, offset %q", w.Code, w.Header().Get(OffsetHeader))
+	}
+	if len(completed) != 0 {
+		t.Errorf("completed before all the data was received")
+	}
+	if w := patch("5", "56789"); w.Code != http.StatusNoContent || w.Header().Get(OffsetHeader) != "10" {
+		t.Errorf("last patch: status %d, offset %q", w.Code, w.Header().Get(OffsetHeader))
+	}
+	if len(completed) != 1 || !completed[0].Complete() {
+		t.Errorf("completed = %v", completed)
+	}
+	id := strings.TrimPrefix(loc, "/uploads/")
+	if data, _ := store.Data(id); string(data) != "0123456789" {
+		t.Errorf("data = %q", data)
+	}
+
+	if w := do(mux, "DELETE", loc, ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if w := do(mux, "HEAD", loc, ""); w.Code != http.StatusNotFound {
+		t.Errorf("head after delete: status %d", w.Code)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	mux := shortmux.NewServeMux()
+	Register(mux, "/uploads/", &Handler{Store: NewMemoryStore(), Expiration: time.Nanosecond})
+	loc := do(mux, "POST", "/uploads/", "", LengthHeader, "3").Header().Get("Location")
+
+	if w := do(mux, "PATCH", loc, "abc", OffsetHeader, "0"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("patch without content type: status %d", w.Code)
+	}
+	if w := do(mux, "PATCH", loc, "abc", "Content-Type", ContentType); w.Code != http.StatusBadRequest {
+		t.Errorf("patch without offset: status %d", w.Code)
+	}
+	time.Sleep(time.Millisecond)
+	if w := do(mux, "PATCH", loc, "abc", "Content-Type", ContentType, OffsetHeader, "0"); w.Code != http.StatusGone {
+		t.Errorf("patch after expiration: status %d", w.Code)
+	}
+	if w := do(mux, "HEAD", loc, ""); w.Code != http.StatusNotFound {
+		t.Errorf("head after expiration: status %d", w.Code)
+	}
+
+	Register(mux, "/big/", &Handler{Store: NewMemoryStore()})
+	loc = do(mux, "POST", "/big/", "", LengthHeader, "3").Header().Get("Location")
+	w := do(mux, "PATCH", loc, "abcdef", "Content-Type", ContentType, OffsetHeader, "0")
+	if w.Code != http.StatusRequestEntityTooLarge || w.Header().Get(OffsetHeader) != "3" {
+		t.Errorf("patch past the length: status %d, offset %q", w.Code, w.Header().Get(OffsetHeader))
+	}
+}
@@ -0,0 +1,143 @@
+package shortmux
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A VirtualHost is a handler, typically a [ServeMux], served for a set of
+// hosts by [VirtualHosts].
+type VirtualHost struct {
+	Handler http.Handler
+
+	// TLSConfig, if non-nil, is used for TLS connections whose
+	// server name (SNI) selects this virtual host.
+	TLSConfig *tls.Config
+}
+
+// VirtualHosts dispatches requests arriving on a single listener to
+// different handlers, based on the TLS server name (SNI) or the Host header,
+// before the handler does its own matching.
+//
+// Host names are matched case-insensitively. A name of the form
+// "*.example.com" matches any single label followed by ".example.com",
+// and is only used when there is no exact match.
+//
+// When a TLS connection carries a server name, it selects the virtual host.
+// A request whose Host header selects a different virtual host is answered
+// with 421 Misdirected Request, so a client can't use the certificate of one
+// host to reach another.
+type VirtualHosts struct {
+	// Hosts maps host names to virtual hosts. The names are normalized to
+	// lower case on first use, after which Hosts must not be modified.
+	Hosts map[string]*VirtualHost
+
+	// Default, if non-nil, serves requests for unknown hosts.
+	// Otherwise, they are answered with 404 Not Found.
+	Default *VirtualHost
+
+	once  sync.Once
+	hosts map[string]*VirtualHost // Hosts, with normalized names
+}
+
+// lookup returns the virtual host for host, or nil, and the label
+// matched by the wildcard of its name, if any.
+func (vh *VirtualHosts) lookup(host string) (*VirtualHost, string) {
+	vh.once.Do(func() {
+		vh.hosts = make(map[string]*VirtualHost, len(vh.Hosts))
+		for name, v := range vh.Hosts {
+			vh.hosts[normalizeHostName(name)] = v
+		}
+	})
+	host = normalizeHostName(host)
+	if v, ok := vh.hosts[host]; ok {
+		return v, ""
+	}
+	if label, parent, ok := strings.Cut(host, "."); ok {
+		if v, ok := vh.hosts["*."+parent]; ok {
+			return v, label
+		}
+	}
+	return vh.Default, ""
+}
+
+// handler returns the handler of v, or nil if v is nil.
+func (v *VirtualHost) handler() http.Handler {
+	if v == nil {
+		return nil
+	}
+	return v.Handler
+}
+
+// normalizeHostName returns the host name in lower case, without a
+// trailing dot.
+func normalizeHostName(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// hostLabelKey is the context key for the label matched by the wildcard
+// of the name of a virtual host.
+type hostLabelKey struct{}
+
+// ServeHTTP dispatches the request to the handler of its virtual host.
+// The label matched by the wildcard of a name such as "*.example.com" is
+// available to the handler with [Params].
+func (vh *VirtualHosts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v, label := vh.lookup(stripHostPort(r.Host))
+	if r.TLS != nil && r.TLS.ServerName != "" {
+		if sv, _ := vh.lookup(r.TLS.ServerName); sv != v {
+			// The error page is that of the mux of the host named by
+			// the server name, as the client is connected to it.
+			if mux, ok := sv.handler().(*ServeMux); ok {
+				mux.Error(w, r, http.StatusMisdirectedRequest)
+			} else {
+				http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+			}
+			return
+		}
+	}
+	if v == nil || v.Handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if label != "" {
+		r = r.WithContext(context.WithValue(r.Context(), hostLabelKey{}, label))
+	}
+	v.Handler.ServeHTTP(w, r)
+}
+
+// TLSConfig returns a clone of base that selects the TLS configuration of
+// the virtual host named by the client's SNI, falling back to base itself.
+// base may be nil.
+func (vh *VirtualHosts) TLSConfig(base *tls.Config) *tls.Config {
+	cfg := base.Clone()
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	next := cfg.GetConfigForClient
+	cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if v, _ := vh.lookup(hello.ServerName); v != nil && v.TLSConfig != nil {
+			return v.TLSConfig, nil
+		}
+		if next != nil {
+			return next(hello)
+		}
+		return nil, nil
+	}
+	return cfg
+}
+
+// Server returns an [http.Server] listening on addr that serves vh,
+// with the TLS configuration returned by vh.TLSConfig(nil).
+// Start it with ListenAndServe, or with ListenAndServeTLS("", "") when the
+// certificates come from the virtual hosts.
+func (vh *VirtualHosts) Server(addr string) *http.Server {
+	return &http.Server{
+		Addr:      addr,
+		Handler:   vh,
+		TLSConfig: vh.TLSConfig(nil),
+	}
+}
@@ -0,0 +1,62 @@
+package shortmux
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVirtualHosts(t *testing.T) {
+	muxFor := func(name string) *ServeMux {
+		mux := NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		})
+		return mux
+	}
+	apiTLS := &tls.Config{ServerName: "api"}
+	vh := &VirtualHosts{
+		Hosts: map[string]*VirtualHost{
+			"api.example.com":   {Handler: muxFor("api"), TLSConfig: apiTLS},
+			"*.example.com":     {Handler: muxFor("wildcard")},
+			"Shop.Example.org.": {Handler: muxFor("shop")},
+		},
+	}
+
+	for _, test := range []struct {
+		host, sni string
+		wantCode  int
+		wantBody  string
+	}{
+		{"api.example.com", "", 200, "api"},
+		{"API.Example.com.:8443", "", 200, "api"},
+		{"www.example.com", "", 200, "wildcard"},
+		{"a.b.example.com", "", 404, ""},
+		{"api.example.com", "api.example.com", 200, "api"},
+		{"api.example.com", "www.example.com", http.StatusMisdirectedRequest, ""},
+		{"other.com", "", 404, ""},
+		{"shop.example.org", "", 200, "shop"},
+		{"SHOP.example.org", "shop.example.org", 200, "shop"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Host = test.host
+		if test.sni != "" {
+			r.TLS = &tls.ConnectionState{ServerName: test.sni}
+		}
+		w := httptest.NewRecorder()
+		vh.ServeHTTP(w, r)
+		if w.Code != test.wantCode || (test.wantBody != "" && w.Body.String() != test.wantBody) {
+			t.Errorf("host %q sni %q: got %d %q, want %d %q", test.host, test.sni, w.Code, w.Body.String(), test.wantCode, test.wantBody)
+		}
+	}
+
+	cfg := vh.Server(":0").TLSConfig
+	if got, _ := cfg.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "api.example.com"}); got != apiTLS {
+		t.Errorf("got TLS config %v for api.example.com, want the virtual host's", got)
+	}
+	if got, _ := cfg.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "www.example.com"}); got != nil {
+		t.Errorf("got TLS config %v for www.example.com, want nil", got)
+	}
+}
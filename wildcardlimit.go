@@ -0,0 +1,42 @@
+package shortmux
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithWildcardLimit caps the value of the wildcard name of the route, such
+// as "objectname" in "/buckets/{bucket}/{objectname...}", to maxSegments
+// path segments and maxBytes bytes, unescaped, protecting handlers and the
+// services they call from abusive keys. A zero limit is no limit. Requests
+// whose value is longer than maxBytes get 414 URI Too Long, and those with
+// more segments than maxSegments get 400 Bad Request, with [ServeMux.Error].
+//
+// The value of a single wildcard is one segment. WithWildcardLimit panics
+// if the pattern of the route has no wildcard name.
+func WithWildcardLimit(name string, maxSegments, maxBytes int) RouteOption {
+	if maxSegments < 0 || maxBytes < 0 {
+		panic(fmt.Sprintf("shortmux: invalid wildcard limit %d segments, %d bytes", maxSegments, maxBytes))
+	}
+	return func(c *routeConfig) {
+		if !slices.Contains(c.pat.wildcardNames(), name) {
+			panic("shortmux: pattern " + c.pat.str + " has no wildcard " + name)
+		}
+		mux := c.mux
+		c.use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				v := r.PathValue(name)
+				switch {
+				case maxBytes > 0 && len(v) > maxBytes:
+					mux.Error(w, r, http.StatusRequestURITooLong)
+				case maxSegments > 0 && strings.Count(v, "/") >= maxSegments:
+					mux.Error(w, r, http.StatusBadRequest)
+				default:
+					next.ServeHTTP(w, r)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package shortmux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithWildcardLimit(t *testing.T) {
+	mux := NewServeMux()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/objects/{bucket}/{key...}", ok, WithWildcardLimit("key", 3, 20), WithWildcardLimit("bucket", 0, 8))
+
+	for _, test := range []struct {
+		path string
+		want int
+	}{
+		{"/objects/b/a", 200},
+		{"/objects/b/a/b/c", 200},
+		{"/objects/b/a/b/c/d", 400},
+		{"/objects/b/a/b/", 200},
+		{"/objects/b/a/b/c/", 400},
+		{"/objects/b/" + strings.Repeat("x", 20), 200},
+		{"/objects/b/" + strings.Repeat("x", 21), 414},
+		{"/objects/b/" + strings.Repeat("%41", 20), 200},
+		{"/objects/" + strings.Repeat("b", 9) + "/a", 414},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+		if w.Code != test.want {
+			t.Errorf("%s: got %d, want %d", test.path, w.Code, test.want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("limit of a missing wildcard did not panic")
+		}
+	}()
+	mux.Handle("/other/{key}", ok, WithWildcardLimit("name", 1, 0))
+}
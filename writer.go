@@ -0,0 +1,97 @@
+package shortmux
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// instrumentedWriter is a [http.ResponseWriter] that records the status code
+// and the number of body bytes written by a handler.
+// If verify is set, it also records the Content-Length declared by the
+// handler, and refuses to write beyond it, as net/http does.
+// It supports [http.ResponseController] through Unwrap.
+type instrumentedWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+
+	verify   bool
+	declared int64 // Content-Length declared, or -1 if none was
+	excess   int64 // bytes refused beyond declared
+	hijacked bool
+}
+
+// commit records the status code of the response.
+func (w *instrumentedWriter) commit(code int) {
+	w.status = code
+	if !w.verify {
+		return
+	}
+	w.declared = -1
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n >= 0 {
+			w.declared = n
+		}
+	}
+}
+
+func (w *instrumentedWriter) WriteHeader(code int) {
+	if w.status == 0 && (code < 100 || code > 199 || code == http.StatusSwitchingProtocols) {
+		w.commit(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *instrumentedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.commit(http.StatusOK)
+	}
+	if w.verify && w.declared >= 0 && w.written+int64(len(b)) > w.declared {
+		allowed := w.declared - w.written
+		w.excess += int64(len(b)) - allowed
+		n, err := w.ResponseWriter.Write(b[:allowed])
+		w.written += int64(n)
+		if err == nil {
+			err = http.ErrContentLength
+		}
+		return n, err
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// Status returns the response status code, or 200 if the handler
+// did not write anything.
+func (w *instrumentedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *instrumentedWriter) Flush() {
+	_ = w.FlushError()
+}
+
+func (w *instrumentedWriter) FlushError() error {
+	if w.status == 0 {
+		w.commit(http.StatusOK)
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *instrumentedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return c, rw, err
+}
+
+// Unwrap returns the underlying writer, for [http.ResponseController].
+func (w *instrumentedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}